@@ -0,0 +1,463 @@
+// Package jsonschema implements the commonly used subset of JSON Schema
+// (draft 7 / 2020-12 and the OpenAPI 3 dialect) needed to check payloads
+// exchanged with partner APIs.
+//
+// Supported keywords: type, nullable, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, uniqueItems, minLength,
+// maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// multipleOf, allOf, anyOf, oneOf, not and local $ref pointers ("#/...").
+// Unknown keywords are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema document.
+type Schema struct {
+	root     map[string]interface{}
+	node     map[string]interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// Violation describes a single mismatch. Path is a JSON pointer into the
+// validated document.
+type Violation struct {
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// ValidationError lists every violation found in a document.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return "schema validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Compile parses a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	var node map[string]interface{}
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	return FromMap(node, node)
+}
+
+// FromMap builds a schema from an already decoded node. root is the document
+// local $ref pointers are resolved against and may be the node itself.
+func FromMap(node map[string]interface{}, root map[string]interface{}) (*Schema, error) {
+	s := &Schema{root: root, node: node, patterns: map[string]*regexp.Regexp{}}
+	if err := s.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	if err := s.compilePatterns(node); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustCompile is like Compile but panics on invalid documents.
+func MustCompile(data []byte) *Schema {
+	s, err := Compile(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Schema) compilePatterns(node interface{}) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if p, ok := v.(string); ok && k == "pattern" {
+				if _, seen := s.patterns[p]; seen {
+					continue
+				}
+				re, err := regexp.Compile(p)
+				if err != nil {
+					return fmt.Errorf("jsonschema: invalid pattern %q: %w", p, err)
+				}
+				s.patterns[p] = re
+				continue
+			}
+			if err := s.compilePatterns(v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, v := range n {
+			if err := s.compilePatterns(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateJSON validates a raw JSON document.
+func (s *Schema) ValidateJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return &ValidationError{Violations: []Violation{{Message: "invalid json: " + err.Error()}}}
+	}
+	return s.Validate(doc)
+}
+
+// Validate validates a document decoded with encoding/json into interface{}.
+func (s *Schema) Validate(doc interface{}) error {
+	var violations []Violation
+	s.validate(s.node, doc, "", &violations, 0)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+const maxRefDepth = 64
+
+func (s *Schema) validate(node map[string]interface{}, doc interface{}, path string, out *[]Violation, depth int) {
+	add := func(format string, args ...interface{}) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if ref, ok := node["$ref"].(string); ok {
+		if depth > maxRefDepth {
+			add("$ref nesting too deep at %s", ref)
+			return
+		}
+		target, err := s.resolve(ref)
+		if err != nil {
+			add("%v", err)
+			return
+		}
+		s.validate(target, doc, path, out, depth+1)
+		return
+	}
+
+	if doc == nil && node["nullable"] == true {
+		return
+	}
+
+	if t, ok := node["type"]; ok {
+		var types []string
+		switch tt := t.(type) {
+		case string:
+			types = []string{tt}
+		case []interface{}:
+			for _, x := range tt {
+				if xs, ok := x.(string); ok {
+					types = append(types, xs)
+				}
+			}
+		}
+		matched := false
+		for _, tt := range types {
+			if hasType(doc, tt) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			add("expected %s, got %s", strings.Join(types, " or "), typeOf(doc))
+			return
+		}
+	}
+
+	if enum, ok := node["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, doc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add("value is not one of the allowed values")
+		}
+	}
+	if c, ok := node["const"]; ok && !equal(c, doc) {
+		add("value does not match const")
+	}
+
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		s.validateObject(node, d, path, out, depth)
+	case []interface{}:
+		s.validateArray(node, d, path, out, depth)
+	case string:
+		n := utf8.RuneCountInString(d)
+		if min, ok := number(node["minLength"]); ok && float64(n) < min {
+			add("string shorter than %v", min)
+		}
+		if max, ok := number(node["maxLength"]); ok && float64(n) > max {
+			add("string longer than %v", max)
+		}
+		if p, ok := node["pattern"].(string); ok {
+			if re := s.patterns[p]; re != nil && !re.MatchString(d) {
+				add("string does not match pattern %q", p)
+			}
+		}
+	default:
+		if f, ok := number(doc); ok {
+			s.validateNumber(node, f, add)
+		}
+	}
+
+	if all, ok := node["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if m, ok := sub.(map[string]interface{}); ok {
+				s.validate(m, doc, path, out, depth+1)
+			}
+		}
+	}
+	if anyOf, ok := node["anyOf"].([]interface{}); ok && s.countMatches(anyOf, doc, depth) == 0 {
+		add("value does not match any schema in anyOf")
+	}
+	if oneOf, ok := node["oneOf"].([]interface{}); ok {
+		if n := s.countMatches(oneOf, doc, depth); n != 1 {
+			add("value matches %d schemas in oneOf, expected exactly 1", n)
+		}
+	}
+	if not, ok := node["not"].(map[string]interface{}); ok {
+		var sub []Violation
+		s.validate(not, doc, path, &sub, depth+1)
+		if len(sub) == 0 {
+			add("value must not match schema in not")
+		}
+	}
+}
+
+func (s *Schema) validateObject(node map[string]interface{}, doc map[string]interface{}, path string, out *[]Violation, depth int) {
+	if required, ok := node["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := doc[name]; !present {
+				*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+			}
+		}
+	}
+
+	props, _ := node["properties"].(map[string]interface{})
+	for name, value := range doc {
+		child := path + "/" + escapePointer(name)
+		if sub, ok := props[name].(map[string]interface{}); ok {
+			s.validate(sub, value, child, out, depth+1)
+			continue
+		}
+		switch ap := node["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				*out = append(*out, Violation{Path: child, Message: "additional property not allowed"})
+			}
+		case map[string]interface{}:
+			s.validate(ap, value, child, out, depth+1)
+		}
+	}
+}
+
+func (s *Schema) validateArray(node map[string]interface{}, doc []interface{}, path string, out *[]Violation, depth int) {
+	if min, ok := number(node["minItems"]); ok && float64(len(doc)) < min {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("array has fewer than %v items", min)})
+	}
+	if max, ok := number(node["maxItems"]); ok && float64(len(doc)) > max {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("array has more than %v items", max)})
+	}
+	if node["uniqueItems"] == true {
+		for i := range doc {
+			for j := i + 1; j < len(doc); j++ {
+				if equal(doc[i], doc[j]) {
+					*out = append(*out, Violation{Path: path, Message: fmt.Sprintf("items %d and %d are equal", i, j)})
+				}
+			}
+		}
+	}
+	if items, ok := node["items"].(map[string]interface{}); ok {
+		for i, item := range doc {
+			s.validate(items, item, path+"/"+strconv.Itoa(i), out, depth+1)
+		}
+	}
+}
+
+func (s *Schema) validateNumber(node map[string]interface{}, f float64, add func(string, ...interface{})) {
+	if min, ok := number(node["minimum"]); ok {
+		if f < min || (node["exclusiveMinimum"] == true && f == min) {
+			add("value below minimum %v", min)
+		}
+	}
+	if max, ok := number(node["maximum"]); ok {
+		if f > max || (node["exclusiveMaximum"] == true && f == max) {
+			add("value above maximum %v", max)
+		}
+	}
+	if min, ok := number(node["exclusiveMinimum"]); ok && f <= min {
+		add("value not above exclusive minimum %v", min)
+	}
+	if max, ok := number(node["exclusiveMaximum"]); ok && f >= max {
+		add("value not below exclusive maximum %v", max)
+	}
+	if m, ok := number(node["multipleOf"]); ok && m > 0 {
+		q := f / m
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			add("value is not a multiple of %v", m)
+		}
+	}
+}
+
+func (s *Schema) countMatches(schemas []interface{}, doc interface{}, depth int) int {
+	n := 0
+	for _, sub := range schemas {
+		m, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var violations []Violation
+		s.validate(m, doc, "", &violations, depth+1)
+		if len(violations) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Schema) resolve(ref string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported non-local $ref %q", ref)
+	}
+	var cur interface{} = s.root
+	pointer := strings.TrimPrefix(ref, "#")
+	if pointer != "" {
+		for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+			if cur, ok = m[part]; !ok {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+		}
+	}
+	m, ok := cur.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unresolvable $ref %q", ref)
+	}
+	return m, nil
+}
+
+func hasType(doc interface{}, t string) bool {
+	switch t {
+	case "null":
+		return doc == nil
+	case "boolean":
+		_, ok := doc.(bool)
+		return ok
+	case "string":
+		_, ok := doc.(string)
+		return ok
+	case "object":
+		_, ok := doc.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := doc.([]interface{})
+		return ok
+	case "number":
+		_, ok := number(doc)
+		return ok
+	case "integer":
+		f, ok := number(doc)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func typeOf(doc interface{}) string {
+	switch doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if _, ok := number(doc); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", doc)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func equal(a, b interface{}) bool {
+	fa, aok := number(a)
+	fb, bok := number(b)
+	if aok && bok {
+		return fa == fb
+	}
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, x := range t {
+			out[k] = normalize(x)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, x := range t {
+			out[i] = normalize(x)
+		}
+		return out
+	}
+	return v
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,148 @@
+package jsonschema_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/onmetahq/meta-http/pkg/jsonschema"
+)
+
+const paymentSchema = `{
+	"type": "object",
+	"required": ["id", "amount", "status"],
+	"properties": {
+		"id": {"type": "string", "pattern": "^pay_"},
+		"amount": {"$ref": "#/definitions/Money"},
+		"status": {"enum": ["pending", "settled"]},
+		"email": {"type": "string", "format": "email"},
+		"refunds": {"type": "array", "items": {"$ref": "#/definitions/Refund"}},
+		"note": {"type": "string", "nullable": true}
+	},
+	"additionalProperties": false,
+	"definitions": {
+		"Money": {
+			"type": "object",
+			"required": ["value", "currency"],
+			"properties": {
+				"value": {"type": "integer", "minimum": 0},
+				"currency": {"type": "string", "minLength": 3, "maxLength": 3}
+			}
+		},
+		"Refund": {
+			"type": "object",
+			"required": ["amount"],
+			"properties": {"amount": {"$ref": "#/definitions/Money"}}
+		}
+	}
+}`
+
+func TestValidateJSON(t *testing.T) {
+	schema := jsonschema.MustCompile([]byte(paymentSchema))
+
+	tcs := []struct {
+		name string
+		doc  string
+		// violations lists the expected violations as "path: message"
+		// substrings; none when empty.
+		violations []string
+	}{
+		{
+			name: "valid",
+			doc:  `{"id":"pay_1","amount":{"value":100,"currency":"EUR"},"status":"settled","note":null}`,
+		},
+		{
+			name:       "wrong type",
+			doc:        `{"id":1,"amount":{"value":100,"currency":"EUR"},"status":"settled"}`,
+			violations: []string{"/id: expected string, got number"},
+		},
+		{
+			name:       "integer",
+			doc:        `{"id":"pay_1","amount":{"value":1.5,"currency":"EUR"},"status":"settled"}`,
+			violations: []string{"/amount/value: expected integer, got number"},
+		},
+		{
+			name:       "not an object",
+			doc:        `["pay_1"]`,
+			violations: []string{"/: expected object, got array"},
+		},
+		{
+			name:       "missing required",
+			doc:        `{"id":"pay_1","amount":{"value":100}}`,
+			violations: []string{`/: missing required property "status"`, `/amount: missing required property "currency"`},
+		},
+		{
+			name:       "enum",
+			doc:        `{"id":"pay_1","amount":{"value":100,"currency":"EUR"},"status":"lost"}`,
+			violations: []string{"/status: value is not one of the allowed values"},
+		},
+		{
+			// format is not supported and thus not checked.
+			name: "format",
+			doc:  `{"id":"pay_1","amount":{"value":100,"currency":"EUR"},"status":"settled","email":"not an email"}`,
+		},
+		{
+			name:       "pattern",
+			doc:        `{"id":"ord_1","amount":{"value":100,"currency":"EUR"},"status":"settled"}`,
+			violations: []string{`/id: string does not match pattern "^pay_"`},
+		},
+		{
+			name:       "nested ref",
+			doc:        `{"id":"pay_1","amount":{"value":100,"currency":"EUR"},"status":"settled","refunds":[{"amount":{"value":-1,"currency":"EURO"}}]}`,
+			violations: []string{"/refunds/0/amount/value: value below minimum 0", "/refunds/0/amount/currency: string longer than 3"},
+		},
+		{
+			name:       "additional property",
+			doc:        `{"id":"pay_1","amount":{"value":100,"currency":"EUR"},"status":"settled","extra":true}`,
+			violations: []string{"/extra: additional property not allowed"},
+		},
+		{
+			name:       "invalid json",
+			doc:        `{"id":`,
+			violations: []string{"/: invalid json"},
+		},
+	}
+
+	for _, tc := range tcs {
+		err := schema.ValidateJSON([]byte(tc.doc))
+		if len(tc.violations) == 0 {
+			if err != nil {
+				t.Errorf("%s: expected no violation, got %v", tc.name, err)
+			}
+			continue
+		}
+		var validationErr *jsonschema.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: expected a validation error, got %v", tc.name, err)
+			continue
+		}
+		if len(validationErr.Violations) != len(tc.violations) {
+			t.Errorf("%s: expected %d violations, got %v", tc.name, len(tc.violations), err)
+		}
+		for _, want := range tc.violations {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: expected a violation %q, got %v", tc.name, want, err)
+			}
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tcs := []struct {
+		schema string
+		err    string
+	}{
+		{`{"type": "string", "pattern": "("}`, "invalid pattern"},
+		{`{"type": `, "jsonschema:"},
+	}
+	for _, tc := range tcs {
+		if _, err := jsonschema.Compile([]byte(tc.schema)); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", tc.schema, tc.err, err)
+		}
+	}
+
+	schema := jsonschema.MustCompile([]byte(`{"$ref": "#/definitions/Missing"}`))
+	if err := schema.ValidateJSON([]byte(`{}`)); err == nil || !strings.Contains(err.Error(), `unresolvable $ref "#/definitions/Missing"`) {
+		t.Errorf("expected an unresolvable ref, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Package logruslog adapts a logrus logger to metahttp.Logger.
+package logruslog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/sirupsen/logrus"
+)
+
+type logger struct {
+	l logrus.FieldLogger
+}
+
+// New returns a metahttp.Logger writing to l, e.g. a *logrus.Logger or a
+// *logrus.Entry with fields attached. slog style key/value pairs and
+// attributes become logrus fields.
+func New(l logrus.FieldLogger) metahttp.Logger {
+	return logger{l: l}
+}
+
+func (l logger) DebugContext(ctx context.Context, msg string, args ...any) {
+	if l.enabled(logrus.DebugLevel) {
+		l.entry(ctx, args).Debug(msg)
+	}
+}
+
+func (l logger) InfoContext(ctx context.Context, msg string, args ...any) {
+	if l.enabled(logrus.InfoLevel) {
+		l.entry(ctx, args).Info(msg)
+	}
+}
+
+func (l logger) WarnContext(ctx context.Context, msg string, args ...any) {
+	if l.enabled(logrus.WarnLevel) {
+		l.entry(ctx, args).Warn(msg)
+	}
+}
+
+func (l logger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	if l.enabled(logrus.ErrorLevel) {
+		l.entry(ctx, args).Error(msg)
+	}
+}
+
+// enabled reports whether entries at level are logged, so that fields are
+// only built for those.
+func (l logger) enabled(level logrus.Level) bool {
+	switch v := l.l.(type) {
+	case *logrus.Entry:
+		return v.Logger.IsLevelEnabled(level)
+	case interface{ IsLevelEnabled(logrus.Level) bool }:
+		return v.IsLevelEnabled(level)
+	}
+	return true
+}
+
+func (l logger) entry(ctx context.Context, args []any) *logrus.Entry {
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "", 0)
+	r.Add(args...)
+	fields := make(logrus.Fields, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields[a.Key] = a.Value.Resolve().Any()
+		return true
+	})
+	return l.l.WithFields(fields).WithContext(ctx)
+}
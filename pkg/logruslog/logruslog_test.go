@@ -0,0 +1,69 @@
+package logruslog_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/logruslog"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogrusLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logrus.DebugLevel)
+	client := metahttp.NewClient(server.URL, logruslog.New(log), 5*time.Second)
+	if _, err := client.Get(context.Background(), "/orders", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var entry *logrus.Entry
+	for _, e := range hook.AllEntries() {
+		if e.Message == "Call Ended" {
+			entry = e
+		}
+	}
+	if entry == nil {
+		t.Fatalf("no Call Ended entry in %d entries", len(hook.AllEntries()))
+	}
+	if entry.Data["path"] != "/orders" || entry.Data["status"] != int64(http.StatusNoContent) {
+		t.Errorf("unexpected fields: %v", entry.Data)
+	}
+}
+
+type countingValuer struct{ resolved *int }
+
+func (v countingValuer) LogValue() slog.Value {
+	*v.resolved++
+	return slog.StringValue("value")
+}
+
+func TestLogrusLoggerLevels(t *testing.T) {
+	base, hook := test.NewNullLogger()
+	base.SetLevel(logrus.InfoLevel)
+
+	for _, l := range []logrus.FieldLogger{base, base.WithField("service", "payments")} {
+		hook.Reset()
+		log := logruslog.New(l)
+		resolved := 0
+		log.DebugContext(context.Background(), "skipped", slog.Any("v", countingValuer{&resolved}))
+		log.InfoContext(context.Background(), "logged", slog.Any("v", countingValuer{&resolved}))
+
+		if resolved != 1 {
+			t.Errorf("%T: expected fields to be built for enabled levels only, resolved %d times", l, resolved)
+		}
+		if entries := hook.AllEntries(); len(entries) != 1 || entries[0].Data["v"] != "value" {
+			t.Errorf("%T: unexpected entries %+v", l, entries)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Package masking redacts personal and cardholder data from bodies before
+// they are logged, recorded or audited.
+package masking
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const Redacted = "[REDACTED]"
+
+// Pattern finds sensitive values inside free text or JSON strings.
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+	// Valid filters out false positives, e.g. digit runs failing a checksum.
+	// Nil accepts every match.
+	Valid func(match string) bool
+	// Mask returns the replacement of a match, Redacted when nil.
+	Mask func(match string) string
+}
+
+var (
+	// PAN matches Luhn valid card numbers of 13 to 19 digits, optionally
+	// grouped by spaces or dashes, keeping the last four digits.
+	PAN = Pattern{
+		Name:   "pan",
+		Regexp: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Valid:  func(m string) bool { return luhn(digits(m)) },
+		Mask: func(m string) string {
+			d := digits(m)
+			return strings.Repeat("*", len(d)-4) + d[len(d)-4:]
+		},
+	}
+	// Aadhaar matches Verhoeff valid 12 digit Aadhaar numbers.
+	Aadhaar = Pattern{
+		Name:   "aadhaar",
+		Regexp: regexp.MustCompile(`\b[2-9]\d{3}[ -]?\d{4}[ -]?\d{4}\b`),
+		Valid:  func(m string) bool { return verhoeff(digits(m)) },
+	}
+	Email = Pattern{
+		Name:   "email",
+		Regexp: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	}
+)
+
+// DefaultFields are JSON field names masked by Default.
+var DefaultFields = []string{
+	"password", "secret", "token", "access_token", "refresh_token",
+	"cvv", "cvc", "pin", "otp", "card_number", "account_number",
+}
+
+// Masker redacts JSON fields selected by name or path, and any text matching
+// its patterns. The zero value masks nothing; configure it with Fields, Paths
+// and Patterns before sharing it between goroutines.
+type Masker struct {
+	fields   map[string]bool
+	paths    [][]string
+	patterns []Pattern
+}
+
+func New() *Masker {
+	return &Masker{fields: map[string]bool{}}
+}
+
+// Default masks DefaultFields and the PAN, Aadhaar and Email patterns.
+func Default() *Masker {
+	return New().Fields(DefaultFields...).Patterns(PAN, Aadhaar, Email)
+}
+
+// Fields masks the values of object members with any of names, at any
+// depth, ignoring case.
+func (m *Masker) Fields(names ...string) *Masker {
+	if m.fields == nil {
+		m.fields = map[string]bool{}
+	}
+	for _, n := range names {
+		m.fields[strings.ToLower(n)] = true
+	}
+	return m
+}
+
+// Paths masks values at JSONPath-like paths such as "$.card.number" or
+// "$.items[*].holder". "*" matches any member or element.
+func (m *Masker) Paths(paths ...string) *Masker {
+	for _, p := range paths {
+		m.paths = append(m.paths, parsePath(p))
+	}
+	return m
+}
+
+func (m *Masker) Patterns(patterns ...Pattern) *Masker {
+	m.patterns = append(m.patterns, patterns...)
+	return m
+}
+
+// MaskText replaces every pattern match in s.
+func (m *Masker) MaskText(s string) string {
+	for _, p := range m.patterns {
+		s = p.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if p.Valid != nil && !p.Valid(match) {
+				return match
+			}
+			if p.Mask != nil {
+				return p.Mask(match)
+			}
+			return Redacted
+		})
+	}
+	return s
+}
+
+// Mask redacts body. JSON documents are masked field by field and
+// re-encoded (object members end up sorted); anything else is masked as
+// text.
+func (m *Masker) Mask(body []byte) []byte {
+	if m == nil || len(body) == 0 {
+		return body
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return []byte(m.MaskText(string(body)))
+	}
+	masked, err := json.Marshal(m.maskValue(doc, []string{}))
+	if err != nil {
+		return []byte(m.MaskText(string(body)))
+	}
+	return masked
+}
+
+func (m *Masker) maskValue(v interface{}, path []string) interface{} {
+	if m.matchPath(path) {
+		return Redacted
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if m.fields[strings.ToLower(k)] {
+				val[k] = Redacted
+				continue
+			}
+			val[k] = m.maskValue(child, append(path, k))
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = m.maskValue(child, append(path, "[]"))
+		}
+		return val
+	case string:
+		return m.MaskText(val)
+	case json.Number:
+		if masked := m.MaskText(val.String()); masked != val.String() {
+			return masked
+		}
+		return val
+	}
+	return v
+}
+
+func (m *Masker) matchPath(path []string) bool {
+	for _, p := range m.paths {
+		if len(p) != len(path) {
+			continue
+		}
+		match := true
+		for i := range p {
+			if p[i] != "*" && p[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePath splits "$.items[*].holder" into ["items", "[]", "holder"].
+func parsePath(p string) []string {
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+	p = strings.ReplaceAll(p, "[*]", ".[]")
+	var parts []string
+	for _, part := range strings.Split(p, ".") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func luhn(d string) bool {
+	sum := 0
+	double := false
+	for i := len(d) - 1; i >= 0; i-- {
+		n := int(d[i] - '0')
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+		double = !double
+	}
+	return len(d) > 0 && sum%10 == 0
+}
+
+var (
+	verhoeffD = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffP = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+)
+
+func verhoeff(d string) bool {
+	c := 0
+	for i := 0; i < len(d); i++ {
+		n := int(d[len(d)-1-i] - '0')
+		c = verhoeffD[c][verhoeffP[i%8][n]]
+	}
+	return len(d) > 0 && c == 0
+}
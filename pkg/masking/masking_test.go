@@ -0,0 +1,40 @@
+package masking_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onmetahq/meta-http/pkg/masking"
+)
+
+func TestMaskJSON(t *testing.T) {
+	masker := masking.Default().Paths("$.customer.name", "$.items[*].holder")
+	body := []byte(`{
+		"password": "hunter2",
+		"customer": {"name": "Asha", "email": "asha@example.com", "CVV": 987},
+		"items": [{"holder": "Asha", "card": "4111 1111 1111 1111"}],
+		"order_id": "1234567890123",
+		"note": "aadhaar 2341 2341 2346 on file"
+	}`)
+
+	got := string(masker.Mask(body))
+	for _, leaked := range []string{"hunter2", "Asha", "asha@example.com", "987", "4111 1111 1111 1111", "2341 2341 2346"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("%q was not masked: %s", leaked, got)
+		}
+	}
+	if !strings.Contains(got, `"************1111"`) {
+		t.Errorf("card numbers should keep their last four digits: %s", got)
+	}
+	if !strings.Contains(got, `"order_id":"1234567890123"`) {
+		t.Errorf("numbers failing the Luhn check should be kept: %s", got)
+	}
+}
+
+func TestMaskText(t *testing.T) {
+	masker := masking.Default()
+	got := string(masker.Mask([]byte("card=4111111111111111&email=a.b@example.org")))
+	if got != "card=************1111&email="+masking.Redacted {
+		t.Errorf("unexpected masked text: %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+package metahttp
+
+import (
+	"net/http"
+
+	"github.com/onmetahq/meta-http/pkg/utils"
+)
+
+// AsHTTPClient returns an *http.Client sharing the client's transport stack,
+// for third-party SDKs that only accept one: their calls get the headers and
+// caller tag carried by the request context, logging, retries, stats and
+// every other configured behaviour. Headers the SDK sets win over those from
+// the context, and neither default headers nor JSON Content-Type and Accept
+// are added, as the SDK owns the format of its requests. The returned client
+// shares the client's connections and is closed with it.
+func (c *client) AsHTTPClient() *http.Client {
+	return &http.Client{
+		Transport:     contextHeadersRoundTripper{next: c.HTTPClient.Transport},
+		Timeout:       c.HTTPClient.Timeout,
+		CheckRedirect: c.HTTPClient.CheckRedirect,
+	}
+}
+
+// contextHeadersRoundTripper adds the headers of the request context, caller
+// tag included, the request does not set itself.
+type contextHeadersRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (t contextHeadersRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	var cloned *http.Request
+	set := func(key, val string) {
+		if r.Header.Get(key) != "" {
+			return
+		}
+		// RoundTrippers must not modify the request they are given.
+		if cloned == nil {
+			cloned = r.Clone(r.Context())
+		}
+		cloned.Header.Set(key, val)
+	}
+	utils.EachHeaderFromContext(r.Context(), set)
+	if caller := CallerFromContext(r.Context()); caller != "" {
+		set(CallerHeader, caller)
+	}
+	if cloned != nil {
+		r = cloned
+	}
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,64 @@
+package metahttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestAsHTTPClient(t *testing.T) {
+	var got []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+		if len(got) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClientWithRetry(server.URL, nil, 5*time.Second, models.Retry{
+		MaxRetries: 2,
+		Validator:  func(status int) bool { return status < 500 },
+	}, metahttp.WithoutLogging())
+	client.SetDefaultHeaders(map[string]string{"X-Api-Version": "2"})
+	httpClient := client.AsHTTPClient()
+
+	ctx := context.WithValue(context.Background(), models.RequestID, "req-1")
+	ctx = context.WithValue(ctx, models.UserID, "user-1")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/v1/charges", strings.NewReader("amount=100"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(string(models.UserID), "sdk-user")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent || len(got) != 2 {
+		t.Fatalf("expected the call to be retried once, got status %d after %d attempts", res.StatusCode, len(got))
+	}
+	h := got[1]
+	if h.Get(string(models.RequestID)) != "req-1" {
+		t.Errorf("expected the context request id, got %q", h.Get(string(models.RequestID)))
+	}
+	if h.Get(string(models.UserID)) != "sdk-user" {
+		t.Errorf("expected the SDK header to win, got %q", h.Get(string(models.UserID)))
+	}
+	if h.Get("Content-Type") != "application/x-www-form-urlencoded" || h.Get("X-Api-Version") != "" {
+		t.Errorf("expected the SDK to own its request format, got %v", h)
+	}
+	if req.Header.Get(string(models.RequestID)) != "" {
+		t.Error("the SDK request was modified")
+	}
+	if client.Stats().Retries != 1 {
+		t.Errorf("expected the retry to be counted, got %+v", client.Stats())
+	}
+}
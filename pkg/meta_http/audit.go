@@ -0,0 +1,236 @@
+package metahttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/masking"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// AuditRecord describes one request attempt exchanged with a partner. Bodies
+// are only represented by their SHA-256 digests so records can be retained
+// without holding cardholder data.
+type AuditRecord struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Duration  time.Duration `json:"duration"`
+	// Caller is the models.UserID of the context, if any.
+	Caller    string `json:"caller,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Method    string `json:"method"`
+	URL       string `json:"url"`
+	// Status is 0 when no response was received.
+	Status int `json:"status"`
+	// RequestDigest and ResponseDigest are hex encoded SHA-256 digests, empty
+	// when there was no body.
+	RequestDigest  string `json:"request_digest,omitempty"`
+	ResponseDigest string `json:"response_digest,omitempty"`
+	ResponseSize   int64  `json:"response_size"`
+	// Partial is set when the caller closed the response body with more than
+	// 1MiB left unread, ResponseDigest and ResponseSize then covering what
+	// was read.
+	Partial bool `json:"partial,omitempty"`
+	// RequestBody and ResponseBody hold the bodies after masking, and are
+	// only set when the client was configured WithMasking. Bodies over 64KiB
+	// are left out, as their cut JSON could not be masked field by field.
+	RequestBody  string `json:"request_body,omitempty"`
+	ResponseBody string `json:"response_body,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+const (
+	auditBodyLimit  = 64 << 10
+	auditDrainLimit = 1 << 20
+)
+
+// AuditRecorder receives a record for every request attempt, e.g. to publish
+// it to Kafka or batch it to S3. Records are delivered once the response
+// body is closed, so Record must not block for long.
+type AuditRecorder interface {
+	Record(ctx context.Context, rec AuditRecord) error
+}
+
+// AuditRecorderFunc adapts a plain function to the AuditRecorder interface.
+type AuditRecorderFunc func(ctx context.Context, rec AuditRecord) error
+
+func (f AuditRecorderFunc) Record(ctx context.Context, rec AuditRecord) error {
+	return f(ctx, rec)
+}
+
+type auditRoundTripper struct {
+	next     http.RoundTripper
+	recorder AuditRecorder
+	masker   *masking.Masker
+	logger   Logger
+}
+
+func (a auditRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	rec := AuditRecord{
+		Timestamp: time.Now(),
+		Caller:    contextString(ctx, models.UserID),
+		TenantID:  contextString(ctx, models.TenantID),
+		RequestID: r.Header.Get(string(models.RequestID)),
+		Method:    r.Method,
+		URL:       r.URL.Redacted(),
+	}
+	var reqBody []byte
+	rec.RequestDigest, reqBody = requestDigest(r, a.masker != nil)
+	if a.masker != nil {
+		rec.RequestBody = string(a.masker.Mask(reqBody))
+	}
+
+	res, err := a.next.RoundTrip(r)
+	if err != nil {
+		rec.Duration = time.Since(rec.Timestamp)
+		rec.Error = err.Error()
+		a.record(ctx, rec)
+		return res, err
+	}
+
+	rec.Status = res.StatusCode
+	res.Body = &auditBody{
+		ReadCloser: res.Body,
+		hash:       sha256.New(),
+		keep:       a.masker != nil,
+		finish: func(body *auditBody) {
+			rec.Duration = time.Since(rec.Timestamp)
+			rec.ResponseSize = body.size
+			rec.Partial = body.partial
+			if body.size > 0 {
+				rec.ResponseDigest = hex.EncodeToString(body.hash.Sum(nil))
+			}
+			if body.readErr != nil {
+				rec.Error = body.readErr.Error()
+			}
+			if a.masker != nil && body.size <= auditBodyLimit {
+				rec.ResponseBody = string(a.masker.Mask(body.kept.Bytes()))
+			}
+			a.record(ctx, rec)
+		},
+	}
+	return res, nil
+}
+
+func (a auditRoundTripper) record(ctx context.Context, rec AuditRecord) {
+	if err := a.recorder.Record(context.WithoutCancel(ctx), rec); err != nil {
+		loggerFor(ctx, a.logger).ErrorContext(
+			ctx,
+			"Audit record failed",
+			slog.String("path", rec.URL),
+			slog.Any("error", err.Error()),
+			slog.String(string(models.RequestID), rec.RequestID),
+		)
+	}
+}
+
+// requestDigest hashes the request body, also returning it when keep is set
+// and it is no larger than auditBodyLimit. Bodies compressed by the client
+// are decompressed first, so digests and masked bodies do not depend on
+// WithRequestCompression.
+func requestDigest(r *http.Request, keep bool) (string, []byte) {
+	if r.GetBody == nil || r.Body == nil || r.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return "", nil
+	}
+	defer body.Close()
+
+	var src io.Reader = body
+	if encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding != "" {
+		dec, err := newDecoder(encoding, body)
+		if err != nil {
+			return "", nil
+		}
+		defer dec.Close()
+		src = dec
+	}
+
+	h := sha256.New()
+	var kept bytes.Buffer
+	var w io.Writer = h
+	if keep {
+		w = io.MultiWriter(h, &limitedWriter{buf: &kept, limit: auditBodyLimit})
+	}
+	n, err := io.Copy(w, src)
+	if err != nil || n == 0 {
+		return "", nil
+	}
+	if n > auditBodyLimit {
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	return hex.EncodeToString(h.Sum(nil)), kept.Bytes()
+}
+
+// limitedWriter keeps the first limit bytes written and discards the rest.
+type limitedWriter struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.buf.Len(); room > 0 {
+		if len(p) > room {
+			w.buf.Write(p[:room])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func contextString(ctx context.Context, key interface{}) string {
+	s, _ := ctx.Value(key).(string)
+	return s
+}
+
+// auditBody hashes the response while it is read. The record is finished on
+// Close, draining up to auditDrainLimit bytes the caller left unread so the
+// digest covers the whole body.
+type auditBody struct {
+	io.ReadCloser
+	hash    hash.Hash
+	size    int64
+	readErr error
+	partial bool
+	keep    bool
+	kept    bytes.Buffer
+	finish  func(body *auditBody)
+	once    sync.Once
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	if b.keep {
+		(&limitedWriter{buf: &b.kept, limit: auditBodyLimit}).Write(p[:n])
+	}
+	b.size += int64(n)
+	if err != nil && err != io.EOF {
+		b.readErr = err
+	}
+	return n, err
+}
+
+func (b *auditBody) Close() error {
+	b.once.Do(func() {
+		if b.readErr == nil {
+			n, _ := io.Copy(io.Discard, io.LimitReader(b, auditDrainLimit+1))
+			b.partial = n > auditDrainLimit
+		}
+		b.finish(b)
+	})
+	return b.ReadCloser.Close()
+}
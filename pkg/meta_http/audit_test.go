@@ -0,0 +1,152 @@
+package metahttp_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/masking"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestAuditRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"id":"pay_1"}`))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var records []metahttp.AuditRecord
+	recorder := metahttp.AuditRecorderFunc(func(ctx context.Context, rec metahttp.AuditRecord) error {
+		mu.Lock()
+		records = append(records, rec)
+		mu.Unlock()
+		return nil
+	})
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithAuditRecorder(recorder))
+
+	ctx := context.WithValue(context.Background(), models.UserID, "user-42")
+	var res map[string]string
+	if _, err := client.Post(ctx, "/payments", nil, map[string]string{"pan": "4111111111111111"}, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	rec := records[0]
+	reqDigest := sha256.Sum256([]byte(`{"pan":"4111111111111111"}`))
+	resDigest := sha256.Sum256([]byte(`{"id":"pay_1"}`))
+	if rec.Caller != "user-42" || rec.Method != http.MethodPost || rec.Status != http.StatusOK || rec.URL != server.URL+"/payments" {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+	if rec.RequestDigest != hex.EncodeToString(reqDigest[:]) || rec.ResponseDigest != hex.EncodeToString(resDigest[:]) {
+		t.Errorf("unexpected digests: %+v", rec)
+	}
+}
+
+func TestAuditRecorderMaskedBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"email":"asha@example.com"}`))
+	}))
+	defer server.Close()
+
+	records := make(chan metahttp.AuditRecord, 1)
+	recorder := metahttp.AuditRecorderFunc(func(ctx context.Context, rec metahttp.AuditRecord) error {
+		records <- rec
+		return nil
+	})
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second,
+		metahttp.WithAuditRecorder(recorder),
+		metahttp.WithMasking(masking.Default()),
+	)
+
+	if _, err := client.Post(context.Background(), "/payments", nil, map[string]string{"pan": "4111111111111111"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	rec := <-records
+	if rec.RequestBody != `{"pan":"************1111"}` {
+		t.Errorf("unexpected request body: %s", rec.RequestBody)
+	}
+	if rec.ResponseBody != `{"email":"[REDACTED]"}` {
+		t.Errorf("unexpected response body: %s", rec.ResponseBody)
+	}
+}
+
+func TestAuditRecorderLeavesOutLargeBodies(t *testing.T) {
+	large := `{"password":"hunter2","items":"` + strings.Repeat("x", 70<<10) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(large))
+	}))
+	defer server.Close()
+
+	records := make(chan metahttp.AuditRecord, 1)
+	recorder := metahttp.AuditRecorderFunc(func(ctx context.Context, rec metahttp.AuditRecord) error {
+		records <- rec
+		return nil
+	})
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithAuditRecorder(recorder),
+		metahttp.WithMasking(masking.New().Fields("password")),
+	)
+
+	if _, err := client.Post(context.Background(), "/payments", nil, json.RawMessage(large), nil); err != nil {
+		t.Fatal(err)
+	}
+	rec := <-records
+	if rec.RequestBody != "" || rec.ResponseBody != "" || rec.RequestDigest == "" || rec.ResponseSize != int64(len(large)) || rec.Partial {
+		t.Errorf("unexpected record: size %d, partial %v, bodies %d and %d bytes", rec.ResponseSize, rec.Partial, len(rec.RequestBody), len(rec.ResponseBody))
+	}
+}
+
+func TestAuditRecorderCompressedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	body := `{"pan":"4111111111111111"}`
+	digest := sha256.Sum256([]byte(body))
+	for _, encoding := range []string{metahttp.EncodingGzip, metahttp.EncodingBrotli, metahttp.EncodingZstd} {
+		records := make(chan metahttp.AuditRecord, 1)
+		recorder := metahttp.AuditRecorderFunc(func(ctx context.Context, rec metahttp.AuditRecord) error {
+			records <- rec
+			return nil
+		})
+		client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+			metahttp.WithAuditRecorder(recorder),
+			metahttp.WithMasking(masking.Default()),
+			metahttp.WithRequestCompression(encoding, 0),
+		)
+
+		if _, err := client.Post(context.Background(), "/payments", nil, json.RawMessage(body), nil); err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		rec := <-records
+		if rec.RequestDigest != hex.EncodeToString(digest[:]) {
+			t.Errorf("%s: expected the digest of the uncompressed body, got %s", encoding, rec.RequestDigest)
+		}
+		if rec.RequestBody != `{"pan":"************1111"}` {
+			t.Errorf("%s: unexpected request body: %q", encoding, rec.RequestBody)
+		}
+	}
+}
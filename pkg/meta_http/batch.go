@@ -0,0 +1,214 @@
+package metahttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// ErrBatchPartMissing is the error of the batched requests the batch
+// response has no part for.
+var ErrBatchPartMissing = errors.New("no response part for batched request")
+
+// maxBatchPreamble bounds the lines skipped before the first boundary of a
+// batch response.
+const maxBatchPreamble = 64
+
+// BatchRequest is a call sent within a Batch.
+type BatchRequest struct {
+	Method string
+	// Path is the request target of the call, sent as-is, e.g.
+	// "/v1/reports/42?fields=id".
+	Path   string
+	Header http.Header
+	// Body is sent JSON encoded, none when nil.
+	Body interface{}
+	// Result receives the JSON body of a successful response when not nil.
+	Result interface{}
+}
+
+// BatchResponse is the outcome of a BatchRequest.
+type BatchResponse struct {
+	StatusCode int
+	Header     http.Header
+	// Err is a *models.HttpClientErrorResponse for unsuccessful responses,
+	// the error decoding Result, or ErrBatchPartMissing.
+	Err error
+}
+
+// Batch sends reqs as the parts of a single multipart/mixed POST to path,
+// as Google-style batch endpoints expect, and returns their responses in
+// the order of reqs. Response parts are matched to requests by Content-ID,
+// or by position when they have none, and decoded as they are read off the
+// connection. The returned error is that of the batch call itself.
+func Batch(ctx context.Context, c Requests, path string, reqs []BatchRequest, opts ...CallOption) ([]BatchResponse, error) {
+	body, contentType, err := encodeBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+	decoder := &batchDecoder{reqs: reqs, responses: make([]BatchResponse, len(reqs))}
+	opts = append(opts[:len(opts):len(opts)], WithContentType(contentType), WithAccept("multipart/mixed"))
+	if _, err := c.Post(ctx, path, nil, body, decoder, opts...); err != nil {
+		return nil, err
+	}
+	for i := range decoder.responses {
+		if !decoder.seen(i) {
+			decoder.responses[i].Err = ErrBatchPartMissing
+		}
+	}
+	return decoder.responses, nil
+}
+
+func encodeBatch(reqs []BatchRequest) ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for i, req := range reqs {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"application/http"},
+			"Content-Id":   {"<item-" + strconv.Itoa(i+1) + ">"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		method := req.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		fmt.Fprintf(part, "%s %s HTTP/1.1\r\n", strings.ToUpper(method), req.Path)
+		header := req.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		var payload []byte
+		if req.Body != nil {
+			if payload, err = json.Marshal(req.Body); err != nil {
+				return nil, "", fmt.Errorf("batch request %d: %w", i+1, err)
+			}
+			header.Set("Content-Type", "application/json")
+			header.Set("Content-Length", strconv.Itoa(len(payload)))
+		}
+		if err := header.Write(part); err != nil {
+			return nil, "", err
+		}
+		io.WriteString(part, "\r\n")
+		part.Write(payload)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "multipart/mixed; boundary=" + mw.Boundary(), nil
+}
+
+// batchDecoder demultiplexes a batch response as a BodyDecoder, reading the
+// parts straight off the connection.
+type batchDecoder struct {
+	reqs      []BatchRequest
+	responses []BatchResponse
+	matched   []bool
+}
+
+func (d *batchDecoder) seen(i int) bool {
+	return i < len(d.matched) && d.matched[i]
+}
+
+// DecodeBody finds the boundary from the first delimiter line, so that the
+// body can be read without its Content-Type.
+func (d *batchDecoder) DecodeBody(r io.Reader) error {
+	d.matched = make([]bool, len(d.reqs))
+	br := bufio.NewReader(r)
+	var first string
+	for i := 0; ; i++ {
+		line, err := br.ReadString('\n')
+		if strings.HasPrefix(line, "--") {
+			first = line
+			break
+		}
+		if err != nil || i == maxBatchPreamble {
+			return fmt.Errorf("batch response: no multipart boundary found")
+		}
+	}
+	boundary := strings.TrimRight(first[2:], " \t\r\n")
+	mr := multipart.NewReader(io.MultiReader(strings.NewReader(first), br), boundary)
+
+	for position := 0; ; position++ {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("batch response: %w", err)
+		}
+		i := d.index(part.Header.Get("Content-Id"), position)
+		if i < 0 || d.matched[i] {
+			continue
+		}
+		d.matched[i] = true
+		d.responses[i] = d.decodePart(part, d.reqs[i].Method, d.reqs[i].Result)
+	}
+}
+
+// index resolves the request of a response part from its Content-ID, e.g.
+// "<response-item-2>", falling back to its position.
+func (d *batchDecoder) index(contentID string, position int) int {
+	id := strings.Trim(contentID, "<>")
+	if id == "" {
+		if position < len(d.reqs) {
+			return position
+		}
+		return -1
+	}
+	id = strings.TrimPrefix(id, "response-")
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "item-"))
+	if err != nil || n < 1 || n > len(d.reqs) {
+		return -1
+	}
+	return n - 1
+}
+
+func (d *batchDecoder) decodePart(part io.Reader, method string, result interface{}) BatchResponse {
+	req := &http.Request{Method: method}
+	res, err := http.ReadResponse(bufio.NewReader(part), req)
+	if err != nil {
+		return BatchResponse{Err: fmt.Errorf("batch response part: %w", err)}
+	}
+	defer res.Body.Close()
+	out := BatchResponse{StatusCode: res.StatusCode, Header: res.Header}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		out.Err = err
+		return out
+	}
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		errRes := &models.HttpClientErrorResponse{}
+		if json.Unmarshal(body, errRes) != nil || errRes.Err.Message == "" {
+			errRes.Err = models.ErrorInfo{
+				Message: fmt.Sprintf("unknown error, status code: %d, response: %s", res.StatusCode, body),
+			}
+		}
+		errRes.StatusCode = res.StatusCode
+		out.Err = errRes
+		return out
+	}
+	if result != nil && len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, result); err != nil {
+			out.Err = &models.HttpClientErrorResponse{
+				StatusCode: http.StatusInternalServerError,
+				Category:   models.CategoryDecode,
+				Err:        models.ErrorInfo{Message: err.Error()},
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package metahttp_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "multipart/mixed" || r.URL.Path != "/batch" {
+			t.Errorf("unexpected batch call %s %s", mediaType, r.URL.Path)
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		var ids, lines []string
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			sub, err := http.ReadRequest(bufio.NewReader(part))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(sub.Body)
+			ids = append(ids, part.Header.Get("Content-ID"))
+			lines = append(lines, fmt.Sprintf("%s %s %s %s", sub.Method, sub.URL, sub.Header.Get("X-Customer"), body))
+		}
+		want := []string{"GET /v1/reports/1  ", `POST /v1/reports c-7 {"name":"daily"}`, "GET /v1/reports/404  "}
+		if fmt.Sprint(lines) != fmt.Sprint(want) {
+			t.Errorf("unexpected sub-requests %q", lines)
+		}
+
+		// Parts are answered out of order and the third one is missing.
+		rw.Header().Set("Content-Type", "multipart/mixed; boundary=batch_resp")
+		fmt.Fprint(rw, "--batch_resp\r\n"+
+			"Content-Type: application/http\r\nContent-ID: <response-"+ids[1][1:len(ids[1])-1]+">\r\n\r\n"+
+			"HTTP/1.1 201 Created\r\nContent-Type: application/json\r\n\r\n{\"id\":\"r-2\"}\r\n"+
+			"--batch_resp\r\n"+
+			"Content-Type: application/http\r\nContent-ID: <response-"+ids[0][1:len(ids[0])-1]+">\r\n\r\n"+
+			"HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"id\":\"r-1\"}\r\n"+
+			"--batch_resp--\r\n")
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	var first, second struct{ ID string }
+	responses, err := metahttp.Batch(context.Background(), client, "/batch", []metahttp.BatchRequest{
+		{Path: "/v1/reports/1", Result: &first},
+		{Method: http.MethodPost, Path: "/v1/reports", Header: http.Header{"X-Customer": {"c-7"}}, Body: map[string]string{"name": "daily"}, Result: &second},
+		{Path: "/v1/reports/404"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if responses[0].StatusCode != http.StatusOK || first.ID != "r-1" || responses[1].StatusCode != http.StatusCreated || second.ID != "r-2" {
+		t.Errorf("unexpected responses %+v, %+v, %+v", responses, first, second)
+	}
+	if !errors.Is(responses[2].Err, metahttp.ErrBatchPartMissing) {
+		t.Errorf("expected the third part to be missing, got %v", responses[2].Err)
+	}
+}
+
+func TestBatchPartErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		// Parts without Content-ID are matched by position.
+		fmt.Fprint(rw, "--xyz\r\nContent-Type: application/http\r\n\r\n"+
+			"HTTP/1.1 404 Not Found\r\nContent-Type: application/json\r\n\r\n{\"error\":{\"code\":404,\"message\":\"no such report\"}}\r\n"+
+			"--xyz\r\nContent-Type: application/http\r\n\r\n"+
+			"HTTP/1.1 200 OK\r\n\r\nnot json\r\n"+
+			"--xyz--\r\n")
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	var report map[string]interface{}
+	responses, err := metahttp.Batch(context.Background(), client, "/batch", []metahttp.BatchRequest{
+		{Path: "/v1/reports/404"},
+		{Path: "/v1/reports/2", Result: &report},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hce *models.HttpClientErrorResponse
+	if !errors.As(responses[0].Err, &hce) || hce.StatusCode != http.StatusNotFound || hce.Err.Message != "no such report" {
+		t.Errorf("expected the part error, got %v", responses[0].Err)
+	}
+	if models.CategoryOf(responses[1].Err) != models.CategoryDecode {
+		t.Errorf("expected a decode error, got %v", responses[1].Err)
+	}
+}
@@ -0,0 +1,162 @@
+package metahttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// The benchmarks are named key=value so that benchstat can compare runs
+// along any dimension:
+//
+//	go test ./pkg/meta_http -run '^$' -bench . -benchmem -count 10 > new.txt
+//	benchstat old.txt new.txt
+//	benchstat -col /logging new.txt
+
+type benchOrder struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+	Items  []benchItem
+}
+
+type benchItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+}
+
+// benchSizes are the item counts of the small and large payloads, about
+// 40B and 200KB of JSON.
+var benchSizes = []struct {
+	name  string
+	items int
+}{
+	{"small", 0},
+	{"large", 4000},
+}
+
+func newBenchOrder(items int) benchOrder {
+	order := benchOrder{ID: "ord_1", Amount: 100}
+	for i := 0; i < items; i++ {
+		order.Items = append(order.Items, benchItem{SKU: fmt.Sprintf("sku_%d", i), Quantity: 1, Price: 25})
+	}
+	return order
+}
+
+// newBenchServer answers every call with payload, and with a 503 to every
+// other call when flaky.
+func newBenchServer(b *testing.B, payload []byte, flaky bool) *httptest.Server {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if flaky && calls.Add(1)%2 == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write(payload)
+	}))
+	b.Cleanup(server.Close)
+	return server
+}
+
+func newBenchClient(url string, logging, retries bool) metahttp.Requests {
+	var opts []metahttp.Option
+	var logger metahttp.Logger
+	if logging {
+		logger = slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	} else {
+		opts = append(opts, metahttp.WithoutLogging())
+	}
+	if !retries {
+		return metahttp.NewClient(url, logger, 5*time.Second, opts...)
+	}
+	return metahttp.NewClientWithRetry(url, logger, 5*time.Second, models.Retry{
+		MaxRetries: 2,
+		Validator:  func(status int) bool { return status < 500 },
+	}, opts...)
+}
+
+// benchVariants runs fn for every combination of payload size, logging and
+// retries. With retries every call fails once before succeeding.
+func benchVariants(b *testing.B, fn func(b *testing.B, client metahttp.Requests, order benchOrder)) {
+	for _, size := range benchSizes {
+		order := newBenchOrder(size.items)
+		payload, err := json.Marshal(order)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, logging := range []bool{false, true} {
+			for _, retries := range []bool{false, true} {
+				name := fmt.Sprintf("size=%s/logging=%s/retries=%s", size.name, onOff(logging), onOff(retries))
+				b.Run(name, func(b *testing.B) {
+					server := newBenchServer(b, payload, retries)
+					client := newBenchClient(server.URL, logging, retries)
+					b.SetBytes(int64(len(payload)))
+					b.ReportAllocs()
+					b.ResetTimer()
+					fn(b, client, order)
+				})
+			}
+		}
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func BenchmarkGet(b *testing.B) {
+	benchVariants(b, func(b *testing.B, client metahttp.Requests, _ benchOrder) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			var res benchOrder
+			if _, err := client.Get(ctx, "/orders/1", nil, &res); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPost(b *testing.B) {
+	benchVariants(b, func(b *testing.B, client metahttp.Requests, order benchOrder) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			var res benchOrder
+			if _, err := client.Post(ctx, "/orders", nil, order, &res); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkGetParallel measures contention on the shared transport chain.
+func BenchmarkGetParallel(b *testing.B) {
+	server := newBenchServer(b, []byte(`{"id":"ord_1","amount":100}`), false)
+	client := newBenchClient(server.URL, false, false)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var res benchOrder
+			if _, err := client.Get(ctx, "/orders/1", nil, &res); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
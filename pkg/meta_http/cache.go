@@ -0,0 +1,349 @@
+package metahttp
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// CacheStatusHeader is set on the responses of clients WithResponseCache to
+// CacheHit, CacheStale or CacheMiss.
+const CacheStatusHeader = "X-Cache"
+
+const (
+	CacheHit = "HIT"
+	// CacheStale responses are served past their TTL while being refreshed
+	// in the background.
+	CacheStale = "STALE"
+	CacheMiss  = "MISS"
+)
+
+const (
+	defaultCacheMaxEntries  = 1000
+	defaultCacheMaxBodySize = 1 << 20
+)
+
+// defaultCacheVary are the request headers telling callers apart, so that a
+// response is never served to a caller other than the one it was made for.
+var defaultCacheVary = []string{
+	"Authorization",
+	string(models.UserID), string(models.TenantID),
+	string(models.MerchantAPIKey), string(models.APIContextKey),
+}
+
+// negotiationHeaders tell apart the representations of a URL, whatever the
+// Vary of the cache: a CSV export must not be served the cached JSON.
+var negotiationHeaders = []string{"Accept", "Accept-Encoding"}
+
+// ResponseCache configures the in-memory cache of GET responses set with
+// WithResponseCache. Only 2xx responses are cached, and the statuses of
+// NegativeStatuses with NegativeTTL set, never those marked Cache-Control:
+// no-store.
+type ResponseCache struct {
+	// TTL is how long a response is served without asking the upstream.
+	// With neither TTL nor StaleWhileRevalidate set, only negative
+	// responses are cached.
+	TTL time.Duration
+	// StaleWhileRevalidate is how long past its TTL a response is still
+	// served, immediately, while a background call refreshes it. Zero
+	// makes callers wait for the upstream once the TTL is over.
+	StaleWhileRevalidate time.Duration
+	// MaxEntries bounds the number of cached responses, 1000 by default.
+	// The least recently used ones are evicted first.
+	MaxEntries int
+	// MaxBodySize bounds the size of cached bodies, 1MB by default. Larger
+	// responses are passed through uncached.
+	MaxBodySize int64
+	// NegativeTTL caches the responses whose status is listed in
+	// NegativeStatuses for this long, so that repeated lookups of missing
+	// resources do not reach the upstream. Zero disables negative caching.
+	// Negative entries are never served stale.
+	NegativeTTL time.Duration
+	// NegativeStatuses defaults to 404 Not Found and 410 Gone.
+	NegativeStatuses []int
+	// Vary lists the request headers responses are cached by on top of the
+	// URL. It defaults to the credential and identity headers, Authorization,
+	// user-id, tenant-id, x-api-key and apikey. Responses are always told
+	// apart by Accept and Accept-Encoding as well.
+	Vary []string
+}
+
+type noCacheKey struct{}
+
+// WithoutCache makes the call bypass the response cache and the memo of
+// ContextWithMemo, neither served from them nor stored in them.
+func WithoutCache() CallOption {
+	return func(co *callOptions) {
+		co.noCache = true
+	}
+}
+
+type cacheEntry struct {
+	key        [sha256.Size]byte
+	res        *http.Response
+	body       []byte
+	expires    time.Time
+	staleUntil time.Time
+	refreshing bool
+	elem       *list.Element
+}
+
+// response gives every caller its own copy of the cached response.
+func (e *cacheEntry) response(r *http.Request, status string) *http.Response {
+	res := *e.res
+	res.Header = e.res.Header.Clone()
+	res.Header.Set(CacheStatusHeader, status)
+	res.Body = io.NopCloser(bytes.NewReader(e.body))
+	res.ContentLength = int64(len(e.body))
+	res.Request = r
+	return &res
+}
+
+// cachingRoundTripper serves GET requests from a bounded LRU cache.
+type cachingRoundTripper struct {
+	next    http.RoundTripper
+	cfg     ResponseCache
+	timeout time.Duration
+	logger  Logger
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*cacheEntry
+	lru     *list.List
+}
+
+func newCachingRoundTripper(cfg ResponseCache, timeout time.Duration, log Logger, next http.RoundTripper) *cachingRoundTripper {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultCacheMaxEntries
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultCacheMaxBodySize
+	}
+	if cfg.Vary == nil {
+		cfg.Vary = defaultCacheVary
+	}
+	if cfg.NegativeStatuses == nil {
+		cfg.NegativeStatuses = []int{http.StatusNotFound, http.StatusGone}
+	}
+	return &cachingRoundTripper{
+		next:    next,
+		cfg:     cfg,
+		timeout: timeout,
+		logger:  log,
+		entries: map[[sha256.Size]byte]*cacheEntry{},
+		lru:     list.New(),
+	}
+}
+
+func (c *cachingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !c.cacheable(r) {
+		return c.next.RoundTrip(r)
+	}
+	key := requestKey(r, c.cfg.Vary)
+
+	entry, fresh, refresh := c.lookup(key, time.Now())
+	if entry != nil {
+		if refresh {
+			go c.refresh(r.Clone(r.Context()), key)
+		}
+		if fresh {
+			return entry.response(r, CacheHit), nil
+		}
+		return entry.response(r, CacheStale), nil
+	}
+
+	res, err := c.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.store(key, r, res)
+}
+
+func (c *cachingRoundTripper) cacheable(r *http.Request) bool {
+	if r.Method != http.MethodGet || r.Context().Value(noCacheKey{}) != nil {
+		return false
+	}
+	if r.Header.Get("Range") != "" || r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+		return false
+	}
+	cc := strings.ToLower(r.Header.Get("Cache-Control"))
+	return !strings.Contains(cc, "no-cache") && !strings.Contains(cc, "no-store")
+}
+
+// requestKey hashes the method, URL and vary headers of r, so that
+// credentials are not kept around in the clear.
+func requestKey(r *http.Request, vary []string) [sha256.Size]byte {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.String())
+	for _, name := range append(negotiationHeaders[:len(negotiationHeaders):len(negotiationHeaders)], vary...) {
+		io.WriteString(h, "\n"+name+":")
+		io.WriteString(h, strings.Join(r.Header.Values(name), ","))
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// lookup returns the entry of key if it can still be served, and whether
+// the caller must refresh it in the background.
+func (c *cachingRoundTripper) lookup(key [sha256.Size]byte, now time.Time) (entry *cacheEntry, fresh, refresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false, false
+	}
+	switch {
+	case now.Before(entry.expires):
+		fresh = true
+	case now.Before(entry.staleUntil):
+		refresh = !entry.refreshing
+		entry.refreshing = true
+	default:
+		c.remove(entry)
+		return nil, false, false
+	}
+	c.lru.MoveToFront(entry.elem)
+	return entry, fresh, refresh
+}
+
+// store caches res if it can be, and returns the response to hand to the
+// caller in its place.
+func (c *cachingRoundTripper) store(key [sha256.Size]byte, r *http.Request, res *http.Response) (*http.Response, error) {
+	ttl, stale, ok := c.ttl(res)
+	if !ok {
+		res.Header.Set(CacheStatusHeader, CacheMiss)
+		return res, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, c.cfg.MaxBodySize+1))
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > c.cfg.MaxBodySize {
+		res.Header.Set(CacheStatusHeader, CacheMiss)
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+		return res, nil
+	}
+	res.Body.Close()
+
+	// The entry must not retain the request, its context and credentials.
+	stored := *res
+	stored.Body, stored.Request = nil, nil
+	now := time.Now()
+	entry := &cacheEntry{
+		key:        key,
+		res:        &stored,
+		body:       body,
+		expires:    now.Add(ttl),
+		staleUntil: now.Add(ttl + stale),
+	}
+	c.mu.Lock()
+	if old, ok := c.entries[key]; ok {
+		c.remove(old)
+	}
+	entry.elem = c.lru.PushFront(entry)
+	c.entries[key] = entry
+	for c.lru.Len() > c.cfg.MaxEntries {
+		c.remove(c.lru.Back().Value.(*cacheEntry))
+	}
+	c.mu.Unlock()
+	return entry.response(r, CacheMiss), nil
+}
+
+// ttl returns how long res is cached and served stale, false when it is not
+// cached at all.
+func (c *cachingRoundTripper) ttl(res *http.Response) (ttl, stale time.Duration, ok bool) {
+	if strings.Contains(strings.ToLower(res.Header.Get("Cache-Control")), "no-store") {
+		return 0, 0, false
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		if c.cfg.TTL+c.cfg.StaleWhileRevalidate <= 0 {
+			return 0, 0, false
+		}
+		return c.cfg.TTL, c.cfg.StaleWhileRevalidate, true
+	}
+	if c.cfg.NegativeTTL > 0 {
+		for _, status := range c.cfg.NegativeStatuses {
+			if res.StatusCode == status {
+				return c.cfg.NegativeTTL, 0, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// remove must be called with mu held.
+func (c *cachingRoundTripper) remove(entry *cacheEntry) {
+	c.lru.Remove(entry.elem)
+	delete(c.entries, entry.key)
+}
+
+// refresh calls the upstream for a stale entry, outliving the call that
+// found it stale. A failed refresh keeps serving the stale entry until its
+// staleness bound, and so does a panicking one, which is logged: no caller
+// is left to recover it.
+func (c *cachingRoundTripper) refresh(r *http.Request, key [sha256.Size]byte) {
+	ctx := refreshContext(r.Context())
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	defer func() {
+		c.mu.Lock()
+		if entry, ok := c.entries[key]; ok {
+			entry.refreshing = false
+		}
+		c.mu.Unlock()
+	}()
+	defer func() {
+		if p := recover(); p != nil {
+			loggerFor(ctx, c.logger).ErrorContext(
+				ctx,
+				"Recovered from panic in cache refresh",
+				slog.String("path", r.URL.Path),
+				slog.String("host", r.URL.Host),
+				slog.Any("panic", p),
+				slog.String("stack", string(debug.Stack())),
+			)
+		}
+	}()
+
+	res, err := c.next.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		return
+	}
+	if res, err = c.store(key, r, res); err == nil {
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+}
+
+// refreshContext carries the call configuration found in ctx, such as its
+// logger and route, over to a context of the refresh's own. The retry
+// history and counters of the call that found the entry stale are left
+// out: that call has returned already.
+func refreshContext(ctx context.Context) context.Context {
+	fresh := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+	for _, key := range []interface{}{loggerKey{}, routeKey{}, callerKey{}, endpointKey{}, retryPolicyKey{}, priorityKey{}} {
+		if v := ctx.Value(key); v != nil {
+			fresh = context.WithValue(fresh, key, v)
+		}
+	}
+	return fresh
+}
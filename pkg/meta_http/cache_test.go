@@ -0,0 +1,234 @@
+package metahttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	var calls atomic.Int32
+	var version atomic.Value
+	version.Store("v1")
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 2 {
+			// The background refresh: a slow upstream nobody must wait for.
+			<-release
+		}
+		rw.Write([]byte(`{"version":"` + version.Load().(string) + `"}`))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithResponseCache(metahttp.ResponseCache{
+			TTL:                  50 * time.Millisecond,
+			StaleWhileRevalidate: time.Minute,
+		}))
+	ctx := context.Background()
+	get := func(opts ...metahttp.CallOption) (string, string) {
+		t.Helper()
+		var res struct{ Version string }
+		data, err := client.Get(ctx, "/currencies", nil, &res, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Version, data.Header.Get(metahttp.CacheStatusHeader)
+	}
+
+	if v, status := get(); v != "v1" || status != metahttp.CacheMiss {
+		t.Fatalf("expected a miss, got %s %s", v, status)
+	}
+	if v, status := get(); v != "v1" || status != metahttp.CacheHit || calls.Load() != 1 {
+		t.Fatalf("expected a hit, got %s %s after %d calls", v, status, calls.Load())
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	version.Store("v2")
+	started := time.Now()
+	if v, status := get(); v != "v1" || status != metahttp.CacheStale {
+		t.Fatalf("expected the stale copy, got %s %s", v, status)
+	}
+	if v, status := get(); v != "v1" || status != metahttp.CacheStale {
+		t.Fatalf("expected the stale copy while refreshing, got %s %s", v, status)
+	}
+	if time.Since(started) > time.Second {
+		t.Fatal("stale reads waited for the upstream")
+	}
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if v, status := get(); v == "v2" && status == metahttp.CacheHit {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the entry was not refreshed in the background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected a single refresh, got %d calls", calls.Load())
+	}
+
+	if _, status := get(metahttp.WithoutCache()); status != "" {
+		t.Errorf("expected the cache to be bypassed, got %s", status)
+	}
+	var res struct{ Version string }
+	data, err := client.Get(ctx, "/currencies", map[string]string{"Authorization": "Bearer other"}, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Header.Get(metahttp.CacheStatusHeader) != metahttp.CacheMiss {
+		t.Error("expected responses not to be shared across credentials")
+	}
+}
+
+func TestNegativeCaching(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/merchants/gone":
+			rw.WriteHeader(http.StatusGone)
+		case "/merchants/missing":
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"success":false,"error":{"message":"merchant not found"}}`))
+		case "/merchants/broken":
+			rw.WriteHeader(http.StatusInternalServerError)
+		default:
+			rw.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithResponseCache(metahttp.ResponseCache{NegativeTTL: 50 * time.Millisecond}))
+	ctx := context.Background()
+	get := func(path string) (string, error) {
+		data, err := client.Get(ctx, path, nil, &map[string]any{})
+		return data.Header.Get(metahttp.CacheStatusHeader), err
+	}
+
+	for _, path := range []string{"/merchants/missing", "/merchants/gone"} {
+		if status, err := get(path); err == nil || status != metahttp.CacheMiss {
+			t.Fatalf("%s: expected an uncached error, got %s, %v", path, status, err)
+		}
+		status, err := get(path)
+		if err == nil || status != metahttp.CacheHit {
+			t.Fatalf("%s: expected a cached error, got %s, %v", path, status, err)
+		}
+	}
+	if _, err := get("/merchants/missing"); err == nil || !strings.Contains(err.Error(), "merchant not found") {
+		t.Errorf("expected the cached error body to be decoded, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 upstream calls, got %d", calls.Load())
+	}
+
+	for _, path := range []string{"/merchants/broken", "/merchants/broken", "/merchants/1", "/merchants/1"} {
+		get(path)
+	}
+	if calls.Load() != 6 {
+		t.Errorf("expected other responses not to be cached, got %d calls", calls.Load())
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if status, _ := get("/merchants/missing"); status != metahttp.CacheMiss {
+		t.Errorf("expected the negative entry to expire, got %s", status)
+	}
+}
+
+func TestCacheKeyedOnAccept(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if strings.HasPrefix(r.Header.Get("Accept"), "text/csv") {
+			rw.Header().Set("Content-Type", "text/csv")
+			rw.Write([]byte("id\n1\n"))
+			return
+		}
+		rw.Write([]byte(`[{"id":1}]`))
+	}))
+	defer server.Close()
+
+	cached := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithResponseCache(metahttp.ResponseCache{TTL: time.Minute}))
+	plain := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	for _, tc := range []struct {
+		name   string
+		client metahttp.Requests
+		ctx    context.Context
+	}{
+		{"cache", cached, context.Background()},
+		{"memo", plain, metahttp.ContextWithMemo(context.Background())},
+	} {
+		calls.Store(0)
+		for i := 0; i < 2; i++ {
+			var rows []map[string]int
+			if _, err := tc.client.Get(tc.ctx, "/reports", nil, &rows); err != nil || len(rows) != 1 || rows[0]["id"] != 1 {
+				t.Fatalf("%s: json %v %v", tc.name, rows, err)
+			}
+			var csv string
+			if _, err := tc.client.Get(tc.ctx, "/reports", nil, &csv, metahttp.WithAccept("text/csv")); err != nil || csv != "id\n1\n" {
+				t.Fatalf("%s: csv %q %v", tc.name, csv, err)
+			}
+		}
+		if n := calls.Load(); n != 2 {
+			t.Errorf("%s: expected one call per representation, got %d", tc.name, n)
+		}
+	}
+}
+
+func TestStaleRefreshPanics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"version":"v1"}`))
+	}))
+	defer server.Close()
+
+	var signed atomic.Int32
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithResponseCache(metahttp.ResponseCache{
+			TTL:                  20 * time.Millisecond,
+			StaleWhileRevalidate: time.Minute,
+		}),
+		metahttp.WithSigner(metahttp.SignerFunc(func(r *http.Request) error {
+			if signed.Add(1) > 1 {
+				panic("signer bug")
+			}
+			return nil
+		})))
+	get := func() string {
+		t.Helper()
+		var res struct{ Version string }
+		data, err := client.Get(context.Background(), "/currencies", nil, &res)
+		if err != nil || res.Version != "v1" {
+			t.Fatalf("unexpected response %+v, %v", res, err)
+		}
+		return data.Header.Get(metahttp.CacheStatusHeader)
+	}
+
+	get()
+	time.Sleep(40 * time.Millisecond)
+	if status := get(); status != metahttp.CacheStale {
+		t.Fatalf("expected the stale copy, got %s", status)
+	}
+	// The panicking refresh leaves the process alive and the entry served,
+	// to be refreshed again by a later call.
+	deadline := time.Now().Add(2 * time.Second)
+	for signed.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("the entry was not refreshed again after a panic")
+		}
+		if status := get(); status != metahttp.CacheStale {
+			t.Fatalf("expected the stale copy, got %s", status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
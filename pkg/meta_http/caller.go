@@ -0,0 +1,32 @@
+package metahttp
+
+import "context"
+
+// CallerHeader carries the caller tag of a call to the upstream, so that
+// partner-side usage can be attributed too.
+const CallerHeader = "X-Caller"
+
+type callerKey struct{}
+
+// ContextWithCaller tags the calls made with ctx with caller, the product
+// feature or team making them, e.g. "checkout" or "payouts-reconciliation".
+// The tag is sent in CallerHeader, logged and counted in Stats.Callers, so
+// partner API usage and cost can be attributed to features. Keep the set of
+// tags small: each one gets its own counter.
+func ContextWithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller tag set by ContextWithCaller, empty
+// when there is none.
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// WithCaller tags a single call, replacing the tag of its context.
+func WithCaller(caller string) CallOption {
+	return func(co *callOptions) {
+		co.caller = caller
+	}
+}
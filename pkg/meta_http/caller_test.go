@@ -0,0 +1,53 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestCallerTags(t *testing.T) {
+	var callers []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		callers = append(callers, r.Header.Get(metahttp.CallerHeader))
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second)
+
+	ctx := metahttp.ContextWithCaller(context.Background(), "checkout")
+	for _, call := range []struct {
+		ctx  context.Context
+		opts []metahttp.CallOption
+	}{
+		{ctx, nil},
+		{ctx, nil},
+		{ctx, []metahttp.CallOption{metahttp.WithCaller("payouts")}},
+		{context.Background(), nil},
+	} {
+		if _, err := client.Get(call.ctx, "/quotes", nil, nil, call.opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if strings.Join(callers, ",") != "checkout,checkout,payouts," {
+		t.Errorf("unexpected caller headers %q", callers)
+	}
+	stats := client.Stats()
+	if len(stats.Callers) != 2 || stats.Callers["checkout"] != 2 || stats.Callers["payouts"] != 1 {
+		t.Errorf("unexpected caller counts %v", stats.Callers)
+	}
+	if !strings.Contains(logs.String(), `"caller":"payouts"`) {
+		t.Errorf("expected the caller to be logged:\n%s", logs.String())
+	}
+}
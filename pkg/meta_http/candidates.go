@@ -0,0 +1,83 @@
+package metahttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNoCandidate is returned by TryDecode when the body matches none of the
+// candidate targets.
+var ErrNoCandidate = errors.New("response matched no candidate type")
+
+// TryDecode decodes data into the first of targets it matches and returns
+// that target's index. A target matches when data decodes into it without
+// unknown fields and, should the target have a Validate() error method,
+// validation passes. Targets that did not match may be partially filled.
+func TryDecode(data []byte, targets ...interface{}) (int, error) {
+	errs := []error{ErrNoCandidate}
+	for i, target := range targets {
+		err := decodeCandidate(data, target)
+		if err == nil {
+			return i, nil
+		}
+		errs = append(errs, fmt.Errorf("candidate %d (%T): %w", i, target, err))
+	}
+	return -1, errors.Join(errs...)
+}
+
+func decodeCandidate(data []byte, target interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(target); err != nil {
+		return err
+	}
+	if v, ok := target.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// Candidates is a response target for APIs answering with one of several
+// shapes under the same status, e.g. an error object with a 200:
+//
+//	var order Order
+//	var failure PartnerError
+//	res := metahttp.OneOf(&order, &failure)
+//	if _, err := client.Get(ctx, "/orders/1", nil, res); err != nil {
+//		return err
+//	}
+//	if res.Matched == 1 {
+//		return failure
+//	}
+type Candidates struct {
+	Targets []interface{}
+	// Matched is the index in Targets of the target the body was decoded
+	// into, -1 before decoding.
+	Matched int
+}
+
+// OneOf returns a response target decoding into the first of targets the
+// body matches, as TryDecode does.
+func OneOf(targets ...interface{}) *Candidates {
+	return &Candidates{Targets: targets, Matched: -1}
+}
+
+func (c *Candidates) DecodeBody(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	c.Matched, err = TryDecode(data, c.Targets...)
+	return err
+}
+
+// Match returns the target the body was decoded into, nil if none.
+func (c *Candidates) Match() interface{} {
+	if c.Matched < 0 {
+		return nil
+	}
+	return c.Targets[c.Matched]
+}
@@ -0,0 +1,83 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+type partnerOrder struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+type partnerError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *partnerError) Validate() error {
+	if e.Code == "" {
+		return errors.New("missing code")
+	}
+	return nil
+}
+
+func TestOneOf(t *testing.T) {
+	bodies := map[string]string{
+		"/ok":      `{"id":"ord_1","amount":100}`,
+		"/failed":  `{"code":"E42","message":"card declined"}`,
+		"/unknown": `{"status":"pending"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(bodies[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second)
+
+	var order partnerOrder
+	var failure partnerError
+	res := metahttp.OneOf(&order, &failure)
+	if _, err := client.Get(context.Background(), "/ok", nil, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Matched != 0 || order.ID != "ord_1" {
+		t.Errorf("expected the order to match, got %d: %+v", res.Matched, order)
+	}
+
+	res = metahttp.OneOf(&partnerOrder{}, &failure)
+	if _, err := client.Get(context.Background(), "/failed", nil, res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Match() != &failure || failure.Code != "E42" {
+		t.Errorf("expected the error to match, got %d: %+v", res.Matched, failure)
+	}
+
+	res = metahttp.OneOf(&partnerOrder{}, &partnerError{})
+	_, err := client.Get(context.Background(), "/unknown", nil, res)
+	if models.CategoryOf(err) != models.CategoryDecode || res.Match() != nil {
+		t.Errorf("expected a decode error, got %v, matched %d", err, res.Matched)
+	}
+}
+
+func TestTryDecodeValidates(t *testing.T) {
+	// Empty objects decode into anything, Validate rejects them.
+	_, err := metahttp.TryDecode([]byte(`{}`), &partnerError{})
+	if !errors.Is(err, metahttp.ErrNoCandidate) {
+		t.Errorf("expected no candidate, got %v", err)
+	}
+	if i, err := metahttp.TryDecode([]byte(`{}`), &partnerError{}, &partnerOrder{}); err != nil || i != 1 {
+		t.Errorf("expected the second candidate, got %d, %v", i, err)
+	}
+}
@@ -4,11 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/onmetahq/meta-http/pkg/models"
@@ -22,67 +30,363 @@ type RequestOptions struct {
 
 type Requests interface {
 	SetDefaultHeaders(headers map[string]string)
-	Get(ctx context.Context, path string, headers map[string]string, v interface{}) (*models.ResponseData, error)
-	Post(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}) (*models.ResponseData, error)
-	Put(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}) (*models.ResponseData, error)
+	Get(ctx context.Context, path string, headers map[string]string, v interface{}, opts ...CallOption) (*models.ResponseData, error)
+	Post(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error)
+	Put(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error)
+	Do(ctx context.Context, method string, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error)
 	GetConfig() RequestOptions
+	Stats() Stats
+	SnapshotAndReset() StatsWindow
+	Healthy(ctx context.Context, path string, opts ...HealthOption) HealthResult
+	Schedule(ctx context.Context, at time.Time, req ScheduledRequest) (string, error)
+	Close(ctx context.Context) error
+	CloseIdleConnections()
+	WarmUp(ctx context.Context, n int) error
+	DialWebSocket(ctx context.Context, path string, headers map[string]string) (*WebSocketConn, *models.ResponseData, error)
+	AsHTTPClient() *http.Client
 }
 
 type client struct {
-	BaseURL        string
-	HTTPClient     *http.Client
-	defaultHeaders map[string]string
+	BaseURL      string
+	HTTPClient   *http.Client
+	headers      atomic.Pointer[headerTemplate]
+	codecs       map[string]Codec
+	strictPaths  bool
+	validator    StructValidator
+	resValidator StructValidator
+	compression  *requestCompression
+	// responseHeaders limits the headers exposed in ResponseData, nil
+	// exposes all of them.
+	responseHeaders headerSet
+	// methods is nil when every method is allowed.
+	methods map[string]bool
+	// envelope is the top-level field JSON responses are decoded from, empty
+	// for the whole document.
+	envelope     string
+	redirectMode RedirectMode
+	routes       *router
+	// skewThreshold is the clock skew warned about, 0 for none.
+	skewThreshold time.Duration
+	onSkew        func(ClockSkew)
+	shed          func(ShedRequest) bool
+	// concurrency is shared with the transport, for the calls made around
+	// it such as those over gRPC.
+	concurrency *concurrencyLimiter
+	// timeoutWarnings warns when too many calls come close to their timeout.
+	timeoutWarnings bool
+	dryRun          bool
+	scheduler       *scheduler
+	usageReport     *usageReporter
+	// transports are the connection pools the client dials through.
+	transports []*http.Transport
+	signers    []Signer
+	closed     atomic.Bool
+	logger     Logger
+	stats      *clientStats
 }
 
-func NewClient(baseUrl string, log *slog.Logger, timeout time.Duration) Requests {
-	return &client{
-		BaseURL: baseUrl,
-		HTTPClient: &http.Client{
-			Transport: &loggingRoundTripper{
-				logger: log,
-				next:   defaultPooledTransport(),
-			},
-			Timeout: timeout,
-		},
+func NewClient(baseUrl string, log Logger, timeout time.Duration, opts ...Option) Requests {
+	return newClient(baseUrl, log, timeout, nil, opts)
+}
+
+func NewClientWithRetry(baseUrl string, log Logger, timeout time.Duration, retry models.Retry, opts ...Option) Requests {
+	return newClient(baseUrl, log, timeout, &retry, opts)
+}
+
+// NewClientE is NewClient for configuration that is not known to be valid
+// upfront: a malformed base URL or non-positive timeout is reported here
+// instead of on the first call.
+func NewClientE(baseUrl string, log Logger, timeout time.Duration, opts ...Option) (Requests, error) {
+	if err := validateConfig(baseUrl, timeout, nil); err != nil {
+		return nil, err
 	}
+	return newClient(baseUrl, log, timeout, nil, opts), nil
 }
 
-func NewClientWithRetry(baseUrl string, log *slog.Logger, timeout time.Duration, retry models.Retry) Requests {
-	return &client{
+// NewClientWithRetryE is NewClientWithRetry with the validation of
+// NewClientE. The retry config must allow at least one attempt, have a
+// non-negative delay and a validator.
+func NewClientWithRetryE(baseUrl string, log Logger, timeout time.Duration, retry models.Retry, opts ...Option) (Requests, error) {
+	if err := validateConfig(baseUrl, timeout, &retry); err != nil {
+		return nil, err
+	}
+	return newClient(baseUrl, log, timeout, &retry, opts), nil
+}
+
+func validateConfig(baseUrl string, timeout time.Duration, retry *models.Retry) error {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return fmt.Errorf("%w: base url: %v", models.ErrInvalidConfig, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: base url %q must use http or https", models.ErrInvalidConfig, baseUrl)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: base url %q has no host", models.ErrInvalidConfig, baseUrl)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("%w: timeout must be positive, got %s", models.ErrInvalidConfig, timeout)
+	}
+	if retry == nil {
+		return nil
+	}
+	return validateRetry(*retry)
+}
+
+func validateRetry(retry models.Retry) error {
+	if retry.MaxRetries < 1 {
+		return fmt.Errorf("%w: retry MaxRetries must be at least 1, got %d", models.ErrInvalidConfig, retry.MaxRetries)
+	}
+	if retry.DelayBetweenRetry < 0 {
+		return fmt.Errorf("%w: retry DelayBetweenRetry must not be negative, got %s", models.ErrInvalidConfig, retry.DelayBetweenRetry)
+	}
+	if retry.Validator == nil {
+		return fmt.Errorf("%w: retry Validator is required", models.ErrInvalidConfig)
+	}
+	return nil
+}
+
+func newClient(baseUrl string, log Logger, timeout time.Duration, retry *models.Retry, opts []Option) *client {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.noLogging {
+		log = noopLogger{}
+	} else {
+		log = withTraceIDs(orDefault(log))
+	}
+
+	stats := newClientStats()
+	stats.timeout = timeout
+
+	pooled := defaultPooledTransport()
+	o.timeouts.apply(pooled)
+	if o.ssrf != nil {
+		o.ssrf.apply(pooled)
+	}
+	stats.countConns(pooled)
+
+	var transport http.RoundTripper = pooled
+	if o.fixtureDir != "" {
+		transport = fixtureTransport{dir: o.fixtureDir}
+	}
+	if o.dryRun {
+		transport = dryRunTransport{
+			logger: log,
+			masker: o.masker,
+			scrub:  newHeaderSet(append(append([]string{}, defaultHARRedactedHeaders...), o.logScrub...)...),
+		}
+	}
+	transport = sizeRoundTripper{stats: stats, next: transport}
+	if len(o.policies) > 0 {
+		transport = &destinationRoundTripper{
+			policies: o.policies,
+			next:     transport,
+		}
+	}
+	transport = &decompressRoundTripper{
+		acceptEncoding: strings.Join(o.encodings, ", "),
+		next:           transport,
+	}
+	transport = truncationRoundTripper{next: transport}
+	if o.timeouts.BodyReadIdle > 0 {
+		transport = &bodyIdleTimeoutRoundTripper{
+			timeout: o.timeouts.BodyReadIdle,
+			next:    transport,
+		}
+	}
+	if o.audit != nil {
+		transport = &auditRoundTripper{
+			recorder: o.audit,
+			masker:   o.masker,
+			logger:   log,
+			next:     transport,
+		}
+	}
+	if o.har != nil {
+		transport = &harRoundTripper{
+			recorder: o.har,
+			masker:   o.masker,
+			next:     transport,
+		}
+	}
+	if o.slowThreshold > 0 {
+		transport = &slowRoundTripper{
+			threshold: o.slowThreshold,
+			onSlow:    o.onSlow,
+			logger:    log,
+			next:      transport,
+		}
+	}
+	transport = newStatsRoundTripper(stats, transport)
+	if !o.noLogging {
+		logging := &loggingRoundTripper{
+			logger: log,
+			next:   transport,
+		}
+		if o.logHeaders {
+			logging.scrub = newHeaderSet(append(append([]string{}, defaultHARRedactedHeaders...), o.logScrub...)...)
+		}
+		transport = logging
+	}
+	redirects := redirectPolicy{strip: defaultRedirectStripHeaders, mode: o.redirectMode}
+	if o.redirectStrip != nil {
+		redirects.strip = o.redirectStrip
+	}
+	// Every attempt is signed anew, past rate limiting, so that timestamps,
+	// nonces and tokens are fresh when it is sent.
+	if len(o.signers) > 0 {
+		transport = &signingRoundTripper{
+			signers:       o.signers,
+			skipCrossHost: len(redirects.strip) > 0,
+			next:          transport,
+		}
+	}
+	// Nodes are picked before signing, as signatures may cover the host.
+	if o.sticky != nil {
+		transport = newStickyRoundTripper(*o.sticky, baseUrl, log, transport)
+	}
+	if o.deadlineReserve > 0 && o.deadlineReserve < 1 {
+		transport = deadlineBudgetRoundTripper{
+			reserve: o.deadlineReserve,
+			next:    transport,
+		}
+	}
+	var concurrency *concurrencyLimiter
+	if o.concurrency != nil {
+		concurrency = newConcurrencyLimiter(*o.concurrency)
+		transport = concurrencyRoundTripper{
+			limiter: concurrency,
+			next:    transport,
+		}
+	}
+	if o.rateLimit != nil {
+		if o.usage == nil {
+			o.usage = &usageCounters{}
+		}
+		transport = &rateLimitRoundTripper{
+			limiter: o.rateLimit.limiter(),
+			usage:   o.usage,
+			next:    transport,
+		}
+	}
+	if o.truncationAttempts > 1 {
+		transport = truncationRetryRoundTripper{
+			maxAttempts: o.truncationAttempts,
+			delay:       o.truncationDelay,
+			stats:       stats,
+			next:        transport,
+		}
+	}
+	// Clients without a retry policy still retry the calls made WithRetry.
+	retrying := &retryRoundTripper{maxRetries: 1, next: transport, stats: stats, observer: o.onRetryAttempt}
+	if retry != nil {
+		retrying.maxRetries = retry.MaxRetries
+		retrying.delay = retry.DelayBetweenRetry
+		retrying.validator = retry.Validator
+	}
+	transport = retrying
+	transports := []*http.Transport{pooled}
+	if o.shadow != nil && !o.dryRun {
+		transport = newShadowRoundTripper(*o.shadow, baseUrl, log, transport)
+		if shadow, ok := transport.(*shadowRoundTripper); ok {
+			transports = append(transports, shadow.shadow)
+		}
+	}
+	if o.cache != nil {
+		transport = newCachingRoundTripper(*o.cache, timeout, log, transport)
+	}
+	transport = memoRoundTripper{next: transport}
+	if o.coalesceWindow > 0 {
+		transport = newCoalescingRoundTripper(o.coalesceWindow, transport)
+	}
+	transport = &recoveryRoundTripper{
+		logger: log,
+		next:   transport,
+	}
+
+	codecs := defaultCodecs()
+	for mediaType, codec := range o.codecs {
+		codecs[mediaType] = codec
+	}
+
+	c := &client{
 		BaseURL: baseUrl,
 		HTTPClient: &http.Client{
-			Transport: &retryRoundTripper{
-				maxRetries: retry.MaxRetries,
-				delay:      retry.DelayBetweenRetry,
-				next: &loggingRoundTripper{
-					logger: log,
-					next:   defaultPooledTransport(),
-				},
-				validator: retry.Validator,
-			},
-			Timeout: timeout,
+			Transport:     transport,
+			Timeout:       timeout,
+			CheckRedirect: redirects.checkRedirect,
 		},
+		codecs:          codecs,
+		strictPaths:     o.strictPaths,
+		validator:       o.validator,
+		resValidator:    o.resValidator,
+		compression:     o.compression,
+		responseHeaders: o.responseHeaders,
+		methods:         o.methods,
+		envelope:        o.envelope,
+		redirectMode:    o.redirectMode,
+		routes:          o.routes,
+		skewThreshold:   o.skewThreshold,
+		onSkew:          o.onSkew,
+		shed:            o.shed,
+		concurrency:     concurrency,
+		timeoutWarnings: o.timeoutWarnings,
+		dryRun:          o.dryRun,
+		scheduler:       newScheduler(o.scheduleStore, o.onScheduled, log, newHeaderSet(append(append([]string{}, defaultHARRedactedHeaders...), o.logScrub...)...)),
+		transports:      transports,
+		signers:         o.signers,
+		logger:          log,
+		stats:           stats,
 	}
+	c.headers.Store(newHeaderTemplate(nil))
+	if o.onUsage != nil {
+		c.usageReport = newUsageReporter(o.usageInterval, o.onUsage)
+	}
+	if o.scheduleStore != nil {
+		go c.scheduler.resume(context.Background(), c)
+	}
+	return c
 }
 
+// SetDefaultHeaders replaces the headers sent with every request. It is safe
+// to call while requests are in flight, they keep the previous headers.
 func (c *client) SetDefaultHeaders(headers map[string]string) {
-	c.defaultHeaders = headers
+	c.headers.Store(newHeaderTemplate(headers))
 }
 
-func (c *client) sendRequest(req *http.Request, v interface{}) (*models.ResponseData, error) {
+func (c *client) sendRequest(req *http.Request, v interface{}, co *callOptions) (*models.ResponseData, error) {
 	response := models.ResponseData{}
+	sent := time.Now()
 	res, err := c.HTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	if skew, date, ok := clockSkew(res, sent, time.Now()); ok {
+		response.ClockSkew = skew
+		c.checkClockSkew(req, date, skew)
+	}
 
 	response.Header = res.Header
+	if c.responseHeaders != nil {
+		response.Header = c.responseHeaders.filter(res.Header)
+	}
 	response.Status = res.Status
 	response.StatusCode = res.StatusCode
 
 	defer res.Body.Close()
 
-	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+	failed := res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest
+	if co.expectedStatus != nil {
+		expected := slices.Contains(co.expectedStatus, res.StatusCode)
+		if expected && (res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices) {
+			io.Copy(io.Discard, res.Body)
+			return &response, nil
+		}
+		failed = !expected
+	}
+	if failed {
 		errRes := models.HttpClientErrorResponse{}
 		errRes.StatusCode = res.StatusCode
 		b, _ := io.ReadAll(res.Body)
@@ -96,17 +400,169 @@ func (c *client) sendRequest(req *http.Request, v interface{}) (*models.Response
 		return &response, &errRes
 	}
 
-	if err = json.NewDecoder(res.Body).Decode(&v); err != nil {
+	if response.Redirect = redirection(res); response.Redirect != nil {
+		io.Copy(io.Discard, res.Body)
+		if c.redirectMode == RejectRedirects {
+			return &response, fmt.Errorf("%w: %s", models.ErrUnexpectedRedirect, describeRedirect(response.Redirect))
+		}
+		return &response, nil
+	}
+
+	// A 304 has no body: the caller's copy is current and v is left as is.
+	if res.StatusCode == http.StatusNoContent || res.StatusCode == http.StatusNotModified || co.discardBody {
+		io.Copy(io.Discard, res.Body)
+		return &response, nil
+	}
+
+	var body io.Reader = res.Body
+	if co.responseSchema != nil {
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return &response, err
+		}
+		if err := co.responseSchema.ValidateJSON(b); err != nil {
+			return &response, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	if v == nil {
+		io.Copy(io.Discard, body)
+		return &response, nil
+	}
+
+	if bd, ok := v.(BodyDecoder); ok {
+		if err = bd.DecodeBody(body); err != nil {
+			if errors.Is(err, models.ErrTruncatedBody) {
+				return &response, err
+			}
+			errRes := models.HttpClientErrorResponse{}
+			errRes.Success = false
+			errRes.StatusCode = http.StatusInternalServerError
+			errRes.Category = models.CategoryDecode
+			errRes.Err.Message = err.Error()
+			return &response, &errRes
+		}
+		return &response, validateResponseBody(c.resValidator, v)
+	}
+
+	var codec Codec
+	switch v.(type) {
+	case *string, *[]byte:
+		// Raw targets capture the body as-is whatever the Content-Type.
+		codec = textCodec{}
+	default:
+		var ok bool
+		if codec, ok = joseCodecFor(c.codecs, req.Header.Get("Accept")); ok {
+			break
+		}
+		contentType := res.Header.Get("Content-Type")
+		if contentType == "" && co.accept != "" {
+			contentType, _, _ = strings.Cut(co.accept, ",")
+		}
+		if codec, ok = codecFor(c.codecs, contentType); !ok {
+			io.Copy(io.Discard, body)
+			return &response, &models.UnsupportedContentTypeError{ContentType: contentType}
+		}
+		if c.envelope != "" {
+			switch codec.(type) {
+			case jsonCodec, textCodec:
+				codec = envelopeCodec{field: c.envelope}
+			}
+		}
+	}
+
+	// Targets that are not a non-nil pointer are decoded through &v as they
+	// always were, into a copy the caller does not see, rather than failing.
+	target := v
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Pointer || rv.IsNil() {
+		target = &v
+	}
+	if err = codec.Decode(body, target); err != nil {
+		if errors.Is(err, models.ErrTruncatedBody) {
+			return &response, err
+		}
 		errRes := models.HttpClientErrorResponse{}
 		errRes.Success = false
 		errRes.StatusCode = http.StatusInternalServerError
+		errRes.Category = models.CategoryDecode
 		errRes.Err.Message = err.Error()
 		return &response, &errRes
 	}
-	return &response, nil
+	return &response, validateResponseBody(c.resValidator, v)
+}
+
+// admit fails the calls the client must not make: those shed under load and
+// those whose method is not allowed.
+func (c *client) admit(ctx context.Context, method, path string) error {
+	if c.shed != nil && c.shed(ShedRequest{
+		Method:   strings.ToUpper(method),
+		Path:     path,
+		Route:    routeFor(ctx),
+		Caller:   CallerFromContext(ctx),
+		Priority: PriorityFromContext(ctx),
+	}) {
+		loggerFor(ctx, c.logger).WarnContext(
+			ctx,
+			"Shed call under load",
+			slog.String("method", method),
+			slog.String("path", path),
+			slog.Int("priority", int(PriorityFromContext(ctx))),
+			slog.String(string(models.RequestID), contextString(ctx, models.RequestID)),
+		)
+		return fmt.Errorf("%w: %s %s", models.ErrLoadShed, strings.ToUpper(method), path)
+	}
+
+	if c.methods != nil && !c.methods[strings.ToUpper(method)] {
+		loggerFor(ctx, c.logger).WarnContext(
+			ctx,
+			"Rejected call with disallowed method",
+			slog.String("method", method),
+			slog.String("path", path),
+			slog.String(string(models.RequestID), contextString(ctx, models.RequestID)),
+		)
+		return fmt.Errorf("%w: %s", models.ErrMethodNotAllowed, method)
+	}
+	return nil
 }
 
+// generateUrl appends relativePath to basePath. Query strings on both sides
+// are merged (base parameters first) and a fragment on relativePath wins over
+// one on basePath.
 func generateUrl(basePath string, relativePath string) string {
+	base, baseQuery, baseFragment := splitUrl(basePath)
+	relative, relativeQuery, relativeFragment := splitUrl(relativePath)
+
+	ul := joinPaths(base, relative)
+
+	query := baseQuery
+	if relativeQuery != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += relativeQuery
+	}
+	if query != "" {
+		ul += "?" + query
+	}
+
+	fragment := relativeFragment
+	if fragment == "" {
+		fragment = baseFragment
+	}
+	if fragment != "" {
+		ul += "#" + fragment
+	}
+	return ul
+}
+
+func splitUrl(ul string) (path string, query string, fragment string) {
+	ul, fragment, _ = strings.Cut(ul, "#")
+	path, query, _ = strings.Cut(ul, "?")
+	return path, query, fragment
+}
+
+func joinPaths(basePath string, relativePath string) string {
 	if len(basePath) == 0 {
 		return relativePath
 	}
@@ -131,107 +587,274 @@ func generateUrl(basePath string, relativePath string) string {
 	}
 }
 
-func (c *client) Get(ctx context.Context, path string, headers map[string]string, v interface{}) (*models.ResponseData, error) {
-	ul := generateUrl(c.BaseURL, path)
-	u, err := url.ParseRequestURI(ul)
-	if err != nil || u.Host == "" || u.Scheme == "" {
-		return nil, fmt.Errorf("%w url: %s, err: %v", models.ErrBadURL, ul, err)
-	}
+func (c *client) Get(ctx context.Context, path string, headers map[string]string, v interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	return c.do(ctx, http.MethodGet, path, headers, nil, v, opts)
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ul, nil)
-	if err != nil {
-		return nil, err
-	}
+func (c *client) Post(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	return c.do(ctx, http.MethodPost, path, headers, &v, res, opts)
+}
 
-	ctxHeaders := utils.FetchHeadersFromContext(ctx)
-	for k, v := range ctxHeaders {
-		req.Header.Set(k, v)
+func (c *client) Put(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	return c.do(ctx, http.MethodPut, path, headers, &v, res, opts)
+}
+
+// Do sends a request with an arbitrary method. A nil v sends no body.
+func (c *client) Do(ctx context.Context, method string, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	if v == nil {
+		return c.do(ctx, method, path, headers, nil, res, opts)
 	}
+	return c.do(ctx, method, path, headers, &v, res, opts)
+}
 
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("Accept", "application/json; charset=utf-8")
+// do builds and sends a request. body is nil for requests without a payload
+// and otherwise points at the value to be marshaled, which may itself be nil.
+func (c *client) do(ctx context.Context, method string, path string, headers map[string]string, body *interface{}, res interface{}, opts []CallOption) (_ *models.ResponseData, err error) {
+	started := time.Now()
+	done := c.stats.begin()
+	var budget time.Duration
+	history := &retryHistory{}
+	ctx = context.WithValue(ctx, retryHistoryKey{}, history)
+	caller := ctx
+	// The timeout of the call is canceled once its error is classified, so
+	// that the cancellation is not mistaken for the caller's.
+	cancel := context.CancelFunc(func() {})
+	defer func() {
+		err = history.wrap(categorize(c.explainTimeout(caller, ctx, started, err)))
+		cancel()
+		c.recordDeadline(ctx, started, budget, err)
+		// Usage is recorded before done, which lets Close stop the reporter
+		// once no call is in flight.
+		if c.usageReport != nil {
+			route := routeFor(ctx)
+			if route == "" {
+				route = (&router{}).route(path)
+			}
+			c.usageReport.record(strings.ToUpper(method), route, CallerFromContext(ctx), time.Since(started), err)
+		}
+		done(endpointFor(ctx), err)
+	}()
 
-	for k, v := range c.defaultHeaders {
-		req.Header.Set(k, v)
+	if c.closed.Load() {
+		return nil, models.ErrClientClosed
 	}
 
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	co := &noCallOptions
+	if len(opts) > 0 {
+		co = &callOptions{}
+		for _, opt := range opts {
+			opt(co)
+		}
 	}
 
-	return c.sendRequest(req, v)
-}
-
-func (c *client) Post(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}) (*models.ResponseData, error) {
-	ul := generateUrl(c.BaseURL, path)
-	u, err := url.ParseRequestURI(ul)
-	if err != nil || u.Host == "" || u.Scheme == "" {
-		return nil, fmt.Errorf("invalid url, url: %s, err: %v", ul, err)
+	if co.err != nil {
+		return nil, co.err
 	}
-
-	postBody, err := json.Marshal(v)
-	if err != nil {
+	if co.logger != nil {
+		ctx = ContextWithLogger(ctx, co.logger)
+	}
+	if c.routes != nil && routeFor(ctx) == "" {
+		ctx = context.WithValue(ctx, routeKey{}, c.routes.route(path))
+	}
+	if co.caller != "" {
+		ctx = ContextWithCaller(ctx, co.caller)
+	}
+	if co.noCache {
+		ctx = context.WithValue(ctx, noCacheKey{}, true)
+	}
+	if fn := co.informational; fn != nil {
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			Got1xxResponse: func(status int, header textproto.MIMEHeader) error {
+				fn(status, http.Header(header))
+				return nil
+			},
+		})
+	}
+	if co.retry != nil {
+		ctx = context.WithValue(ctx, retryPolicyKey{}, *co.retry)
+	}
+	budget = c.callBudget(ctx, co)
+	if co.timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeout(ctx, co.timeout)
+		cancel = stop
+	}
+	c.stats.countCaller(CallerFromContext(ctx))
+	if co.priority != nil {
+		ctx = ContextWithPriority(ctx, *co.priority)
+	}
+	if err := c.admit(ctx, method, path); err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ul, bytes.NewBuffer(postBody))
-	if err != nil {
-		return nil, err
+	var payload *requestBody
+	if body != nil {
+		if err := validateRequestBody(c.validator, *body); err != nil {
+			return nil, err
+		}
+
+		var codec Codec = jsonCodec{}
+		if co.contentType != "" {
+			var ok bool
+			if codec, ok = codecFor(c.codecs, co.contentType); !ok {
+				codec = textCodec{}
+			}
+		}
+
+		if _, isJSON := codec.(jsonCodec); co.streamBody && isJSON {
+			payload = &requestBody{getBody: streamJSONBody(*body), length: -1}
+			if c.compression != nil {
+				payload.getBody = compressStream(payload.getBody, c.compression.encoding)
+				payload.encoding = c.compression.encoding
+			}
+		} else {
+			buf, err := encodeBody(codec, *body)
+			if err != nil {
+				return nil, err
+			}
+			defer buf.Release()
+			payload = &requestBody{getBody: buf.NewReader, length: int64(buf.Len())}
+
+			if c.compression != nil && buf.Len() >= c.compression.minSize {
+				compressed, err := compressBuffer(buf, c.compression.encoding)
+				if err != nil {
+					return nil, err
+				}
+				defer compressed.Release()
+				payload = &requestBody{
+					getBody:  compressed.NewReader,
+					length:   int64(compressed.Len()),
+					encoding: c.compression.encoding,
+				}
+			}
+		}
 	}
 
-	ctxHeaders := utils.FetchHeadersFromContext(ctx)
-	for k, v := range ctxHeaders {
-		req.Header.Set(k, v)
+	var attempts *attemptCounter
+	if _, disabled := c.logger.(noopLogger); !disabled {
+		attempts = &attemptCounter{}
+		ctx = context.WithValue(ctx, attemptCounterKey{}, attempts)
 	}
 
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("Accept", "application/json; charset=utf-8")
+	sizes := &sizeCounter{}
+	ctx = context.WithValue(ctx, sizeCounterKey{}, sizes)
 
-	for k, v := range c.defaultHeaders {
-		req.Header.Set(k, v)
+	req, err := c.newRequest(ctx, method, path, headers, payload, co)
+	if err != nil {
+		return nil, err
 	}
-
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	data, err := c.sendRequest(req, res, co)
+	if data != nil {
+		data.BytesSent, data.BytesReceived = sizes.sent.Load(), sizes.received.Load()
+	}
+	if attempts != nil {
+		c.logSummary(req, attempts, started, data, err)
 	}
+	return data, err
+}
 
-	return c.sendRequest(req, res)
+// logSummary logs one line per logical request once every attempt is done.
+func (c *client) logSummary(r *http.Request, attempts *attemptCounter, started time.Time, data *models.ResponseData, err error) {
+	attrs := []any{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("host", r.URL.Host),
+		slog.Int("attempts", int(attempts.n.Load())),
+		slog.Int64("duration", time.Since(started).Milliseconds()),
+	}
+	if route := routeFor(r.Context()); route != "" {
+		attrs = append(attrs, slog.String("route", route))
+	}
+	if caller := CallerFromContext(r.Context()); caller != "" {
+		attrs = append(attrs, slog.String("caller", caller))
+	}
+	if data != nil {
+		attrs = append(attrs,
+			slog.Int("status", data.StatusCode),
+			slog.Int64("bytes_sent", data.BytesSent),
+			slog.Int64("bytes_received", data.BytesReceived),
+		)
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err.Error()))
+	}
+	attrs = append(attrs, slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))))
+	loggerFor(r.Context(), c.logger).DebugContext(r.Context(), "Request Ended", attrs...)
 }
 
-func (c *client) Put(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}) (*models.ResponseData, error) {
+func (c *client) newRequest(ctx context.Context, method string, path string, headers map[string]string, payload *requestBody, co *callOptions) (*http.Request, error) {
+	if c.strictPaths {
+		if err := utils.CheckPath(path); err != nil {
+			return nil, err
+		}
+	}
+
 	ul := generateUrl(c.BaseURL, path)
 	u, err := url.ParseRequestURI(ul)
 	if err != nil || u.Host == "" || u.Scheme == "" {
-		return nil, fmt.Errorf("invalid url, url: %s, err: %v", ul, err)
+		// Calls without a payload keep the message of Get, those with one
+		// the message of Post and Put.
+		if payload == nil {
+			return nil, fmt.Errorf("%w url: %s, err: %v", models.ErrBadURL, ul, err)
+		}
+		return nil, fmt.Errorf("%w, url: %s, err: %v", models.ErrBadURL, ul, err)
 	}
 
-	postBody, err := json.Marshal(v)
-	if err != nil {
-		return nil, err
+	if len(co.query) > 0 {
+		q := u.Query()
+		for k, vs := range co.query {
+			q[k] = append(q[k], vs...)
+		}
+		u.RawQuery = q.Encode()
+		ul = u.String()
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, ul, bytes.NewBuffer(postBody))
+	req, err := http.NewRequestWithContext(ctx, method, ul, nil)
 	if err != nil {
 		return nil, err
 	}
-
-	ctxHeaders := utils.FetchHeadersFromContext(ctx)
-	for k, v := range ctxHeaders {
-		req.Header.Set(k, v)
+	if payload != nil {
+		if req.Body, err = payload.getBody(); err != nil {
+			return nil, err
+		}
+		req.ContentLength = payload.length
+		req.GetBody = payload.getBody
 	}
 
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("Accept", "application/json; charset=utf-8")
-
-	for k, v := range c.defaultHeaders {
-		req.Header.Set(k, v)
+	tmpl := c.headers.Load()
+	req.Header = make(http.Header, len(*tmpl)+len(headers)+2)
+	utils.EachHeaderFromContext(ctx, req.Header.Set)
+	tmpl.apply(req.Header)
+	if caller := CallerFromContext(ctx); caller != "" {
+		req.Header.Set(CallerHeader, caller)
+	}
+	if co.contentType != "" {
+		req.Header.Set("Content-Type", co.contentType)
+	}
+	if co.accept != "" {
+		req.Header.Set("Accept", co.accept)
+	}
+	if payload != nil && payload.encoding != "" {
+		req.Header.Set("Content-Encoding", payload.encoding)
 	}
 
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
 
-	return c.sendRequest(req, res)
+	for k, values := range co.header {
+		req.Header.Del(k)
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+
+	return req, nil
+}
+
+// Stats returns the client's counters.
+func (c *client) Stats() Stats {
+	return c.stats.snapshot()
 }
 
 func (c *client) GetConfig() RequestOptions {
@@ -241,67 +864,194 @@ func (c *client) GetConfig() RequestOptions {
 	}
 }
 
+// NewLoggingRoundTripper logs the calls made through next the way clients
+// do, for *http.Clients that cannot be replaced by one, e.g. those passed to
+// third-party SDKs. A nil log logs to slog.Default and a nil next uses
+// http.DefaultTransport.
+func NewLoggingRoundTripper(log Logger, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &loggingRoundTripper{logger: withTraceIDs(orDefault(log)), next: next}
+}
+
 type loggingRoundTripper struct {
 	next   http.RoundTripper
-	logger *slog.Logger
+	logger Logger
+	// scrub is set when headers are logged and names the headers whose
+	// values are redacted.
+	scrub headerSet
 }
 
 func (l loggingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
 	presentTime := time.Now()
-	l.logger.Debug(
+	logger := loggerFor(r.Context(), l.logger)
+	attempt := slog.Int("attempt", nextAttempt(r.Context()))
+	logger.DebugContext(
+		r.Context(),
 		"Initiating call",
 		slog.String("path", r.URL.Path),
 		slog.String("host", r.URL.Host),
+		attempt,
 		slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))),
 	)
 	res, err := l.next.RoundTrip(r)
 	if err != nil {
-		l.logger.Debug(
+		logger.DebugContext(
+			r.Context(),
 			"Call Ended",
 			slog.String("path", r.URL.Path),
 			slog.String("host", r.URL.Host),
+			attempt,
 			slog.Int64("duration", time.Since(presentTime).Milliseconds()),
 			slog.Any("error", err.Error()),
 			slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))),
 		)
 		return nil, err
 	}
-	l.logger.Debug(
-		"Call Ended",
+	attrs := []any{
 		slog.String("path", r.URL.Path),
 		slog.String("host", r.URL.Host),
+		attempt,
 		slog.Int64("duration", time.Since(presentTime).Milliseconds()),
 		slog.Int("status", res.StatusCode),
 		slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))),
-	)
+	}
+	if route := routeFor(r.Context()); route != "" {
+		attrs = append(attrs, slog.String("route", route))
+	}
+	if l.scrub != nil {
+		attrs = append(attrs,
+			slog.Any("request_headers", l.scrub.scrub(r.Header)),
+			slog.Any("response_headers", l.scrub.scrub(res.Header)),
+		)
+	}
+	logger.DebugContext(r.Context(), "Call Ended", attrs...)
 	return res, err
 }
 
+// NewRetryRoundTripper retries the calls made through next as clients
+// created with NewClientWithRetry do. Request bodies are resent from
+// Request.GetBody, which http.NewRequest sets for in-memory bodies; requests
+// with a body and no GetBody are sent once. A nil next uses
+// http.DefaultTransport. It fails when retry is invalid, like
+// NewClientWithRetryE.
+func NewRetryRoundTripper(retry models.Retry, next http.RoundTripper) (http.RoundTripper, error) {
+	if err := validateRetry(retry); err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &retryRoundTripper{
+		maxRetries: retry.MaxRetries,
+		delay:      retry.DelayBetweenRetry,
+		validator:  retry.Validator,
+		next:       next,
+	}, nil
+}
+
 type retryRoundTripper struct {
 	next       http.RoundTripper
 	maxRetries int
 	delay      time.Duration
 	validator  func(int) bool
+	stats      *clientStats
+	observer   func(RetryAttempt)
 }
 
 func (rrt retryRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Context().Value(noRetryKey{}) != nil {
+		return rrt.next.RoundTrip(r)
+	}
+	if retry, ok := r.Context().Value(retryPolicyKey{}).(models.Retry); ok {
+		rrt.maxRetries, rrt.delay, rrt.validator = retry.MaxRetries, retry.DelayBetweenRetry, retry.Validator
+	}
+	if rrt.maxRetries <= 1 {
+		return rrt.once(r)
+	}
+	// A body that cannot be read again can only be sent once.
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return rrt.once(r)
+	}
+	var res *http.Response
+	done := false
+	// A panicking validator must not leak the connection held by res.
+	defer func() {
+		if !done && res != nil && res.Body != nil {
+			res.Body.Close()
+		}
+	}()
+
 	attempts := 0
 	for {
-		res, err := rrt.next.RoundTrip(r)
+		var err error
+		started := time.Now()
+		res, err = rrt.next.RoundTrip(r)
 		attempts = attempts + 1
 
 		if attempts == rrt.maxRetries {
+			done = true
+			rrt.observe(r, attempts, res, err, started, false, err != nil || !rrt.validator(res.StatusCode))
 			return res, err
 		}
 
 		if err == nil && rrt.validator(res.StatusCode) {
+			done = true
+			rrt.observe(r, attempts, res, err, started, false, false)
 			return res, err
 		}
+		rrt.observe(r, attempts, res, err, started, true, false)
 
 		select {
 		case <-r.Context().Done():
+			done = true
 			return res, r.Context().Err()
 		case <-time.After(rrt.delay):
 		}
+		if res != nil && res.Body != nil {
+			res.Body.Close()
+		}
+		res = nil
+		// The previous attempt consumed the body, retries send a fresh one.
+		if r.Body != nil && r.Body != http.NoBody && r.GetBody != nil {
+			body, err := r.GetBody()
+			if err != nil {
+				done = true
+				return nil, err
+			}
+			r = r.Clone(r.Context())
+			r.Body = body
+		}
+		if rrt.stats != nil {
+			rrt.stats.retries.Add(1)
+		}
 	}
 }
+
+type recoveryRoundTripper struct {
+	next   http.RoundTripper
+	logger Logger
+}
+
+func (rec recoveryRoundTripper) RoundTrip(r *http.Request) (res *http.Response, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			loggerFor(r.Context(), rec.logger).ErrorContext(
+				r.Context(),
+				"Recovered from panic",
+				slog.String("path", r.URL.Path),
+				slog.String("host", r.URL.Host),
+				slog.Any("panic", p),
+				slog.String("stack", string(debug.Stack())),
+				slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))),
+			)
+			if res != nil && res.Body != nil {
+				res.Body.Close()
+			}
+			res = nil
+			err = fmt.Errorf("%w: %v", models.ErrPanic, p)
+		}
+	}()
+	return rec.next.RoundTrip(r)
+}
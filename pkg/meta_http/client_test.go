@@ -1,14 +1,17 @@
 package metahttp_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -272,6 +275,21 @@ func TestMetaHTTPClientWithBadURLs(t *testing.T) {
 		if errors.Unwrap(err) != models.ErrBadURL {
 			t.Errorf("not a bad url error, url: %s", tc.url)
 		}
+
+		_, err = metaHttpClient.Post(context.Background(), tc.url, map[string]string{}, nil, &res)
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid url, url: ") {
+			t.Errorf("unexpected post error for url %s: %v", tc.url, err)
+		}
+
+		_, err = metaHttpClient.Do(context.Background(), http.MethodDelete, tc.url, map[string]string{}, nil, &res)
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid url url: ") {
+			t.Errorf("unexpected delete error for url %s: %v", tc.url, err)
+		}
+
+		_, err = metaHttpClient.Do(context.Background(), http.MethodGet, tc.url, map[string]string{}, map[string]string{}, &res)
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid url, url: ") {
+			t.Errorf("unexpected get with body error for url %s: %v", tc.url, err)
+		}
 	}
 }
 
@@ -315,3 +333,540 @@ func TestMetaHTTPClientUnAuthorised(t *testing.T) {
 		t.Error("invalid status code")
 	}
 }
+
+func TestMetaHTTPClientRecoversFromPanic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClientWithRetry(server.URL, logger, 10*time.Second, models.Retry{
+		MaxRetries:        3,
+		DelayBetweenRetry: 10 * time.Millisecond,
+		Validator: func(int) bool {
+			panic("validator exploded")
+		},
+	})
+
+	var res map[string]any
+	_, err := metaHttpClient.Get(context.Background(), "/test", map[string]string{}, &res)
+	if !errors.Is(err, models.ErrPanic) {
+		t.Errorf("expected panic error, got: %v", err)
+	}
+}
+
+func TestResponseCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		http.SetCookie(rw, &http.Cookie{Name: "session", Value: "abc", HttpOnly: true})
+		rw.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	var res map[string]any
+	resp, err := metaHttpClient.Get(context.Background(), "/login", map[string]string{}, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "abc" {
+		t.Errorf("unexpected cookies: %v", cookies)
+	}
+}
+
+func TestMultiValueHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		bytes, _ := json.Marshal(req.Header.Values("Forwarded"))
+		rw.Write(bytes)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	var res []string
+	headers := http.Header{}
+	headers.Add("Forwarded", "for=10.0.0.1")
+	headers.Add("Forwarded", "for=10.0.0.2")
+	_, err := metaHttpClient.Get(context.Background(), "/test", map[string]string{"Forwarded": "for=overridden"}, &res, metahttp.WithHeaderValues(headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0] != "for=10.0.0.1" || res[1] != "for=10.0.0.2" {
+		t.Errorf("unexpected forwarded headers: %v", res)
+	}
+}
+
+func TestQueryEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	type Page struct {
+		Limit int `url:"limit"`
+	}
+	filters := struct {
+		Page
+		Status   []string  `url:"status"`
+		Tags     []string  `url:"tags,comma"`
+		From     time.Time `url:"from" layout:"2006-01-02"`
+		Until    time.Time `url:"until,unix"`
+		Merchant string    `url:"merchant,omitempty"`
+		Internal string    `url:"-"`
+	}{
+		Page:     Page{Limit: 50},
+		Status:   []string{"success", "failed"},
+		Tags:     []string{"a", "b"},
+		From:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Until:    time.Unix(1700000000, 0),
+		Internal: "secret",
+	}
+
+	var res string
+	_, err := metaHttpClient.Get(context.Background(), "/orders?sort=desc", map[string]string{}, &res, metahttp.WithQuery(filters))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "from=2024-01-02&limit=50&sort=desc&status=success&status=failed&tags=a%2Cb&until=1700000000"
+	if res != want {
+		t.Errorf("unexpected query: %s", res)
+	}
+}
+
+func TestSafePathJoining(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.EscapedPath()))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second, metahttp.WithStrictPaths())
+
+	var res string
+	_, err := metaHttpClient.Get(context.Background(), utils.JoinPath("users", "a/b?c", "orders"), map[string]string{}, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "/users/a%2Fb%3Fc/orders" {
+		t.Errorf("unexpected path: %s", res)
+	}
+
+	for _, path := range []string{"/users/../admin", "/users/%2e%2e/admin", "/users/a%5Cb"} {
+		_, err = metaHttpClient.Get(context.Background(), path, map[string]string{}, &res)
+		if !errors.Is(err, models.ErrUnsafePath) {
+			t.Errorf("expected unsafe path error for %s, got: %v", path, err)
+		}
+	}
+
+	if _, err := utils.StrictJoinPath("users", ".."); !errors.Is(err, models.ErrUnsafePath) {
+		t.Errorf("expected unsafe path error, got: %v", err)
+	}
+}
+
+func TestBaseURLQueryIsPreserved(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.RequestURI()))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL+"/api?apikey=x", logger, 10*time.Second)
+
+	tcs := []struct {
+		path string
+		want string
+	}{
+		{path: "/users", want: "/api/users?apikey=x"},
+		{path: "users?limit=5", want: "/api/users?apikey=x&limit=5"},
+		{path: "", want: "/api?apikey=x"},
+	}
+
+	for _, tc := range tcs {
+		var res string
+		if _, err := metaHttpClient.Get(context.Background(), tc.path, map[string]string{}, &res); err != nil {
+			t.Fatal(err)
+		}
+		if res != tc.want {
+			t.Errorf("path: %s, expected: %s, got: %s", tc.path, tc.want, res)
+		}
+	}
+}
+
+func TestRequestValidator(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		rw.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	type payment struct {
+		Amount int
+	}
+	validator := metahttp.StructValidatorFunc(func(s interface{}) error {
+		if s.(payment).Amount <= 0 {
+			return errors.New("amount must be positive")
+		}
+		return nil
+	})
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second, metahttp.WithRequestValidator(validator))
+
+	var res map[string]any
+	_, err := metaHttpClient.Post(context.Background(), "/payments", map[string]string{}, payment{Amount: 0}, &res)
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("expected validation error, got: %v", err)
+	}
+	if calls != 0 {
+		t.Error("invalid payload should not be sent")
+	}
+
+	if _, err := metaHttpClient.Post(context.Background(), "/payments", map[string]string{}, payment{Amount: 10}, &res); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestStreamingRequestBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var items []int
+		if err := json.NewDecoder(req.Body).Decode(&items); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		res := map[string]any{"count": len(items), "chunked": req.ContentLength == -1}
+		bytes, _ := json.Marshal(res)
+		rw.Write(bytes)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	items := make([]int, 100000)
+	var res struct {
+		Count   int  `json:"count"`
+		Chunked bool `json:"chunked"`
+	}
+	if _, err := metaHttpClient.Post(context.Background(), "/batch", map[string]string{}, items, &res, metahttp.WithStreamingBody()); err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != len(items) || !res.Chunked {
+		t.Errorf("unexpected response: %+v", res)
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithAllowedMethods(http.MethodGet, "head"))
+
+	if _, err := client.Get(context.Background(), "/report", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(context.Background(), http.MethodHead, "/report", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Post(context.Background(), "/report", nil, map[string]string{}, nil); !errors.Is(err, models.ErrMethodNotAllowed) {
+		t.Errorf("expected method not allowed, got: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("rejected calls must not be sent, got %d calls", calls)
+	}
+}
+
+func TestClientConfigValidation(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	retry := models.Retry{MaxRetries: 3, DelayBetweenRetry: time.Millisecond, Validator: func(int) bool { return true }}
+
+	if _, err := metahttp.NewClientE("https://api.example.com/v1", logger, 5*time.Second); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+	if _, err := metahttp.NewClientWithRetryE("http://localhost:8080", logger, 5*time.Second, retry); err != nil {
+		t.Errorf("valid retry config rejected: %v", err)
+	}
+
+	for name, build := range map[string]func() (metahttp.Requests, error){
+		"no scheme": func() (metahttp.Requests, error) {
+			return metahttp.NewClientE("api.example.com", logger, 5*time.Second)
+		},
+		"bad url": func() (metahttp.Requests, error) {
+			return metahttp.NewClientE("https://api.example.com/%zz", logger, 5*time.Second)
+		},
+		"no host": func() (metahttp.Requests, error) {
+			return metahttp.NewClientE("https:///v1", logger, 5*time.Second)
+		},
+		"zero timeout": func() (metahttp.Requests, error) {
+			return metahttp.NewClientE("https://api.example.com", logger, 0)
+		},
+		"no attempts": func() (metahttp.Requests, error) {
+			r := retry
+			r.MaxRetries = 0
+			return metahttp.NewClientWithRetryE("https://api.example.com", logger, 5*time.Second, r)
+		},
+		"negative delay": func() (metahttp.Requests, error) {
+			r := retry
+			r.DelayBetweenRetry = -time.Second
+			return metahttp.NewClientWithRetryE("https://api.example.com", logger, 5*time.Second, r)
+		},
+		"no validator": func() (metahttp.Requests, error) {
+			r := retry
+			r.Validator = nil
+			return metahttp.NewClientWithRetryE("https://api.example.com", logger, 5*time.Second, r)
+		},
+	} {
+		client, err := build()
+		if client != nil || !errors.Is(err, models.ErrInvalidConfig) {
+			t.Errorf("%s: expected invalid config, got %v, %v", name, client, err)
+		}
+	}
+}
+
+func TestConditionalWrite(t *testing.T) {
+	version := 1
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"v%d"`, version)
+		if r.Method == http.MethodPut {
+			if r.Header.Get("If-Match") != etag {
+				rw.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			version++
+			etag = fmt.Sprintf(`"v%d"`, version)
+		}
+		rw.Header().Set("ETag", etag)
+		rw.Write([]byte(`{"limit":10}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second)
+
+	var config map[string]int
+	read, err := client.Get(context.Background(), "/config", nil, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.ETag() != `"v1"` {
+		t.Fatalf("unexpected etag %q", read.ETag())
+	}
+	config["limit"] = 20
+	if _, err := client.Put(context.Background(), "/config", nil, config, nil, metahttp.WithIfMatch(read.ETag())); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Put(context.Background(), "/config", nil, config, nil, metahttp.WithIfMatch(read.ETag()))
+	if !errors.Is(err, models.ErrConflict) {
+		t.Fatalf("expected conflict, got: %v", err)
+	}
+	var httpErr *models.HttpClientErrorResponse
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected the 412 response error, got: %v", err)
+	}
+}
+
+type limitError struct {
+	Limit int
+}
+
+func (e *limitError) Error() string {
+	return fmt.Sprintf("daily limit of %d reached", e.Limit)
+}
+
+func TestRegisteredErrorCodes(t *testing.T) {
+	errInsufficientBalance := errors.New("insufficient balance")
+	models.RegisterErrorCode(91001, errInsufficientBalance)
+	models.RegisterErrorCode(91002, &limitError{Limit: 5})
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(rw, `{"success":false,"error":{"code":%s,"message":"rejected"}}`, r.URL.Query().Get("code"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second)
+
+	_, err := client.Post(context.Background(), "/withdraw?code=91001", nil, map[string]int{"amount": 100}, nil)
+	if !errors.Is(err, errInsufficientBalance) {
+		t.Errorf("expected insufficient balance, got: %v", err)
+	}
+	if models.CategoryOf(err) != models.CategoryHTTP4xx {
+		t.Errorf("unexpected category %q", models.CategoryOf(err))
+	}
+
+	_, err = client.Post(context.Background(), "/withdraw?code=91002", nil, map[string]int{"amount": 100}, nil)
+	var limitErr *limitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != 5 {
+		t.Errorf("expected the limit error, got: %v", err)
+	}
+
+	_, err = client.Post(context.Background(), "/withdraw?code=91003", nil, map[string]int{"amount": 100}, nil)
+	if errors.Is(err, errInsufficientBalance) || errors.As(err, &limitErr) {
+		t.Errorf("unregistered codes must not match, got: %v", err)
+	}
+
+	models.RegisterErrorCode(91001, nil)
+	_, err = client.Post(context.Background(), "/withdraw?code=91001", nil, map[string]int{"amount": 100}, nil)
+	if errors.Is(err, errInsufficientBalance) || models.CategoryOf(err) != models.CategoryHTTP4xx {
+		t.Errorf("expected a code registered as nil to be removed, got: %v", err)
+	}
+}
+
+func TestRetriesResendBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClientWithRetry(server.URL, nil, 5*time.Second, models.Retry{
+		MaxRetries: 3,
+		Validator:  func(status int) bool { return status < 500 },
+	}, metahttp.WithoutLogging())
+
+	var res map[string]any
+	if _, err := client.Post(context.Background(), "/orders", nil, map[string]int{"amount": 100}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(bodies))
+	}
+	for i, b := range bodies {
+		if b != `{"amount":100}` {
+			t.Errorf("attempt %d sent %q", i+1, b)
+		}
+	}
+}
+
+func TestDiscardBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/octet-stream")
+		rw.Write([]byte{0xde, 0xad, 0xbe, 0xef})
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	ctx := context.Background()
+
+	var res map[string]any
+	if _, err := client.Post(ctx, "/invalidate", nil, nil, &res); err == nil {
+		t.Fatal("expected an unsupported content type error without DiscardBody")
+	}
+	data, err := client.Post(ctx, "/invalidate", nil, nil, &res, metahttp.DiscardBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.StatusCode != http.StatusOK || res != nil {
+		t.Errorf("unexpected result: status %d, decoded %v", data.StatusCode, res)
+	}
+	if _, err := client.Post(ctx, "/missing", nil, nil, &res, metahttp.DiscardBody()); err == nil {
+		t.Error("expected error responses to be reported")
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("ETag", `"v1"`)
+		rw.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		if r.Header.Get("If-None-Match") == `"v1"` || r.Header.Get("If-Modified-Since") == modified.Format(http.TimeFormat) {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		rw.Write([]byte(`{"name":"meta"}`))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	ctx := context.Background()
+
+	var res map[string]string
+	data, err := client.Get(ctx, "/profile", nil, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.NotModified() || res["name"] != "meta" {
+		t.Fatalf("unexpected first read: %v", res)
+	}
+
+	cached := res
+	for _, opt := range []metahttp.CallOption{
+		metahttp.WithIfNoneMatch(data.ETag()),
+		metahttp.WithIfModifiedSince(modified.In(time.FixedZone("CEST", 2*60*60))),
+	} {
+		res = cached
+		data, err = client.Get(ctx, "/profile", nil, &res, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !data.NotModified() || res["name"] != "meta" {
+			t.Errorf("expected a NotModified result leaving the copy intact, got status %d, %v", data.StatusCode, res)
+		}
+	}
+}
+
+func TestExportedRoundTrippers(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if _, err := metahttp.NewRetryRoundTripper(models.Retry{MaxRetries: 3}, nil); !errors.Is(err, models.ErrInvalidConfig) {
+		t.Errorf("expected a missing validator to be rejected, got %v", err)
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	transport, err := metahttp.NewRetryRoundTripper(models.Retry{
+		MaxRetries: 3,
+		Validator:  func(status int) bool { return status < 500 },
+	}, metahttp.NewLoggingRoundTripper(logger, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := &http.Client{Transport: transport}
+
+	res, err := httpClient.Post(server.URL+"/webhooks", "application/json", strings.NewReader(`{"event":"paid"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent || len(bodies) != 2 || bodies[1] != `{"event":"paid"}` {
+		t.Errorf("expected the body to be resent on retry, got status %d and bodies %q", res.StatusCode, bodies)
+	}
+	if n := strings.Count(logs.String(), `"msg":"Call Ended"`); n != 2 {
+		t.Errorf("expected 2 logged attempts, got %d:\n%s", n, logs.String())
+	}
+}
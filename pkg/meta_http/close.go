@@ -0,0 +1,64 @@
+package metahttp
+
+import (
+	"context"
+	"time"
+)
+
+// closeDrainInterval is how often Close checks for in-flight calls.
+const closeDrainInterval = 10 * time.Millisecond
+
+// Close shuts the client down: new calls fail with models.ErrClientClosed,
+// scheduled calls are stopped (they stay in their ScheduleStore) and the
+// background refresh of token providers having a Close method ends. Close
+// then waits for in-flight calls to finish, or ctx to be done, reports
+// pending usage and closes idle connections. It returns ctx.Err() when calls
+// were still running.
+func (c *client) Close(ctx context.Context) error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	c.scheduler.stop()
+	for _, signer := range c.signers {
+		if ts, ok := signer.(tokenSigner); ok {
+			closeBackground(ts.provider)
+		}
+		closeBackground(signer)
+	}
+
+	err := c.drain(ctx)
+	if c.usageReport != nil {
+		c.usageReport.stop()
+	}
+	c.CloseIdleConnections()
+	return err
+}
+
+func (c *client) drain(ctx context.Context) error {
+	if c.stats.inFlight.Load() == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(closeDrainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if c.stats.inFlight.Load() == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// closeBackground stops the background work of v, e.g. a
+// CachedTokenProvider.
+func closeBackground(v interface{}) {
+	switch c := v.(type) {
+	case interface{ Close() }:
+		c.Close()
+	case interface{ Close() error }:
+		c.Close()
+	}
+}
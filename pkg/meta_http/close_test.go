@@ -0,0 +1,78 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestClose(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var fetches atomic.Int32
+	tokens := metahttp.NewCachedTokenProvider(metahttp.TokenProviderFunc(func(ctx context.Context) (*models.Token, error) {
+		fetches.Add(1)
+		return &models.Token{AccessToken: "t", ExpiresAt: time.Now().Add(1100 * time.Millisecond)}, nil
+	}), time.Second, nil)
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithTokenProvider(tokens))
+	if _, err := client.Schedule(context.Background(), time.Now().Add(50*time.Millisecond), metahttp.ScheduledRequest{Method: http.MethodPost, Path: "/later"}); err != nil {
+		t.Fatal(err)
+	}
+
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := client.Get(context.Background(), "/slow", nil, nil)
+		inflight <- err
+	}()
+	for client.Stats().InFlight == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected close to give up on the in-flight call, got %v", err)
+	}
+	if _, err := client.Get(context.Background(), "/", nil, nil); !errors.Is(err, models.ErrClientClosed) {
+		t.Errorf("expected closed client error, got %v", err)
+	}
+	if res := client.Healthy(context.Background(), "/"); !errors.Is(res.Err, models.ErrClientClosed) {
+		t.Errorf("expected closed client error, got %+v", res)
+	}
+
+	close(release)
+	if err := <-inflight; err != nil {
+		t.Errorf("in-flight call must complete, got %v", err)
+	}
+	if err := client.Close(context.Background()); err != nil {
+		t.Errorf("closing again must succeed, got %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("scheduled call must not run after close, got %d calls", n)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("token refresh must stop after close, got %d fetches", n)
+	}
+}
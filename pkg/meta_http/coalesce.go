@@ -0,0 +1,139 @@
+package metahttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader identifies retries of the same logical operation.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxCoalescedBody bounds the responses buffered to be shared. Larger ones
+// stream to the first caller only.
+const maxCoalescedBody = 1 << 20
+
+// errCoalescedIncomplete is handed to the callers waiting on a request that
+// panicked.
+var errCoalescedIncomplete = errors.New("coalesced request did not complete")
+
+// coalescingRoundTripper sends concurrent requests sharing an Idempotency-Key
+// upstream once. Requests arriving within window after the first completed
+// share its outcome as well.
+type coalescingRoundTripper struct {
+	next   http.RoundTripper
+	window time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	res  *http.Response
+	body []byte
+	err  error
+	// unshared is set when the outcome is the first caller's own: its
+	// context ended, or the response was too large to buffer. The others
+	// then send the request themselves.
+	unshared bool
+}
+
+func newCoalescingRoundTripper(window time.Duration, next http.RoundTripper) *coalescingRoundTripper {
+	return &coalescingRoundTripper{
+		next:     next,
+		window:   window,
+		inflight: map[string]*coalescedCall{},
+	}
+}
+
+func (c *coalescingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		return c.next.RoundTrip(r)
+	}
+	key = r.Method + " " + r.URL.String() + " " + key
+
+	for {
+		c.mu.Lock()
+		call, ok := c.inflight[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			if call.unshared {
+				continue
+			}
+			closeRequestBody(r)
+			return call.response(r)
+		case <-r.Context().Done():
+			closeRequestBody(r)
+			return nil, r.Context().Err()
+		}
+	}
+	call := &coalescedCall{done: make(chan struct{}), err: errCoalescedIncomplete}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	// Deferred so that the waiting callers are released even on panics.
+	defer func() {
+		if call.unshared {
+			c.mu.Lock()
+			delete(c.inflight, key)
+			c.mu.Unlock()
+		} else {
+			time.AfterFunc(c.window, func() {
+				c.mu.Lock()
+				delete(c.inflight, key)
+				c.mu.Unlock()
+			})
+		}
+		close(call.done)
+	}()
+
+	res, err := c.next.RoundTrip(r)
+	if err != nil {
+		call.err, call.unshared = err, r.Context().Err() != nil
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxCoalescedBody+1))
+	if err != nil {
+		res.Body.Close()
+		call.err, call.unshared = err, r.Context().Err() != nil
+		return nil, err
+	}
+	if len(body) > maxCoalescedBody {
+		call.unshared = true
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+		return res, nil
+	}
+	res.Body.Close()
+	call.res, call.body, call.err = res, body, nil
+	return call.response(r)
+}
+
+// response gives every caller its own copy of the shared response.
+func (call *coalescedCall) response(r *http.Request) (*http.Response, error) {
+	if call.err != nil {
+		return nil, call.err
+	}
+	res := *call.res
+	res.Header = call.res.Header.Clone()
+	res.Body = io.NopCloser(bytes.NewReader(call.body))
+	res.Request = r
+	return &res, nil
+}
+
+func closeRequestBody(r *http.Request) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+}
@@ -0,0 +1,170 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestIdempotencyCoalescing(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, `{"payment":%d}`, n)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithIdempotencyCoalescing(100*time.Millisecond))
+	submit := func(key string) int {
+		var res struct{ Payment int }
+		headers := map[string]string{metahttp.IdempotencyKeyHeader: key}
+		if _, err := client.Post(context.Background(), "/payments", headers, map[string]int{"amount": 100}, &res); err != nil {
+			t.Error(err)
+		}
+		return res.Payment
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = submit("key-1")
+		}(i)
+	}
+	wg.Wait()
+	for _, payment := range results {
+		if payment != 1 {
+			t.Errorf("expected every caller to share payment 1, got %v", results)
+			break
+		}
+	}
+
+	if payment := submit("key-1"); payment != 1 || calls.Load() != 1 {
+		t.Errorf("duplicates within the window must be coalesced, got payment %d after %d calls", payment, calls.Load())
+	}
+	if payment := submit("key-2"); payment != 2 {
+		t.Errorf("other keys must not be coalesced, got payment %d", payment)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if payment := submit("key-1"); payment != 3 {
+		t.Errorf("expected a new call after the window, got payment %d", payment)
+	}
+}
+
+func TestIdempotencyCoalescingLeaderCanceled(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprintf(rw, `{"payment":%d}`, n)
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(), metahttp.WithIdempotencyCoalescing(time.Second))
+	headers := map[string]string{metahttp.IdempotencyKeyHeader: "key-1"}
+
+	leader, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := client.Post(leader, "/payments", headers, map[string]int{"amount": 100}, nil)
+		leaderErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	var res struct{ Payment int }
+	if _, err := client.Post(context.Background(), "/payments", headers, map[string]int{"amount": 100}, &res); err != nil {
+		t.Fatalf("follower failed with the leader's cancellation: %v", err)
+	}
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader: %v", err)
+	}
+	if res.Payment != 2 {
+		t.Errorf("expected the follower to send the call itself, got payment %d", res.Payment)
+	}
+}
+
+func TestIdempotencyCoalescingLargeResponse(t *testing.T) {
+	large := strings.Repeat("x", 2<<20)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		rw.Write([]byte(large))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(), metahttp.WithIdempotencyCoalescing(time.Second))
+	headers := map[string]string{metahttp.IdempotencyKeyHeader: "key-1"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var body string
+			if _, err := client.Post(context.Background(), "/exports", headers, nil, &body); err != nil || len(body) != len(large) {
+				t.Errorf("got %d bytes, %v", len(body), err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected large responses not to be shared, got %d calls", n)
+	}
+}
+
+func TestIdempotencyCoalescingLeaderPanics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var signed atomic.Int32
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(), metahttp.WithIdempotencyCoalescing(time.Second),
+		metahttp.WithSigner(metahttp.SignerFunc(func(r *http.Request) error {
+			if signed.Add(1) == 1 {
+				time.Sleep(50 * time.Millisecond)
+				panic("signer bug")
+			}
+			return nil
+		})))
+	headers := map[string]string{metahttp.IdempotencyKeyHeader: "key-1"}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.Post(context.Background(), "/payments", headers, nil, nil)
+			errs <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("expected the panicking call to fail its callers")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("caller left waiting on a panicked call")
+		}
+	}
+}
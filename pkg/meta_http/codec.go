@@ -0,0 +1,156 @@
+package metahttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes request bodies and decodes response bodies for a media type.
+type Codec interface {
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Decode reads the body into a pooled buffer and unmarshals it, which
+// allocates far less than a json.Decoder per response. Unmarshal rejects
+// bodies a Decoder accepts, such as trailing data after the document, so
+// those are decoded again the Decoder way.
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+	if _, err := buf.ReadFrom(r); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(buf.Bytes(), v); err != nil {
+		return json.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(v)
+	}
+	return nil
+}
+
+// envelopeCodec decodes JSON responses from a top-level field of the
+// document.
+type envelopeCodec struct {
+	field string
+}
+
+func (envelopeCodec) Encode(w io.Writer, v interface{}) error {
+	return jsonCodec{}.Encode(w, v)
+}
+
+func (e envelopeCodec) Decode(r io.Reader, v interface{}) error {
+	var envelope map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
+		return err
+	}
+	field, ok := envelope[e.field]
+	if !ok {
+		return fmt.Errorf("response envelope has no %q field", e.field)
+	}
+	return json.Unmarshal(field, v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Encode(w io.Writer, v interface{}) error {
+	return msgpack.NewEncoder(w).Encode(v)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v interface{}) error {
+	return msgpack.NewDecoder(r).Decode(v)
+}
+
+// textCodec reads and writes bodies verbatim through string and []byte values.
+// Any other decode target is treated as JSON.
+type textCodec struct{}
+
+func (textCodec) Encode(w io.Writer, v interface{}) error {
+	switch t := v.(type) {
+	case string:
+		_, err := io.WriteString(w, t)
+		return err
+	case []byte:
+		_, err := w.Write(t)
+		return err
+	}
+	return fmt.Errorf("text codec cannot encode %T", v)
+}
+
+func (textCodec) Decode(r io.Reader, v interface{}) error {
+	switch t := v.(type) {
+	case *string:
+		b, err := io.ReadAll(r)
+		*t = string(b)
+		return err
+	case *[]byte:
+		b, err := io.ReadAll(r)
+		*t = b
+		return err
+	}
+	// Plenty of upstreams (and Go's own content sniffing) label JSON bodies
+	// as text/plain, so structured targets keep being decoded as JSON.
+	return jsonCodec{}.Decode(r, v)
+}
+
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		"application/json":      jsonCodec{},
+		"text/json":             jsonCodec{},
+		"application/xml":       xmlCodec{},
+		"text/xml":              xmlCodec{},
+		"application/msgpack":   msgpackCodec{},
+		"application/x-msgpack": msgpackCodec{},
+		"text/plain":            textCodec{},
+	}
+}
+
+// codecFor resolves the codec for a Content-Type header value. Structured
+// syntax suffixes such as "application/problem+json" fall back to the codec
+// registered for "application/json". A missing Content-Type is treated as
+// JSON.
+func codecFor(codecs map[string]Codec, contentType string) (Codec, bool) {
+	// Spare parsing the overwhelmingly common case.
+	if contentType == "" || contentType == "application/json" || strings.HasPrefix(contentType, "application/json;") {
+		return codecs["application/json"], true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	if codec, ok := codecs[mediaType]; ok {
+		return codec, true
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		codec, ok := codecs["application/"+mediaType[i+1:]]
+		return codec, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,214 @@
+package metahttp_test
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+type greeting struct {
+	XMLName xml.Name `xml:"greeting" msgpack:"-"`
+	Goodbye string   `xml:"goodbye" msgpack:"goodbye"`
+}
+
+func TestContentTypeDrivenDecoding(t *testing.T) {
+	packed, _ := msgpack.Marshal(greeting{Goodbye: "World"})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/xml":
+			rw.Header().Set("Content-Type", "application/xml")
+			rw.Write([]byte("<greeting><goodbye>World</goodbye></greeting>"))
+		case "/msgpack":
+			rw.Header().Set("Content-Type", "application/msgpack")
+			rw.Write(packed)
+		case "/problem":
+			rw.Header().Set("Content-Type", "application/problem+json")
+			rw.Write([]byte(`{"Goodbye":"World"}`))
+		default:
+			rw.Header().Set("Content-Type", "application/pdf")
+			rw.Write([]byte("%PDF"))
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	for _, path := range []string{"/xml", "/msgpack", "/problem"} {
+		var res greeting
+		if _, err := metaHttpClient.Get(context.Background(), path, map[string]string{}, &res); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+		if res.Goodbye != "World" {
+			t.Errorf("%s: response body is not as expected", path)
+		}
+	}
+
+	var res greeting
+	_, err := metaHttpClient.Get(context.Background(), "/pdf", map[string]string{}, &res)
+	var unsupported *models.UnsupportedContentTypeError
+	if !errors.As(err, &unsupported) || unsupported.ContentType != "application/pdf" {
+		t.Errorf("expected unsupported content type error, got: %v", err)
+	}
+}
+
+// Response targets that are not a non-nil pointer are decoded through a
+// pointer to a copy, as they always were: the call succeeds, and the caller's
+// value is left as it was.
+func TestNonPointerResponseTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"Goodbye":"World"}`))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 10*time.Second, metahttp.WithoutLogging())
+	var nilTarget *greeting
+	values := map[string]string{}
+	for _, res := range []interface{}{greeting{}, values, nilTarget} {
+		if _, err := client.Get(context.Background(), "/json", nil, res); err != nil {
+			t.Errorf("%T: expected the call to succeed, got %v", res, err)
+		}
+	}
+	if len(values) != 0 || nilTarget != nil {
+		t.Errorf("expected the targets to be left as they were, got %v, %v", values, nilTarget)
+	}
+
+	var res greeting
+	if _, err := client.Get(context.Background(), "/json", nil, &res); err != nil || res.Goodbye != "World" {
+		t.Errorf("unexpected response %+v, %v", res, err)
+	}
+}
+
+func TestRawResponseTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/csv" {
+			rw.Header().Set("Content-Type", "text/csv")
+			rw.Write([]byte("id,name\n1,meta\n"))
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	var text string
+	if _, err := metaHttpClient.Get(context.Background(), "/ok", map[string]string{}, &text); err != nil {
+		t.Error(err.Error())
+	}
+	if text != "OK" {
+		t.Errorf("unexpected body: %q", text)
+	}
+
+	var raw []byte
+	if _, err := metaHttpClient.Get(context.Background(), "/csv", map[string]string{}, &raw); err != nil {
+		t.Error(err.Error())
+	}
+	if string(raw) != "id,name\n1,meta\n" {
+		t.Errorf("unexpected body: %q", raw)
+	}
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/greeting":
+			rw.Header().Set("Content-Type", "application/json")
+			rw.Write([]byte(`{"success":true,"data":{"goodbye":"world"}}`))
+		case "/raw":
+			rw.Write([]byte(`{"data":{}}`))
+		default:
+			rw.Header().Set("Content-Type", "application/json")
+			rw.Write([]byte(`{"success":true}`))
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithResponseEnvelope("data"))
+
+	var res struct {
+		Goodbye string `json:"goodbye"`
+	}
+	if _, err := client.Get(context.Background(), "/greeting", nil, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Goodbye != "world" {
+		t.Errorf("expected the envelope to be unwrapped, got %+v", res)
+	}
+
+	var raw string
+	if _, err := client.Get(context.Background(), "/raw", nil, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw != `{"data":{}}` {
+		t.Errorf("raw targets must get the whole body, got %q", raw)
+	}
+
+	_, err := client.Get(context.Background(), "/missing", nil, &res)
+	if models.CategoryOf(err) != models.CategoryDecode {
+		t.Errorf("expected a decode error, got: %v", err)
+	}
+}
+
+func TestContentTypeAndAcceptOverrides(t *testing.T) {
+	var gotType, gotAccept, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		gotType, gotAccept, gotBody = req.Header.Get("Content-Type"), req.Header.Get("Accept"), string(b)
+		// No Content-Type: the client must rely on what it asked for.
+		rw.Header()["Content-Type"] = nil
+		rw.Write([]byte("<greeting><goodbye>bye</goodbye></greeting>"))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	ctx := context.Background()
+
+	var res greeting
+	if _, err := client.Post(ctx, "/jose", nil, "eyJhbGciOiJSUzI1NiJ9.e30.c2ln", &res,
+		metahttp.WithContentType("application/jose"),
+		metahttp.WithAccept("application/xml, */*;q=0.1"),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if gotType != "application/jose" || gotAccept != "application/xml, */*;q=0.1" {
+		t.Errorf("headers not overridden: Content-Type %q, Accept %q", gotType, gotAccept)
+	}
+	if gotBody != "eyJhbGciOiJSUzI1NiJ9.e30.c2ln" {
+		t.Errorf("raw body not sent as-is: %q", gotBody)
+	}
+	if res.Goodbye != "bye" {
+		t.Errorf("response not decoded as XML: %+v", res)
+	}
+
+	if _, err := client.Post(ctx, "/xml", nil, greeting{Goodbye: "hi"}, nil,
+		metahttp.WithContentType("application/xml"),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if gotBody != "<greeting><goodbye>hi</goodbye></greeting>" {
+		t.Errorf("body not encoded as XML: %q", gotBody)
+	}
+
+	if _, err := client.Post(ctx, "/jose", nil, map[string]string{"a": "b"}, nil,
+		metahttp.WithContentType("application/jose"),
+	); err == nil {
+		t.Error("expected an error encoding a map without a codec")
+	}
+}
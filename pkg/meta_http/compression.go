@@ -0,0 +1,174 @@
+package metahttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+)
+
+// decompressRoundTripper decodes gzip, brotli and zstd response bodies. The
+// standard transport only decodes gzip when it negotiated it itself, so once
+// Accept-Encoding is set explicitly every encoding is handled here.
+type decompressRoundTripper struct {
+	next           http.RoundTripper
+	acceptEncoding string
+}
+
+func (d decompressRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if d.acceptEncoding != "" && r.Header.Get("Accept-Encoding") == "" {
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept-Encoding", d.acceptEncoding)
+	}
+
+	res, err := d.next.RoundTrip(r)
+	if err != nil || res.Body == nil || r.Method == http.MethodHead {
+		return res, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+	var body io.ReadCloser
+	switch encoding {
+	case EncodingBrotli:
+		body = &decodedBody{Reader: brotli.NewReader(res.Body), raw: res.Body}
+	case EncodingZstd:
+		dec, err := zstd.NewReader(res.Body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			res.Body.Close()
+			return nil, fmt.Errorf("zstd response: %w", err)
+		}
+		body = &decodedBody{Reader: dec, raw: res.Body, close: dec.Close}
+	case EncodingGzip:
+		body = &decodedBody{raw: res.Body, lazyGzip: true}
+	default:
+		return res, nil
+	}
+
+	res.Body = body
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+	return res, nil
+}
+
+// decodedBody reads the decompressed stream and closes the raw body. gzip
+// readers are created on first read since their constructor already reads
+// the header.
+type decodedBody struct {
+	io.Reader
+	raw      io.ReadCloser
+	close    func()
+	lazyGzip bool
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.lazyGzip {
+		b.lazyGzip = false
+		gz, err := gzip.NewReader(b.raw)
+		if err != nil {
+			b.Reader = errReader{err}
+		} else {
+			b.Reader = gz
+		}
+	}
+	return b.Reader.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	if b.close != nil {
+		b.close()
+	}
+	return b.raw.Close()
+}
+
+type requestCompression struct {
+	encoding string
+	minSize  int
+}
+
+func newEncoder(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewWriter(w), nil
+	case EncodingBrotli:
+		return brotli.NewWriter(w), nil
+	case EncodingZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+	return nil, fmt.Errorf("unsupported request content encoding %q", encoding)
+}
+
+// newDecoder decompresses r, encoded with encoding.
+func newDecoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewReader(r)
+	case EncodingBrotli:
+		return io.NopCloser(brotli.NewReader(r)), nil
+	case EncodingZstd:
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// compressBuffer returns a new pooled buffer holding src compressed with
+// encoding. src is left untouched.
+func compressBuffer(src *pooledBuffer, encoding string) (*pooledBuffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	w, err := newEncoder(encoding, buf)
+	if err == nil {
+		if _, err = w.Write(src.Bytes()); err == nil {
+			err = w.Close()
+		}
+	}
+	if err != nil {
+		bufferPool.Put(buf)
+		return nil, err
+	}
+
+	pb := &pooledBuffer{buf: buf}
+	pb.refs.Store(1)
+	return pb, nil
+}
+
+// compressStream compresses every body produced by getBody on the fly.
+func compressStream(getBody func() (io.ReadCloser, error), encoding string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		raw, err := getBody()
+		if err != nil {
+			return nil, err
+		}
+		pr, pw := io.Pipe()
+		w, err := newEncoder(encoding, pw)
+		if err != nil {
+			raw.Close()
+			return nil, err
+		}
+		go func() {
+			_, err := io.Copy(w, raw)
+			raw.Close()
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+}
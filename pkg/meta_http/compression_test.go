@@ -0,0 +1,102 @@
+package metahttp_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestResponseDecompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		encoding := strings.Split(r.Header.Get("Accept-Encoding"), ",")[0]
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Content-Encoding", encoding)
+
+		var w io.WriteCloser
+		switch encoding {
+		case metahttp.EncodingBrotli:
+			w = brotli.NewWriter(rw)
+		case metahttp.EncodingZstd:
+			w, _ = zstd.NewWriter(rw)
+		case metahttp.EncodingGzip:
+			w = gzip.NewWriter(rw)
+		default:
+			t.Errorf("unexpected Accept-Encoding: %q", r.Header.Get("Accept-Encoding"))
+			return
+		}
+		w.Write([]byte(`{"encoding":"` + encoding + `"}`))
+		w.Close()
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	for _, encoding := range []string{metahttp.EncodingBrotli, metahttp.EncodingZstd, metahttp.EncodingGzip} {
+		client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithAcceptEncoding(encoding, metahttp.EncodingGzip))
+
+		var res map[string]string
+		info, err := client.Get(context.Background(), "/", nil, &res)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if res["encoding"] != encoding {
+			t.Errorf("%s: unexpected response: %v", encoding, res)
+		}
+		if info.Header.Get("Content-Encoding") != "" {
+			t.Errorf("%s: Content-Encoding should be removed after decoding", encoding)
+		}
+	}
+}
+
+func TestRequestCompression(t *testing.T) {
+	type received struct {
+		encoding string
+		body     string
+	}
+	bodies := make(chan received, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == metahttp.EncodingGzip {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = gz
+		}
+		b, _ := io.ReadAll(body)
+		bodies <- received{encoding: r.Header.Get("Content-Encoding"), body: string(b)}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithRequestCompression(metahttp.EncodingGzip, 64))
+
+	small := map[string]string{"event": "click"}
+	large := map[string]string{"event": strings.Repeat("x", 128)}
+
+	if _, err := client.Post(context.Background(), "/", nil, small, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-bodies; got.encoding != "" || got.body != `{"event":"click"}` {
+		t.Errorf("small payloads should not be compressed: %+v", got)
+	}
+
+	if _, err := client.Post(context.Background(), "/", nil, large, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-bodies; got.encoding != metahttp.EncodingGzip || !strings.Contains(got.body, strings.Repeat("x", 128)) {
+		t.Errorf("large payloads should be gzipped: %+v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package metahttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// defaultCongestion is the wait after which an adaptive LIFO queue serves
+// the newest request first, when the limit has no QueueTimeout.
+const defaultCongestion = 100 * time.Millisecond
+
+// QueuePolicy is the order requests waiting for a ConcurrencyLimit are let
+// through in.
+type QueuePolicy int
+
+const (
+	// QueueFIFO lets the oldest waiting request through first.
+	QueueFIFO QueuePolicy = iota
+	// QueueAdaptiveLIFO is FIFO while the queue drains quickly and lets the
+	// newest request through first once the oldest has waited for half the
+	// QueueTimeout, or 100ms without one. Under overload, older requests
+	// have likely been given up on by their callers, and serving the newest
+	// keeps latency bounded for some rather than high for all.
+	QueueAdaptiveLIFO
+)
+
+// ConcurrencyLimit bounds the requests of a client in flight at once. Each
+// attempt takes a slot until its response body is read or closed.
+type ConcurrencyLimit struct {
+	MaxInFlight int
+	// MaxQueue bounds the requests waiting for a slot, unbounded when zero.
+	// Requests beyond it fail with models.ErrConcurrencyLimited.
+	MaxQueue int
+	// QueueTimeout bounds the wait for a slot, after which requests fail
+	// with models.ErrConcurrencyLimited. Only the context bounds it when
+	// zero.
+	QueueTimeout time.Duration
+	Policy       QueuePolicy
+}
+
+type concurrencyLimiter struct {
+	limit      ConcurrencyLimit
+	congestion time.Duration
+
+	mu       sync.Mutex
+	inFlight int
+	// queue holds the waiting requests by arrival.
+	queue []*slotWaiter
+}
+
+type slotWaiter struct {
+	ready    chan struct{}
+	enqueued time.Time
+}
+
+func newConcurrencyLimiter(limit ConcurrencyLimit) *concurrencyLimiter {
+	if limit.MaxInFlight < 1 {
+		limit.MaxInFlight = 1
+	}
+	congestion := defaultCongestion
+	if limit.QueueTimeout > 0 {
+		congestion = limit.QueueTimeout / 2
+	}
+	return &concurrencyLimiter{limit: limit, congestion: congestion}
+}
+
+// acquire waits for a slot, which must then be released.
+func (l *concurrencyLimiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	if l.inFlight < l.limit.MaxInFlight && len(l.queue) == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	if l.limit.MaxQueue > 0 && len(l.queue) >= l.limit.MaxQueue {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: %d requests queued", models.ErrConcurrencyLimited, l.limit.MaxQueue)
+	}
+	w := &slotWaiter{ready: make(chan struct{}), enqueued: time.Now()}
+	l.queue = append(l.queue, w)
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.limit.QueueTimeout > 0 {
+		timer := time.NewTimer(l.limit.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-timeout:
+		err = fmt.Errorf("%w: queued for %s", models.ErrConcurrencyLimited, l.limit.QueueTimeout)
+	case <-ctx.Done():
+		err = fmt.Errorf("%w: %v", models.ErrConcurrencyLimited, ctx.Err())
+	}
+
+	l.mu.Lock()
+	for i, queued := range l.queue {
+		if queued == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			l.mu.Unlock()
+			return err
+		}
+	}
+	l.mu.Unlock()
+	// The slot was handed over meanwhile: pass it on.
+	l.release()
+	return err
+}
+
+// release hands the slot over to the next waiting request, if any.
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queue) == 0 {
+		l.inFlight--
+		return
+	}
+	next := 0
+	if l.limit.Policy == QueueAdaptiveLIFO && time.Since(l.queue[0].enqueued) >= l.congestion {
+		next = len(l.queue) - 1
+	}
+	w := l.queue[next]
+	l.queue = append(l.queue[:next], l.queue[next+1:]...)
+	close(w.ready)
+}
+
+type concurrencyRoundTripper struct {
+	next    http.RoundTripper
+	limiter *concurrencyLimiter
+}
+
+func (c concurrencyRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := c.limiter.acquire(r.Context()); err != nil {
+		return nil, err
+	}
+	res, err := c.next.RoundTrip(r)
+	if err != nil || res.Body == nil {
+		c.limiter.release()
+		return res, err
+	}
+	res.Body = &slotBody{ReadCloser: res.Body, release: c.limiter.release}
+	return res, nil
+}
+
+// slotBody releases the slot of its request once read to the end or closed.
+type slotBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *slotBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *slotBody) Close() error {
+	b.once.Do(b.release)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,97 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// gatedServer reports the X-Seq header of each request as it arrives and
+// holds it until the gate lets it through.
+func gatedServer() (*httptest.Server, chan string, chan struct{}) {
+	arrived := make(chan string, 16)
+	gate := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		arrived <- r.Header.Get("X-Seq")
+		<-gate
+	}))
+	return server, arrived, gate
+}
+
+func TestConcurrencyLimitQueuePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy metahttp.QueuePolicy
+		want   string
+	}{
+		{"fifo", metahttp.QueueFIFO, "0123"},
+		{"adaptive lifo", metahttp.QueueAdaptiveLIFO, "0321"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, arrived, gate := gatedServer()
+			defer server.Close()
+			client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+				metahttp.WithConcurrencyLimit(metahttp.ConcurrencyLimit{MaxInFlight: 1, Policy: tc.policy}))
+
+			errs := make(chan error, 4)
+			order := ""
+			for i := 0; i < 4; i++ {
+				go func(i int) {
+					_, err := client.Get(context.Background(), "/", map[string]string{"X-Seq": strconv.Itoa(i)}, nil)
+					errs <- err
+				}(i)
+				if i == 0 {
+					order += <-arrived
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			// The oldest waiter is now past the congestion threshold.
+			time.Sleep(120 * time.Millisecond)
+			for i := 0; i < 3; i++ {
+				gate <- struct{}{}
+				order += <-arrived
+			}
+			gate <- struct{}{}
+			for i := 0; i < 4; i++ {
+				if err := <-errs; err != nil {
+					t.Error(err)
+				}
+			}
+			if order != tc.want {
+				t.Errorf("served in order %s, want %s", order, tc.want)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimitRejects(t *testing.T) {
+	server, arrived, gate := gatedServer()
+	defer server.Close()
+	defer close(gate)
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithConcurrencyLimit(metahttp.ConcurrencyLimit{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 50 * time.Millisecond}))
+
+	go client.Get(context.Background(), "/", nil, nil)
+	<-arrived
+	queued := make(chan error, 1)
+	go func() {
+		_, err := client.Get(context.Background(), "/", nil, nil)
+		queued <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	started := time.Now()
+	if _, err := client.Get(context.Background(), "/", nil, nil); !errors.Is(err, models.ErrConcurrencyLimited) || time.Since(started) > 20*time.Millisecond {
+		t.Errorf("full queue: %v after %s", err, time.Since(started))
+	}
+	if err := <-queued; !errors.Is(err, models.ErrConcurrencyLimited) || models.CategoryOf(err) != models.CategoryRequest {
+		t.Errorf("queue timeout: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package metahttp
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+const (
+	// nearTimeout is the share of its budget past which a call counts as
+	// close to timing out.
+	nearTimeout = 0.8
+	// nearTimeoutWarnShare is the share of calls close to timing out past
+	// which WithTimeoutWarnings warns.
+	nearTimeoutWarnShare = 0.01
+	// minWarnSamples and warnInterval keep the warnings of
+	// WithTimeoutWarnings meaningful and rare.
+	minWarnSamples = 100
+	warnInterval   = time.Minute
+)
+
+// TimeoutReport tells how close the recent calls of a client came to their
+// timeout, to right-size it. Calls rejected before being sent or canceled by
+// their caller are not counted.
+type TimeoutReport struct {
+	// Calls is the number of recent calls the report covers, at most 1024.
+	Calls int `json:"calls"`
+	// Timeout is the timeout of the client. Calls made WithTimeout or with
+	// a context deadline may have had a shorter budget.
+	Timeout time.Duration `json:"timeout"`
+	// P50, P95 and P99 are latency percentiles.
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+	// P50Budget, P95Budget and P99Budget are percentiles of the share of
+	// its budget each call used, 1 or more for calls that timed out.
+	P50Budget float64 `json:"p50_budget"`
+	P95Budget float64 `json:"p95_budget"`
+	P99Budget float64 `json:"p99_budget"`
+	// NearTimeout is the share of calls that used more than 80% of their
+	// budget.
+	NearTimeout float64 `json:"near_timeout"`
+	// Suggested is the timeout putting P99 at 80% of the budget, zero
+	// without calls.
+	Suggested time.Duration `json:"suggested"`
+}
+
+// deadlineTracker samples the latency of recent calls against their budget.
+type deadlineTracker struct {
+	mu        sync.Mutex
+	calls     int64
+	latencies [latencySamples]time.Duration
+	used      [latencySamples]float64
+	near      [latencySamples]bool
+	lastWarn  time.Time
+}
+
+// record samples a call taking d out of budget and reports whether the
+// share of calls close to their timeout deserves a warning.
+func (t *deadlineTracker) record(d, budget time.Duration, warn bool) bool {
+	used := float64(d) / float64(budget)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := t.calls % latencySamples
+	t.latencies[i], t.used[i], t.near[i] = d, used, used > nearTimeout
+	t.calls++
+	if !warn || t.calls < minWarnSamples || time.Since(t.lastWarn) < warnInterval {
+		return false
+	}
+	if t.nearShareLocked() <= nearTimeoutWarnShare {
+		return false
+	}
+	t.lastWarn = time.Now()
+	return true
+}
+
+func (t *deadlineTracker) nearShareLocked() float64 {
+	n := min(t.calls, latencySamples)
+	near := 0
+	for _, isNear := range t.near[:n] {
+		if isNear {
+			near++
+		}
+	}
+	return float64(near) / float64(n)
+}
+
+func (t *deadlineTracker) report(timeout time.Duration) TimeoutReport {
+	t.mu.Lock()
+	n := min(t.calls, latencySamples)
+	latencies := slices.Clone(t.latencies[:n])
+	used := slices.Clone(t.used[:n])
+	report := TimeoutReport{Calls: int(n), Timeout: timeout}
+	if n > 0 {
+		report.NearTimeout = t.nearShareLocked()
+	}
+	t.mu.Unlock()
+	if n == 0 {
+		return report
+	}
+
+	slices.Sort(latencies)
+	slices.Sort(used)
+	report.P50 = percentile(latencies, 0.50)
+	report.P95 = percentile(latencies, 0.95)
+	report.P99 = percentile(latencies, 0.99)
+	report.P50Budget = percentileOf(used, 0.50)
+	report.P95Budget = percentileOf(used, 0.95)
+	report.P99Budget = percentileOf(used, 0.99)
+	report.Suggested = time.Duration(float64(report.P99) / nearTimeout)
+	return report
+}
+
+// percentileOf returns the p-th percentile of sorted samples, nearest rank.
+func percentileOf(sorted []float64, p float64) float64 {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+// callBudget returns the time a call made with ctx and co has, zero when
+// unbounded.
+func (c *client) callBudget(ctx context.Context, co *callOptions) time.Duration {
+	budget := c.HTTPClient.Timeout
+	if co.timeout > 0 && (budget <= 0 || co.timeout < budget) {
+		budget = co.timeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); budget <= 0 || left < budget {
+			budget = left
+		}
+	}
+	return budget
+}
+
+// recordDeadline samples a call started at started with budget, warning
+// when WithTimeoutWarnings asks for it and too many calls come close to
+// their timeout.
+func (c *client) recordDeadline(ctx context.Context, started time.Time, budget time.Duration, err error) {
+	if budget <= 0 {
+		return
+	}
+	switch models.CategoryOf(err) {
+	case models.CategoryRequest, models.CategoryCanceled:
+		return
+	}
+	if !c.stats.deadlines.record(time.Since(started), budget, c.timeoutWarnings) {
+		return
+	}
+	report := c.stats.deadlines.report(c.HTTPClient.Timeout)
+	loggerFor(ctx, c.logger).WarnContext(
+		ctx,
+		"Calls close to their timeout",
+		slog.Float64("near_timeout", report.NearTimeout),
+		slog.Int64("p99", report.P99.Milliseconds()),
+		slog.Int64("timeout", report.Timeout.Milliseconds()),
+		slog.Int64("suggested", report.Suggested.Milliseconds()),
+	)
+}
@@ -0,0 +1,82 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTimeoutReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(170 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	client := metahttp.NewClient(server.URL, logger, 200*time.Millisecond, metahttp.WithTimeoutWarnings())
+	if report := client.Stats().Timeouts; report.Calls != 0 || report.Suggested != 0 || report.Timeout != 200*time.Millisecond {
+		t.Errorf("report before calls = %+v", report)
+	}
+
+	for i := 0; i < 98; i++ {
+		if _, err := client.Get(context.Background(), "/fast", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Contains(logs.String(), "Calls close to their timeout") {
+		t.Error("warned without calls close to their timeout")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), "/slow", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := client.Stats().Timeouts
+	if report.Calls != 100 || report.NearTimeout != 0.02 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.P50Budget >= 0.5 || report.P99Budget < 0.8 || report.P99 < 160*time.Millisecond {
+		t.Errorf("percentiles = %+v", report)
+	}
+	if report.Suggested <= report.Timeout {
+		t.Errorf("suggested %s, want more than %s", report.Suggested, report.Timeout)
+	}
+	if n := strings.Count(logs.String(), "Calls close to their timeout"); n != 1 {
+		t.Errorf("%d warnings, want 1:\n%s", n, logs.String())
+	}
+
+	// A shorter call budget is what the call is measured against.
+	client.Get(context.Background(), "/slow", nil, nil, metahttp.WithTimeout(50*time.Millisecond))
+	if report := client.Stats().Timeouts; report.Calls != 101 || report.NearTimeout != 3.0/101 {
+		t.Errorf("after a timed out call: %+v", report)
+	}
+}
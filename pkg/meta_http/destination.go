@@ -0,0 +1,65 @@
+package metahttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// DestinationPolicy decides whether a request may be sent to u. It is
+// consulted for every attempt and every redirect hop before dialing.
+type DestinationPolicy interface {
+	CheckDestination(u *url.URL) error
+}
+
+// DestinationPolicyFunc adapts a plain function to the DestinationPolicy
+// interface.
+type DestinationPolicyFunc func(u *url.URL) error
+
+func (f DestinationPolicyFunc) CheckDestination(u *url.URL) error {
+	return f(u)
+}
+
+// AllowHosts permits only hosts matching one of patterns. A pattern is either
+// an exact host name ("api.partner.com") or a wildcard ("*.partner.com")
+// matching any subdomain but not the bare domain. Matching ignores case and
+// ports. Other hosts fail with models.ErrBlockedDestination.
+func AllowHosts(patterns ...string) DestinationPolicy {
+	normalized := make([]string, len(patterns))
+	for i, p := range patterns {
+		normalized[i] = strings.ToLower(strings.TrimSuffix(p, "."))
+	}
+	return DestinationPolicyFunc(func(u *url.URL) error {
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		for _, p := range normalized {
+			if suffix, ok := strings.CutPrefix(p, "*"); ok {
+				if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+					return nil
+				}
+			} else if host == p {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: host %s is not allowed", models.ErrBlockedDestination, u.Hostname())
+	})
+}
+
+type destinationRoundTripper struct {
+	next     http.RoundTripper
+	policies []DestinationPolicy
+}
+
+func (d destinationRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	for _, policy := range d.policies {
+		if err := policy.CheckDestination(r.URL); err != nil {
+			if r.Body != nil {
+				r.Body.Close()
+			}
+			return nil, err
+		}
+	}
+	return d.next.RoundTrip(r)
+}
@@ -0,0 +1,59 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestAllowHosts(t *testing.T) {
+	policy := metahttp.AllowHosts("*.partner.com", "api.example.com")
+	cases := map[string]bool{
+		"https://hooks.partner.com/cb":      true,
+		"https://a.b.PARTNER.com:8443/cb":   true,
+		"https://partner.com/cb":            false,
+		"https://evilpartner.com/cb":        false,
+		"https://api.example.com/":          true,
+		"https://api.example.com.evil.io/":  false,
+		"http://169.254.169.254/latest/":    false,
+		"https://hooks.partner.com.evil.io": false,
+	}
+	for raw, allowed := range cases {
+		u, _ := url.Parse(raw)
+		err := policy.CheckDestination(u)
+		if allowed && err != nil {
+			t.Errorf("%s should be allowed: %v", raw, err)
+		}
+		if !allowed && !errors.Is(err, models.ErrBlockedDestination) {
+			t.Errorf("%s should be blocked, got: %v", raw, err)
+		}
+	}
+}
+
+func TestDestinationPolicyOnRedirect(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		t.Error("redirect target should not be called")
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		http.Redirect(rw, r, "http://localhost:"+other.URL[len("http://127.0.0.1:"):], http.StatusFound)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithDestinationPolicy(metahttp.AllowHosts("127.0.0.1")))
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); !errors.Is(err, models.ErrBlockedDestination) {
+		t.Errorf("expected redirect to be blocked, got: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package metahttp
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/onmetahq/meta-http/pkg/masking"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// DryRunHeader is set on the synthetic responses of clients in dry-run mode,
+// see WithDryRun.
+const DryRunHeader = "X-Dry-Run"
+
+// maxDryRunBody bounds the request body logged by a dry run.
+const maxDryRunBody = 64 << 10
+
+// dryRunTransport logs the requests it is given instead of sending them and
+// answers them with an empty 204.
+type dryRunTransport struct {
+	logger Logger
+	masker *masking.Masker
+	scrub  headerSet
+}
+
+func (d dryRunTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, maxDryRunBody))
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	attrs := []any{
+		slog.String("method", r.Method),
+		slog.String("url", r.URL.Redacted()),
+		slog.Any("request_headers", d.scrub.scrub(r.Header)),
+		slog.Int64("content_length", r.ContentLength),
+	}
+	if r.Header.Get("Content-Encoding") == "" {
+		attrs = append(attrs, slog.String("body", string(d.masker.Mask(body))))
+	}
+	if route := routeFor(r.Context()); route != "" {
+		attrs = append(attrs, slog.String("route", route))
+	}
+	attrs = append(attrs, slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))))
+	loggerFor(r.Context(), d.logger).InfoContext(r.Context(), "Dry run", attrs...)
+
+	return newFixtureResponse(r, http.StatusNoContent, http.Header{DryRunHeader: {"true"}}, nil), nil
+}
+
+// dryRunning reports whether c is a client in dry-run mode, so that the calls
+// made around its transports, such as those over gRPC, are skipped as well.
+func dryRunning(c Requests) bool {
+	cl := clientOf(c)
+	return cl != nil && cl.dryRun
+}
@@ -0,0 +1,72 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestDryRun(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		rw.Write([]byte(`{"name":"sent"}`))
+	}))
+	defer server.Close()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithDryRun(),
+		metahttp.WithSigner(metahttp.SignerFunc(func(r *http.Request) error {
+			r.Header.Set("Authorization", "Bearer secret-token")
+			return nil
+		})))
+	ctx := context.WithValue(context.Background(), models.RequestID, "req-1")
+
+	m := merchant{Name: "untouched"}
+	data, err := client.Post(ctx, "/merchants", map[string]string{"X-Channel": "web"}, merchant{Name: "acme"}, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.StatusCode != http.StatusNoContent || data.Header.Get(metahttp.DryRunHeader) != "true" {
+		t.Errorf("response = %d %v", data.StatusCode, data.Header)
+	}
+	if m.Name != "untouched" {
+		t.Errorf("result decoded: %+v", m)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("server got %d calls", n)
+	}
+
+	out := logs.String()
+	for _, want := range []string{`msg="Dry run"`, "method=POST", "/merchants", "X-Channel", "acme", "req-1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log misses %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "secret-token") {
+		t.Errorf("credentials logged:\n%s", out)
+	}
+	if !strings.Contains(out, "Authorization") {
+		t.Errorf("signed header not logged:\n%s", out)
+	}
+
+	if err := client.WarmUp(ctx, 2); err != nil {
+		t.Errorf("warm up: %v", err)
+	}
+	if _, _, err := client.DialWebSocket(ctx, "/stream", nil); !errors.Is(err, models.ErrDryRun) {
+		t.Errorf("websocket: %v", err)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("server got %d calls", n)
+	}
+}
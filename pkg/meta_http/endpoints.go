@@ -0,0 +1,208 @@
+package metahttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// ErrUnknownEndpoint is returned by Endpoints.Call for names no Endpoint
+// was defined with.
+var ErrUnknownEndpoint = errors.New("unknown endpoint")
+
+// Endpoint defines a call once, so that its policies live in one place
+// rather than at every call site. See NewEndpoints.
+type Endpoint struct {
+	// Name identifies the endpoint in Endpoints.Call.
+	Name   string
+	Method string
+	// Path is a template whose {params} are filled by the params of
+	// Endpoints.Call, e.g. /merchants/{id}/orders. It is also the route the
+	// calls are labelled with, see WithRouteTemplates.
+	Path string
+	// Timeout bounds each call, retries included, see WithTimeout.
+	Timeout time.Duration
+	// Retry replaces the retry policy of the client when set, see WithRetry.
+	Retry *models.Retry
+	// ExpectedStatus lists the statuses calls succeed with, any 2xx when
+	// empty, see WithExpectedStatus.
+	ExpectedStatus []int
+	// Options apply to every call, before those given to Endpoints.Call.
+	Options []CallOption
+	// SLO tracks the calls against an objective in Stats.Endpoints.
+	SLO *SLO
+	// GRPC routes the calls over gRPC when set, to GRPCMethod, the full
+	// method name such as "/merchants.v1.Merchants/GetMerchant". The body
+	// and result of the calls must then be messages of the connection's
+	// codec, and calls failing with ErrGRPCFallback are made over HTTP.
+	GRPC       GRPCConn
+	GRPCMethod string
+}
+
+// SLO is the service level objective of an endpoint: the share of calls
+// that must be good. A call is bad when it fails for a reason other than the
+// caller, i.e. not with a 4xx, a rejected request or a canceled context, or
+// when it is slower than Latency.
+type SLO struct {
+	// Target is the share of good calls, e.g. 0.999.
+	Target float64
+	// Latency makes slower calls bad, only failures are when zero.
+	Latency time.Duration
+}
+
+// Endpoints makes the calls of a set of endpoints by name.
+type Endpoints struct {
+	client    Requests
+	endpoints map[string]*endpoint
+}
+
+type endpoint struct {
+	name     string
+	slo      *SLO
+	method   string
+	segments []string
+	route    string
+	opts     []CallOption
+
+	grpc       GRPCConn
+	grpcMethod string
+}
+
+// NewEndpoints defines endpoints for calls made through client. It fails
+// with models.ErrInvalidConfig on duplicate names, malformed paths or
+// invalid policies.
+//
+//	endpoints, err := metahttp.NewEndpoints(client, metahttp.Endpoint{
+//		Name:           "getMerchant",
+//		Method:         http.MethodGet,
+//		Path:           "/merchants/{id}",
+//		Timeout:        2 * time.Second,
+//		ExpectedStatus: []int{http.StatusOK},
+//	})
+//	...
+//	_, err = endpoints.Call(ctx, "getMerchant", map[string]string{"id": id}, nil, &merchant)
+func NewEndpoints(client Requests, endpoints ...Endpoint) (*Endpoints, error) {
+	e := &Endpoints{client: client, endpoints: make(map[string]*endpoint, len(endpoints))}
+	for _, def := range endpoints {
+		ep, err := newEndpoint(def)
+		if err != nil {
+			return nil, fmt.Errorf("%w: endpoint %q: %v", models.ErrInvalidConfig, def.Name, err)
+		}
+		if _, ok := e.endpoints[def.Name]; ok {
+			return nil, fmt.Errorf("%w: endpoint %q defined twice", models.ErrInvalidConfig, def.Name)
+		}
+		e.endpoints[def.Name] = ep
+	}
+	return e, nil
+}
+
+func newEndpoint(def Endpoint) (*endpoint, error) {
+	if def.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if def.Method == "" {
+		return nil, errors.New("method is required")
+	}
+	if def.SLO != nil && (def.SLO.Target <= 0 || def.SLO.Target >= 1) {
+		return nil, fmt.Errorf("SLO target must be between 0 and 1 exclusive, got %v", def.SLO.Target)
+	}
+	if def.GRPC != nil && !strings.HasPrefix(def.GRPCMethod, "/") {
+		return nil, fmt.Errorf("gRPC method %q must be a full method name, e.g. /package.Service/Method", def.GRPCMethod)
+	}
+	if def.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative, got %s", def.Timeout)
+	}
+	path := def.Path
+	if strings.ContainsAny(path, "?#") {
+		return nil, fmt.Errorf("path %q must not have a query, use WithQuery", path)
+	}
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for _, s := range segments {
+		if strings.ContainsAny(s, "{}") && !isRouteParam(s) {
+			return nil, fmt.Errorf("path %q has a malformed parameter %q", path, s)
+		}
+	}
+
+	var opts []CallOption
+	if def.Timeout > 0 {
+		opts = append(opts, WithTimeout(def.Timeout))
+	}
+	if def.Retry != nil {
+		if err := validateRetry(*def.Retry); err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithRetry(*def.Retry))
+	}
+	if len(def.ExpectedStatus) > 0 {
+		opts = append(opts, WithExpectedStatus(def.ExpectedStatus...))
+	}
+	return &endpoint{
+		name:     def.Name,
+		slo:      def.SLO,
+		method:   strings.ToUpper(def.Method),
+		segments: segments,
+		route:    "/" + strings.Join(segments, "/"),
+		opts:     append(opts, def.Options...),
+
+		grpc:       def.GRPC,
+		grpcMethod: def.GRPCMethod,
+	}, nil
+}
+
+// path fills the template with params, path-escaped.
+func (ep *endpoint) path(params map[string]string) (string, error) {
+	segments := make([]string, len(ep.segments))
+	for i, s := range ep.segments {
+		if !isRouteParam(s) {
+			segments[i] = s
+			continue
+		}
+		name := s[1 : len(s)-1]
+		v, ok := params[name]
+		if !ok || v == "" {
+			return "", fmt.Errorf("%w: missing path parameter %q", models.ErrBadURL, name)
+		}
+		segments[i] = url.PathEscape(v)
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// Call makes the call of the endpoint named name: params fill its path
+// template and opts apply after the endpoint's own options. A nil body sends
+// no body. Endpoints with a gRPC connection are called over it, falling back
+// to HTTP on ErrGRPCFallback; their params only fill the path of HTTP calls.
+// Clients in dry-run mode always make HTTP calls.
+func (e *Endpoints) Call(ctx context.Context, name string, params map[string]string, body interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	ep, ok := e.endpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEndpoint, name)
+	}
+	opts = append(ep.opts[:len(ep.opts):len(ep.opts)], opts...)
+	if ep.grpc != nil && !dryRunning(e.client) {
+		data, err := ep.callGRPC(ctx, clientOf(e.client), body, res, opts)
+		if !errors.Is(err, ErrGRPCFallback) {
+			return data, err
+		}
+	}
+	path, err := ep.path(params)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, routeKey{}, ep.route)
+	ctx = context.WithValue(ctx, endpointKey{}, ep)
+	return e.client.Do(ctx, ep.method, path, nil, body, res, opts...)
+}
+
+type endpointKey struct{}
+
+// endpointFor returns the endpoint the call ctx belongs to, nil for calls
+// not made through Endpoints.
+func endpointFor(ctx context.Context) *endpoint {
+	ep, _ := ctx.Value(endpointKey{}).(*endpoint)
+	return ep
+}
@@ -0,0 +1,141 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestEndpoints(t *testing.T) {
+	var flaky atomic.Int32
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		switch r.URL.Path {
+		case "/merchants/unknown":
+			rw.WriteHeader(http.StatusNotFound)
+		case "/settlements":
+			if flaky.Add(1) < 3 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			rw.WriteHeader(http.StatusAccepted)
+		case "/reports":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			rw.Write([]byte(`{"name":"meta"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	endpoints, err := metahttp.NewEndpoints(client,
+		metahttp.Endpoint{
+			Name:           "getMerchant",
+			Method:         http.MethodGet,
+			Path:           "/merchants/{id}",
+			ExpectedStatus: []int{http.StatusOK, http.StatusNotFound},
+		},
+		metahttp.Endpoint{
+			Name:   "settle",
+			Method: http.MethodPost,
+			Path:   "/settlements",
+			Retry: &models.Retry{
+				MaxRetries: 3,
+				Validator:  func(status int) bool { return status < 500 },
+			},
+			ExpectedStatus: []int{http.StatusAccepted},
+		},
+		metahttp.Endpoint{
+			Name:    "report",
+			Method:  http.MethodGet,
+			Path:    "/reports",
+			Timeout: 50 * time.Millisecond,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var merchant struct{ Name string }
+	if _, err := endpoints.Call(ctx, "getMerchant", map[string]string{"id": "m/1"}, nil, &merchant); err != nil || merchant.Name != "meta" {
+		t.Errorf("unexpected result %+v, %v", merchant, err)
+	}
+	if paths[0] != "/merchants/m%2F1" {
+		t.Errorf("expected the parameter to be escaped, got %s", paths[0])
+	}
+	data, err := endpoints.Call(ctx, "getMerchant", map[string]string{"id": "unknown"}, nil, &merchant)
+	if err != nil || data.StatusCode != http.StatusNotFound {
+		t.Errorf("expected an expected 404 to succeed, got %v", err)
+	}
+
+	if _, err := endpoints.Call(ctx, "settle", nil, map[string]int{"amount": 100}, nil); err != nil {
+		t.Errorf("expected the endpoint retry policy to apply, got %v", err)
+	}
+	if flaky.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", flaky.Load())
+	}
+
+	if _, err := endpoints.Call(ctx, "report", nil, nil, nil); models.CategoryOf(err) != models.CategoryTimeout {
+		t.Errorf("expected the endpoint timeout to apply, got %v", err)
+	}
+	if _, err := endpoints.Call(ctx, "getMerchant", nil, nil, nil); !errors.Is(err, models.ErrBadURL) {
+		t.Errorf("expected a missing parameter error, got %v", err)
+	}
+	if _, err := endpoints.Call(ctx, "deleteMerchant", nil, nil, nil); !errors.Is(err, metahttp.ErrUnknownEndpoint) {
+		t.Errorf("expected an unknown endpoint error, got %v", err)
+	}
+
+	for _, invalid := range [][]metahttp.Endpoint{
+		{{Name: "a", Method: http.MethodGet, Path: "/a"}, {Name: "a", Method: http.MethodGet, Path: "/b"}},
+		{{Name: "a", Method: http.MethodGet, Path: "/a/{id"}},
+		{{Name: "a", Path: "/a"}},
+		{{Name: "a", Method: http.MethodGet, Path: "/a", Retry: &models.Retry{MaxRetries: 2}}},
+	} {
+		if _, err := metahttp.NewEndpoints(client, invalid...); !errors.Is(err, models.ErrInvalidConfig) {
+			t.Errorf("expected %+v to be rejected, got %v", invalid, err)
+		}
+	}
+}
+
+func TestEndpointTimeoutKeepsErrorCategory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	endpoints, err := metahttp.NewEndpoints(client, metahttp.Endpoint{
+		Name: "quote", Method: http.MethodGet, Path: "/quote", Timeout: time.Second,
+		SLO: &metahttp.SLO{Target: 0.9},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := endpoints.Call(context.Background(), "quote", nil, nil, nil)
+		if c := models.CategoryOf(err); c != models.CategoryHTTP5xx || errors.Is(err, models.ErrCanceled) {
+			t.Fatalf("error %v has category %s", err, c)
+		}
+	}
+	_, err = client.Get(context.Background(), "/quote", nil, nil, metahttp.WithTimeout(time.Second))
+	if c := models.CategoryOf(err); c != models.CategoryHTTP5xx {
+		t.Errorf("WithTimeout error %v has category %s", err, c)
+	}
+
+	stats := client.Stats()
+	if stats.Errors[models.CategoryCanceled] != 0 || stats.Errors[models.CategoryHTTP5xx] != 4 {
+		t.Errorf("errors = %v", stats.Errors)
+	}
+	if slo := stats.Endpoints["quote"].SLO; slo == nil || slo.Good != 0 || slo.Bad != 3 {
+		t.Errorf("slo = %+v", slo)
+	}
+}
@@ -0,0 +1,104 @@
+package metahttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/jsonschema"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// explainTimeout ties cancellations and timeouts of a call started at
+// started to their cause: the caller's context, a per-call timeout of ctx,
+// the context of the call derived from caller, the client timeout or a
+// per-attempt timeout.
+func (c *client) explainTimeout(caller, ctx context.Context, started time.Time, err error) error {
+	if err == nil {
+		return nil
+	}
+	var timeoutErr *models.TimeoutError
+	if errors.As(err, &timeoutErr) || errors.Is(err, models.ErrCanceled) {
+		return err
+	}
+
+	switch caller.Err() {
+	case context.Canceled:
+		return fmt.Errorf("%w: %w", models.ErrCanceled, err)
+	case context.DeadlineExceeded:
+		return &models.TimeoutError{Source: models.TimeoutContext, Err: err}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return &models.TimeoutError{Source: models.TimeoutCall, Err: err}
+	}
+	if models.CategoryOf(categorize(err)) != models.CategoryTimeout {
+		return err
+	}
+	if timeout := c.HTTPClient.Timeout; timeout > 0 && time.Since(started) >= timeout {
+		return &models.TimeoutError{Source: models.TimeoutClient, Err: err}
+	}
+	return &models.TimeoutError{Source: models.TimeoutAttempt, Err: err}
+}
+
+// categorize wraps err in a *models.CategorizedError unless it already
+// carries a category, so that every error returned by a call answers
+// models.CategoryOf.
+func categorize(err error) error {
+	if err == nil {
+		return nil
+	}
+	var categorized interface{ ErrorCategory() models.ErrorCategory }
+	if errors.As(err, &categorized) {
+		return err
+	}
+	return &models.CategorizedError{Category: classify(err), Err: err}
+}
+
+func classify(err error) models.ErrorCategory {
+	var (
+		netErr      net.Error
+		dnsErr      *net.DNSError
+		opErr       *net.OpError
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		xmlErr      *xml.SyntaxError
+		schemaErr   *jsonschema.ValidationError
+		unknownCA   x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		certErr     x509.CertificateInvalidError
+		verifyErr   *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return models.CategoryCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.CategoryTimeout
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return models.CategoryTimeout
+		}
+		return models.CategoryDNS
+	case errors.As(err, &unknownCA), errors.As(err, &hostnameErr), errors.As(err, &certErr),
+		errors.As(err, &verifyErr), errors.As(err, &recordErr), errors.As(err, &alertErr):
+		return models.CategoryTLS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return models.CategoryTimeout
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.As(err, &xmlErr), errors.As(err, &schemaErr):
+		return models.CategoryDecode
+	case errors.As(err, &opErr), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return models.CategoryConnection
+	}
+	return models.CategoryUnknown
+}
@@ -0,0 +1,105 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestErrorCategories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			rw.WriteHeader(http.StatusNotFound)
+		case "/down":
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		case "/garbled":
+			rw.Header().Set("Content-Type", "application/json")
+			rw.Write([]byte(`{"id":`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient("", logger, 100*time.Millisecond)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tcs := []struct {
+		ctx      context.Context
+		url      string
+		category models.ErrorCategory
+	}{
+		{context.Background(), server.URL + "/missing", models.CategoryHTTP4xx},
+		{context.Background(), server.URL + "/down", models.CategoryHTTP5xx},
+		{context.Background(), server.URL + "/garbled", models.CategoryDecode},
+		{context.Background(), server.URL + "/slow", models.CategoryTimeout},
+		{canceled, server.URL + "/missing", models.CategoryCanceled},
+		{context.Background(), closed.URL, models.CategoryConnection},
+		{context.Background(), "http://metahttp.invalid/", models.CategoryDNS},
+		{context.Background(), "not a url", models.CategoryRequest},
+	}
+	for _, tc := range tcs {
+		var res map[string]interface{}
+		_, err := client.Get(tc.ctx, tc.url, nil, &res)
+		if got := models.CategoryOf(err); got != tc.category {
+			t.Errorf("%s: expected category %s, got %s (%v)", tc.url, tc.category, got, err)
+		}
+	}
+}
+
+func TestTimeoutSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	short := metahttp.NewClient(server.URL, logger, 50*time.Millisecond)
+	_, err := short.Get(context.Background(), "/", nil, nil)
+	var timeoutErr *models.TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Source != models.TimeoutClient || !errors.Is(err, models.ErrDeadlineExceeded) {
+		t.Errorf("expected client timeout, got: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = short.Get(ctx, "/", nil, nil)
+	if !errors.As(err, &timeoutErr) || timeoutErr.Source != models.TimeoutContext || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context timeout, got: %v", err)
+	}
+
+	// A per-call timeout is not the caller's deadline.
+	_, err = metahttp.NewClient(server.URL, logger, 5*time.Second).Get(context.Background(), "/", nil, nil, metahttp.WithTimeout(50*time.Millisecond))
+	if !errors.As(err, &timeoutErr) || timeoutErr.Source != models.TimeoutCall || !errors.Is(err, models.ErrDeadlineExceeded) {
+		t.Errorf("expected call timeout, got: %v", err)
+	}
+
+	perAttempt := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithTimeouts(metahttp.Timeouts{ResponseHeader: 50 * time.Millisecond}))
+	_, err = perAttempt.Get(context.Background(), "/", nil, nil)
+	if !errors.As(err, &timeoutErr) || timeoutErr.Source != models.TimeoutAttempt {
+		t.Errorf("expected attempt timeout, got: %v", err)
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancelNow)
+	_, err = metahttp.NewClient(server.URL, logger, 5*time.Second).Get(canceled, "/", nil, nil)
+	if !errors.Is(err, models.ErrCanceled) || !errors.Is(err, context.Canceled) || errors.Is(err, models.ErrDeadlineExceeded) {
+		t.Errorf("expected cancellation, got: %v", err)
+	}
+}
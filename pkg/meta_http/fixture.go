@@ -0,0 +1,122 @@
+package metahttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// fixtureTransport serves responses from a directory instead of the network.
+// A request is looked up by method and path, ignoring host and query:
+//
+//	GET /users/42  ->  <dir>/GET/users/42.fixture.json
+//	                   <dir>/GET/users/42.<ext>
+//
+// A ".fixture.json" file describes the whole response:
+//
+//	{"status": 201, "headers": {"X-Request-Id": "abc"}, "body": {...}}
+//
+// where body is either any JSON value or a string sent verbatim. Any other
+// file is served with status 200, its contents as the body and a
+// Content-Type derived from the extension. The root path maps to "index".
+type fixtureTransport struct {
+	dir string
+}
+
+type fixture struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+func (f fixtureTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+
+	rel := strings.Trim(path.Clean("/"+r.URL.Path), "/")
+	if rel == "" {
+		rel = "index"
+	}
+	base := filepath.Join(f.dir, r.Method, filepath.FromSlash(rel))
+
+	if b, err := os.ReadFile(base + ".fixture.json"); err == nil {
+		return fixtureResponse(r, b)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	matches, err := filepath.Glob(escapeGlob(base) + ".*")
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s %s", models.ErrFixtureNotFound, r.Method, r.URL.Path)
+	}
+	b, err := os.ReadFile(matches[0])
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	if ct := mime.TypeByExtension(filepath.Ext(matches[0])); ct != "" {
+		header.Set("Content-Type", ct)
+	}
+	return newFixtureResponse(r, http.StatusOK, header, b), nil
+}
+
+func fixtureResponse(r *http.Request, b []byte) (*http.Response, error) {
+	fx := fixture{}
+	if err := json.Unmarshal(b, &fx); err != nil {
+		return nil, fmt.Errorf("invalid fixture for %s %s: %w", r.Method, r.URL.Path, err)
+	}
+	if fx.Status == 0 {
+		fx.Status = http.StatusOK
+	}
+
+	header := http.Header{}
+	for k, v := range fx.Headers {
+		header.Set(k, v)
+	}
+
+	body := []byte(fx.Body)
+	var text string
+	if len(body) > 0 && body[0] == '"' && json.Unmarshal(body, &text) == nil {
+		body = []byte(text)
+	} else if len(body) > 0 && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+	return newFixtureResponse(r, fx.Status, header, body), nil
+}
+
+func newFixtureResponse(r *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
+}
+
+func escapeGlob(p string) string {
+	replacer := strings.NewReplacer("*", "\\*", "?", "\\?", "[", "\\[", "\\", "\\\\")
+	if filepath.Separator == '\\' {
+		replacer = strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]")
+	}
+	return replacer.Replace(p)
+}
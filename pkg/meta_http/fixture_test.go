@@ -0,0 +1,45 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestFixtureMode(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "GET", "users"), 0o755)
+	os.MkdirAll(filepath.Join(dir, "POST"), 0o755)
+	os.WriteFile(filepath.Join(dir, "GET", "users", "42.json"), []byte(`{"name":"meta"}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "POST", "orders.fixture.json"), []byte(`{"status":409,"body":{"success":false,"error":{"code":1001,"message":"duplicate order"}}}`), 0o644)
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient("https://partner.invalid", logger, 10*time.Second, metahttp.WithFixtures(dir))
+
+	var user map[string]string
+	if _, err := metaHttpClient.Get(context.Background(), "/users/42?expand=true", map[string]string{}, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user["name"] != "meta" {
+		t.Errorf("unexpected user: %v", user)
+	}
+
+	var res map[string]any
+	resp, err := metaHttpClient.Post(context.Background(), "/orders", map[string]string{}, map[string]string{}, &res)
+	var httpErr *models.HttpClientErrorResponse
+	if !errors.As(err, &httpErr) || httpErr.Err.Code != 1001 || resp.StatusCode != 409 {
+		t.Errorf("unexpected fixture error: %v", err)
+	}
+
+	_, err = metaHttpClient.Get(context.Background(), "/missing", map[string]string{}, &res)
+	if !errors.Is(err, models.ErrFixtureNotFound) {
+		t.Errorf("expected missing fixture error, got: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package metahttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/utils"
+)
+
+func TestFeatureFlagsPropagation(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("x-feature-flags")
+		ctx := utils.FetchContextFromHeaders(r.Context(), r)
+		json.NewEncoder(rw).Encode(utils.FeatureFlagsFromContext(ctx))
+	}))
+	defer server.Close()
+
+	ctx := utils.ContextWithFeatureFlags(context.Background(), map[string]string{"checkout-v2": "on", "pricing": "b"})
+	ctx = utils.ContextWithFeatureFlags(ctx, map[string]string{"pricing": "c", "odd name": "x,y"})
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	var flags map[string]string
+	if _, err := client.Get(ctx, "/quote", nil, &flags); err != nil {
+		t.Fatal(err)
+	}
+	if want := "checkout-v2=on,odd+name=x%2Cy,pricing=c"; header != want {
+		t.Errorf("header = %q, want %q", header, want)
+	}
+	if len(flags) != 3 || flags["checkout-v2"] != "on" || flags["pricing"] != "c" || flags["odd name"] != "x,y" {
+		t.Errorf("downstream flags = %v", flags)
+	}
+
+	if flags := utils.FeatureFlagsFromContext(context.Background()); len(flags) != 0 {
+		t.Errorf("flags without header = %v", flags)
+	}
+}
@@ -0,0 +1,147 @@
+package metahttp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+	"github.com/onmetahq/meta-http/pkg/utils"
+)
+
+// ErrGRPCFallback marks the errors of a GRPCConn after which Endpoints.Call
+// makes the call over HTTP instead, e.g. for the Unavailable and
+// Unimplemented codes while a service is being migrated.
+var ErrGRPCFallback = errors.New("grpc call falls back to http")
+
+// GRPCConn makes unary gRPC calls for Endpoints, so that the client does not
+// depend on a gRPC implementation. header holds the headers of the call
+// context, caller tag and WithHeaderValues included, for the connection to
+// send as metadata. A *grpc.ClientConn is adapted with GRPCConnFunc:
+//
+//	metahttp.GRPCConnFunc(func(ctx context.Context, method string, header http.Header, req, res interface{}) error {
+//		md := metadata.MD{}
+//		for k, v := range header {
+//			md.Append(k, v...)
+//		}
+//		err := conn.Invoke(metadata.NewOutgoingContext(ctx, md), method, req, res)
+//		if c := status.Code(err); c == codes.Unavailable || c == codes.Unimplemented {
+//			return fmt.Errorf("%w: %v", metahttp.ErrGRPCFallback, err)
+//		}
+//		return err
+//	})
+type GRPCConn interface {
+	Invoke(ctx context.Context, method string, header http.Header, req, res interface{}) error
+}
+
+// GRPCConnFunc is a function implementing GRPCConn.
+type GRPCConnFunc func(ctx context.Context, method string, header http.Header, req, res interface{}) error
+
+func (f GRPCConnFunc) Invoke(ctx context.Context, method string, header http.Header, req, res interface{}) error {
+	return f(ctx, method, header, req, res)
+}
+
+// callGRPC makes the call of ep over its gRPC connection. Only the timeout,
+// header, caller, logger and priority call options apply, and the call does
+// not go through the transports of the client: retries are left to the
+// service config of the connection, and rate limits, signers and the
+// response cache do not apply. The client, nil for other implementations of
+// Requests, still admits and accounts for the call as for HTTP ones: it
+// fails once closed, applies the allowed methods, load shedding and
+// concurrency limit, and counts the call in Stats, the endpoint's SLO
+// included, and its logs.
+func (ep *endpoint) callGRPC(ctx context.Context, c *client, body, res interface{}, opts []CallOption) (_ *models.ResponseData, err error) {
+	co := callOptions{}
+	for _, opt := range opts {
+		opt(&co)
+	}
+	if co.err != nil {
+		return nil, co.err
+	}
+	if c == nil {
+		if co.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, co.timeout)
+			defer cancel()
+		}
+		return ep.invokeGRPC(ctx, &co, body, res)
+	}
+
+	started := time.Now()
+	done := c.stats.begin()
+	ctx = context.WithValue(ctx, routeKey{}, ep.route)
+	caller := ctx
+	// Canceled once the error is classified, as in do.
+	cancel := context.CancelFunc(func() {})
+	defer func() {
+		err = categorize(c.explainTimeout(caller, ctx, started, err))
+		cancel()
+		if errors.Is(err, ErrGRPCFallback) {
+			// Accounted for by the HTTP call that follows.
+			done(nil, nil)
+		} else {
+			done(ep, err)
+		}
+		attrs := []any{
+			slog.String("grpc_method", ep.grpcMethod),
+			slog.String("route", ep.route),
+			slog.Int64("duration", time.Since(started).Milliseconds()),
+			slog.String(string(models.RequestID), contextString(ctx, models.RequestID)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err.Error()))
+		}
+		loggerFor(ctx, c.logger).DebugContext(ctx, "Call Ended", attrs...)
+	}()
+
+	if c.closed.Load() {
+		return nil, models.ErrClientClosed
+	}
+	if co.logger != nil {
+		ctx = ContextWithLogger(ctx, co.logger)
+	}
+	if co.caller != "" {
+		ctx = ContextWithCaller(ctx, co.caller)
+	}
+	if co.timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeout(ctx, co.timeout)
+		cancel = stop
+	}
+	c.stats.countCaller(CallerFromContext(ctx))
+	if co.priority != nil {
+		ctx = ContextWithPriority(ctx, *co.priority)
+	}
+	if err := c.admit(ctx, ep.method, ep.grpcMethod); err != nil {
+		return nil, err
+	}
+	if c.concurrency != nil {
+		if err := c.concurrency.acquire(ctx); err != nil {
+			return nil, err
+		}
+		defer c.concurrency.release()
+	}
+	return ep.invokeGRPC(ctx, &co, body, res)
+}
+
+// invokeGRPC sends the call with the headers of ctx and co as metadata.
+func (ep *endpoint) invokeGRPC(ctx context.Context, co *callOptions, body, res interface{}) (*models.ResponseData, error) {
+	header := http.Header{}
+	utils.EachHeaderFromContext(ctx, header.Set)
+	if caller := CallerFromContext(ctx); caller != "" {
+		header.Set(CallerHeader, caller)
+	}
+	if co.caller != "" {
+		header.Set(CallerHeader, co.caller)
+	}
+	for k, values := range co.header {
+		header[http.CanonicalHeaderKey(k)] = values
+	}
+
+	if err := ep.grpc.Invoke(ctx, ep.grpcMethod, header, body, res); err != nil {
+		return nil, err
+	}
+	return &models.ResponseData{Status: "200 OK", StatusCode: http.StatusOK}, nil
+}
@@ -0,0 +1,158 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+type merchant struct {
+	Name string `json:"name"`
+}
+
+func TestEndpointsOverGRPC(t *testing.T) {
+	var httpCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		httpCalls++
+		rw.Write([]byte(`{"name":"over http"}`))
+	}))
+	defer server.Close()
+
+	var methods []string
+	var gotHeader http.Header
+	var deadline bool
+	conn := metahttp.GRPCConnFunc(func(ctx context.Context, method string, header http.Header, req, res interface{}) error {
+		methods = append(methods, method)
+		gotHeader = header
+		_, deadline = ctx.Deadline()
+		if method == "/reports.v1.Reports/Get" {
+			return fmt.Errorf("%w: code = Unimplemented", metahttp.ErrGRPCFallback)
+		}
+		if method == "/merchants.v1.Merchants/Delete" {
+			return errors.New("code = PermissionDenied")
+		}
+		res.(*merchant).Name = "over grpc"
+		return nil
+	})
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	endpoints, err := metahttp.NewEndpoints(client,
+		metahttp.Endpoint{
+			Name: "getMerchant", Method: http.MethodGet, Path: "/merchants/{id}", Timeout: time.Second,
+			GRPC: conn, GRPCMethod: "/merchants.v1.Merchants/Get",
+		},
+		metahttp.Endpoint{
+			Name: "deleteMerchant", Method: http.MethodDelete, Path: "/merchants/{id}",
+			GRPC: conn, GRPCMethod: "/merchants.v1.Merchants/Delete",
+		},
+		metahttp.Endpoint{
+			Name: "getReport", Method: http.MethodGet, Path: "/reports/{id}",
+			GRPC: conn, GRPCMethod: "/reports.v1.Reports/Get",
+		},
+		metahttp.Endpoint{Name: "listMerchants", Method: http.MethodGet, Path: "/merchants"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), models.TenantID, "tenant-1")
+	var m merchant
+	data, err := endpoints.Call(ctx, "getMerchant", map[string]string{"id": "42"}, nil, &m,
+		metahttp.WithHeaderValues(http.Header{"X-Trace": {"abc"}}))
+	if err != nil || data.StatusCode != http.StatusOK || m.Name != "over grpc" {
+		t.Fatalf("grpc call: %+v %v %+v", data, err, m)
+	}
+	if gotHeader.Get(string(models.TenantID)) != "tenant-1" || gotHeader.Get("X-Trace") != "abc" {
+		t.Errorf("metadata = %v", gotHeader)
+	}
+	if !deadline {
+		t.Error("endpoint timeout not applied to the grpc call")
+	}
+
+	if _, err := endpoints.Call(ctx, "deleteMerchant", map[string]string{"id": "42"}, nil, nil); err == nil || httpCalls != 0 {
+		t.Errorf("grpc error: %v, http calls %d", err, httpCalls)
+	}
+
+	m = merchant{}
+	if _, err := endpoints.Call(ctx, "getReport", map[string]string{"id": "7"}, nil, &m); err != nil || m.Name != "over http" || httpCalls != 1 {
+		t.Errorf("fallback: %v %+v, http calls %d", err, m, httpCalls)
+	}
+
+	m = merchant{}
+	if _, err := endpoints.Call(ctx, "listMerchants", nil, nil, &m); err != nil || m.Name != "over http" {
+		t.Errorf("http endpoint: %v %+v", err, m)
+	}
+	if want := []string{"/merchants.v1.Merchants/Get", "/merchants.v1.Merchants/Delete", "/reports.v1.Reports/Get"}; fmt.Sprint(methods) != fmt.Sprint(want) {
+		t.Errorf("grpc methods = %v, want %v", methods, want)
+	}
+
+	_, err = metahttp.NewEndpoints(client, metahttp.Endpoint{Name: "bad", Method: http.MethodGet, Path: "/x", GRPC: conn, GRPCMethod: "Get"})
+	if !errors.Is(err, models.ErrInvalidConfig) {
+		t.Errorf("relative grpc method: %v", err)
+	}
+}
+
+func TestEndpointsOverGRPCAdmission(t *testing.T) {
+	var calls atomic.Int32
+	conn := metahttp.GRPCConnFunc(func(ctx context.Context, method string, header http.Header, req, res interface{}) error {
+		calls.Add(1)
+		if method == "/merchants.v1.Merchants/Delete" {
+			return errors.New("code = PermissionDenied")
+		}
+		return nil
+	})
+	var shed atomic.Bool
+	client := metahttp.NewClient("http://127.0.0.1:1", nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithAllowedMethods(http.MethodGet),
+		metahttp.WithLoadShedding(func(metahttp.ShedRequest) bool { return shed.Load() }))
+	endpoints, err := metahttp.NewEndpoints(client,
+		metahttp.Endpoint{
+			Name: "getMerchant", Method: http.MethodGet, Path: "/merchants/{id}",
+			GRPC: conn, GRPCMethod: "/merchants.v1.Merchants/Get", SLO: &metahttp.SLO{Target: 0.9},
+		},
+		metahttp.Endpoint{
+			Name: "deleteMerchant", Method: http.MethodDelete, Path: "/merchants/{id}",
+			GRPC: conn, GRPCMethod: "/merchants.v1.Merchants/Delete",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	params := map[string]string{"id": "42"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := endpoints.Call(ctx, "getMerchant", params, nil, &merchant{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := endpoints.Call(ctx, "deleteMerchant", params, nil, nil); !errors.Is(err, models.ErrMethodNotAllowed) {
+		t.Errorf("disallowed method: %v", err)
+	}
+	shed.Store(true)
+	if _, err := endpoints.Call(ctx, "getMerchant", params, nil, &merchant{}); !errors.Is(err, models.ErrLoadShed) {
+		t.Errorf("shed call: %v", err)
+	}
+	shed.Store(false)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 grpc calls, got %d", n)
+	}
+
+	stats := client.Stats().Endpoints["getMerchant"]
+	if stats.Calls != 3 || stats.Errors != 1 || stats.SLO == nil || stats.SLO.Good+stats.SLO.Bad != 3 {
+		t.Errorf("endpoint stats = %+v, slo %+v", stats, stats.SLO)
+	}
+
+	client.Close(ctx)
+	if _, err := endpoints.Call(ctx, "getMerchant", params, nil, &merchant{}); !errors.Is(err, models.ErrClientClosed) {
+		t.Errorf("closed client: %v", err)
+	}
+}
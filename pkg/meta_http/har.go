@@ -0,0 +1,369 @@
+package metahttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/masking"
+)
+
+const (
+	harRedacted        = "[REDACTED]"
+	defaultHARBodySize = 1 << 20
+)
+
+var defaultHARRedactedHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	"X-Api-Key", "Apikey", "Signature",
+}
+
+var defaultHARRedactedQueryParams = []string{
+	"access_token", "api_key", "apikey", "client_secret", "code", "key",
+	"password", "secret", "sig", "signature", "token",
+}
+
+// HARRecorder captures every request/response exchanged by a client in HTTP
+// Archive 1.2 format. Each retry attempt is recorded as its own entry, once
+// its response body is read or closed. Credentials in headers and query
+// parameters are replaced by "[REDACTED]"; bodies are kept up to
+// MaxBodySize bytes, the rest of a response streaming through unbuffered.
+// With a masker, larger bodies are left out: cut JSON cannot be masked field
+// by field.
+type HARRecorder struct {
+	MaxBodySize int
+
+	mu          sync.Mutex
+	entries     []harEntry
+	headers     map[string]bool
+	queryParams map[string]bool
+}
+
+func NewHARRecorder() *HARRecorder {
+	rec := &HARRecorder{
+		MaxBodySize: defaultHARBodySize,
+		headers:     map[string]bool{},
+		queryParams: map[string]bool{},
+	}
+	rec.RedactHeaders(defaultHARRedactedHeaders...)
+	rec.RedactQueryParams(defaultHARRedactedQueryParams...)
+	return rec
+}
+
+// RedactHeaders adds request and response headers whose values are scrubbed.
+func (rec *HARRecorder) RedactHeaders(names ...string) *HARRecorder {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, n := range names {
+		rec.headers[http.CanonicalHeaderKey(n)] = true
+	}
+	return rec
+}
+
+// RedactQueryParams adds query parameters whose values are scrubbed.
+func (rec *HARRecorder) RedactQueryParams(names ...string) *HARRecorder {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, n := range names {
+		rec.queryParams[strings.ToLower(n)] = true
+	}
+	return rec
+}
+
+// Reset drops every recorded entry.
+func (rec *HARRecorder) Reset() {
+	rec.mu.Lock()
+	rec.entries = nil
+	rec.mu.Unlock()
+}
+
+// Len returns the number of recorded entries.
+func (rec *HARRecorder) Len() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.entries)
+}
+
+// WriteTo writes the archive as JSON.
+func (rec *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	rec.mu.Lock()
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "meta-http", Version: "1"},
+		Entries: append([]harEntry{}, rec.entries...),
+	}}
+	rec.mu.Unlock()
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// WriteFile writes the archive to path, e.g. for attaching to a ticket.
+func (rec *HARRecorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := rec.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type harRoundTripper struct {
+	next     http.RoundTripper
+	recorder *HARRecorder
+	masker   *masking.Masker
+}
+
+func (h harRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := h.recorder
+	started := time.Now()
+
+	var reqBody []byte
+	reqTruncated := false
+	if r.GetBody != nil {
+		if rc, err := r.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(io.LimitReader(rc, int64(rec.MaxBodySize)+1))
+			rc.Close()
+			reqTruncated = len(reqBody) > rec.MaxBodySize
+		}
+	}
+
+	res, err := h.next.RoundTrip(r)
+	waited := time.Since(started)
+
+	entry := harEntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Request:         rec.harRequest(r, h.shown(reqBody, reqTruncated)),
+		Cache:           struct{}{},
+	}
+
+	if err != nil {
+		entry.Response = harResponse{
+			StatusText:  err.Error(),
+			Headers:     []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		entry.Time = ms(waited)
+		entry.Timings = harTimings{Send: 0, Wait: ms(waited), Receive: 0}
+		rec.append(entry)
+		return res, err
+	}
+
+	entry.Response = harResponse{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: res.Proto,
+		Headers:     rec.harHeaders(res.Header),
+		Cookies:     []harNameValue{},
+		Content:     harContent{MimeType: res.Header.Get("Content-Type")},
+		RedirectURL: res.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+	res.Body = &harBody{ReadCloser: res.Body, limit: rec.MaxBodySize, finish: func(b *harBody) {
+		received := time.Since(started) - waited
+		entry.Response.Content.Size = b.size
+		entry.Response.Content.Text = string(h.shown(b.kept.Bytes(), !b.eof || b.size > rec.MaxBodySize))
+		entry.Response.BodySize = b.size
+		entry.Time = ms(waited + received)
+		entry.Timings = harTimings{Send: 0, Wait: ms(waited), Receive: ms(received)}
+		rec.append(entry)
+	}}
+	return res, nil
+}
+
+// shown masks body for the archive. A body cut at MaxBodySize is kept only
+// when there is no masker, as its JSON would be masked as mere text.
+func (h harRoundTripper) shown(body []byte, truncated bool) []byte {
+	if !truncated {
+		return h.masker.Mask(body)
+	}
+	if h.masker != nil {
+		return nil
+	}
+	return body[:min(len(body), h.recorder.MaxBodySize)]
+}
+
+// harBody keeps the first limit bytes of a response body as it is read and
+// records its entry once it is read to the end or closed.
+type harBody struct {
+	io.ReadCloser
+	limit  int
+	kept   bytes.Buffer
+	size   int
+	eof    bool
+	once   sync.Once
+	finish func(*harBody)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	if keep := min(n, b.limit+1-b.kept.Len()); keep > 0 {
+		b.kept.Write(p[:keep])
+	}
+	if err != nil {
+		b.eof = err == io.EOF
+		b.once.Do(func() { b.finish(b) })
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.finish(b) })
+	return err
+}
+
+func (rec *HARRecorder) append(entry harEntry) {
+	rec.mu.Lock()
+	rec.entries = append(rec.entries, entry)
+	rec.mu.Unlock()
+}
+
+func (rec *HARRecorder) harRequest(r *http.Request, body []byte) harRequest {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	u := *r.URL
+	q := u.Query()
+	queryString := []harNameValue{}
+	for k, values := range q {
+		for i := range values {
+			if rec.queryParams[strings.ToLower(k)] {
+				values[i] = harRedacted
+			}
+			queryString = append(queryString, harNameValue{Name: k, Value: values[i]})
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	req := harRequest{
+		Method:      r.Method,
+		URL:         u.Redacted(),
+		HTTPVersion: r.Proto,
+		Headers:     rec.harHeadersLocked(r.Header),
+		QueryString: queryString,
+		Cookies:     []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if body != nil {
+		req.PostData = &harPostData{MimeType: r.Header.Get("Content-Type"), Text: string(body)}
+	}
+	return req
+}
+
+func (rec *HARRecorder) harHeaders(h http.Header) []harNameValue {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.harHeadersLocked(h)
+}
+
+func (rec *HARRecorder) harHeadersLocked(h http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for k, values := range h {
+		for _, v := range values {
+			if rec.headers[http.CanonicalHeaderKey(k)] {
+				v = harRedacted
+			}
+			headers = append(headers, harNameValue{Name: k, Value: v})
+		}
+	}
+	return headers
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type errReader struct {
+	err error
+}
+
+func (e errReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
@@ -0,0 +1,133 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/masking"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestHARRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"Goodbye":"World"}`))
+	}))
+	defer server.Close()
+
+	rec := metahttp.NewHARRecorder().RedactQueryParams("token")
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second, metahttp.WithHARRecorder(rec))
+
+	var res map[string]string
+	headers := map[string]string{"Authorization": "Bearer secret"}
+	if _, err := metaHttpClient.Post(context.Background(), "/test?token=secret&page=1", headers, map[string]string{"Hello": "world"}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res["Goodbye"] != "World" {
+		t.Error("Response body is not as expected")
+	}
+
+	var buf bytes.Buffer
+	if _, err := rec.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Error("archive leaks credentials")
+	}
+
+	var doc struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					Method   string
+					PostData struct{ Text string }
+				}
+				Response struct {
+					Status  int
+					Content struct{ Text string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(doc.Log.Entries))
+	}
+	entry := doc.Log.Entries[0]
+	if entry.Request.Method != http.MethodPost || entry.Response.Status != http.StatusOK {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Request.PostData.Text != `{"Hello":"world"}` || entry.Response.Content.Text != `{"Goodbye":"World"}` {
+		t.Errorf("unexpected bodies: %+v", entry)
+	}
+}
+
+func TestHARRecorderBoundsMaskedBodies(t *testing.T) {
+	large := `{"password":"hunter2","items":"` + strings.Repeat("x", 200) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		if req.URL.Path == "/large" {
+			rw.Write([]byte(large))
+			return
+		}
+		rw.Write([]byte(`{"password":"hunter2"}`))
+	}))
+	defer server.Close()
+
+	rec := metahttp.NewHARRecorder()
+	rec.MaxBodySize = 100
+	client := metahttp.NewClient(server.URL, nil, 10*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithHARRecorder(rec), metahttp.WithMasking(masking.New().Fields("password")))
+
+	var res map[string]string
+	if _, err := client.Get(context.Background(), "/large?access_token=secret", nil, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res["password"] != "hunter2" {
+		t.Errorf("the caller got %v", res)
+	}
+	if _, err := client.Get(context.Background(), "/small", nil, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := rec.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "hunter2") || strings.Contains(buf.String(), "secret") {
+		t.Errorf("archive leaks credentials:\n%s", buf.String())
+	}
+	var doc struct {
+		Log struct {
+			Entries []struct {
+				Response struct {
+					BodySize int
+					Content  struct{ Text string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Log.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(doc.Log.Entries))
+	}
+	if got := doc.Log.Entries[0].Response; got.BodySize != len(large) || got.Content.Text != "" {
+		t.Errorf("large body recorded as %+v", got)
+	}
+	if got := doc.Log.Entries[1].Response.Content.Text; !strings.Contains(got, "password") {
+		t.Errorf("small body recorded as %q", got)
+	}
+}
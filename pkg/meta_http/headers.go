@@ -0,0 +1,66 @@
+package metahttp
+
+import (
+	"net/http"
+)
+
+// headerSet holds canonical header names.
+type headerSet map[string]bool
+
+func newHeaderSet(names ...string) headerSet {
+	set := headerSet{}
+	for _, n := range names {
+		set[http.CanonicalHeaderKey(n)] = true
+	}
+	return set
+}
+
+// filter returns the headers of h present in the set.
+func (s headerSet) filter(h http.Header) http.Header {
+	filtered := http.Header{}
+	for k, values := range h {
+		if s[http.CanonicalHeaderKey(k)] {
+			filtered[k] = values
+		}
+	}
+	return filtered
+}
+
+// scrub returns a copy of h with the values of headers in the set replaced
+// by "[REDACTED]", for logging.
+func (s headerSet) scrub(h http.Header) http.Header {
+	scrubbed := make(http.Header, len(h))
+	for k, values := range h {
+		if s[http.CanonicalHeaderKey(k)] {
+			values = []string{harRedacted}
+		}
+		scrubbed[k] = values
+	}
+	return scrubbed
+}
+
+// headerTemplate is the prepared set of headers every request starts from:
+// the JSON Content-Type and Accept, overridden by the client's default
+// headers. It is built once per SetDefaultHeaders and never modified after.
+type headerTemplate http.Header
+
+func newHeaderTemplate(defaults map[string]string) *headerTemplate {
+	h := http.Header{
+		"Content-Type": {"application/json; charset=utf-8"},
+		"Accept":       {"application/json; charset=utf-8"},
+	}
+	for k, v := range defaults {
+		h.Set(k, v)
+	}
+	t := headerTemplate(h)
+	return &t
+}
+
+// apply copies the template into h, replacing the headers of the same name.
+// Each value slice is capped at its length so that an Add on the request
+// copies it rather than writing to the template.
+func (t *headerTemplate) apply(h http.Header) {
+	for k, values := range *t {
+		h[k] = values[:len(values):len(values)]
+	}
+}
@@ -0,0 +1,80 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestResponseHeaderPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Set-Cookie", "session=abc")
+		rw.Header().Set("X-Internal-Debug", "node-7")
+		rw.Header().Set("X-Request-Id", "req-1")
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second,
+		metahttp.WithResponseHeaderAllowlist("X-Request-Id"),
+		metahttp.WithHeaderLogging("X-Internal-Debug"),
+	)
+
+	res, err := client.Get(context.Background(), "/", map[string]string{"Authorization": "Bearer secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Header) != 1 || res.Header.Get("X-Request-Id") != "req-1" {
+		t.Errorf("unexpected response headers: %v", res.Header)
+	}
+
+	out := logs.String()
+	if !strings.Contains(out, "response_headers") || !strings.Contains(out, "req-1") {
+		t.Errorf("headers should be logged: %s", out)
+	}
+	for _, secret := range []string{"session=abc", "node-7", "Bearer secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q should be scrubbed from logs", secret)
+		}
+	}
+}
+
+func TestDefaultHeaderPrecedence(t *testing.T) {
+	var got []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	client.SetDefaultHeaders(map[string]string{"accept": "text/csv", "X-Tenant": "t1"})
+
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "/export", map[string]string{"X-Tenant": "t2"}, nil,
+		metahttp.WithHeaderValues(http.Header{"Accept": {"text/csv", "text/plain"}})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(ctx, "/export", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := got[0].Values("Accept"); len(v) != 2 || got[0].Get("X-Tenant") != "t2" {
+		t.Errorf("per-call headers not applied: %v", got[0])
+	}
+	if v := got[1].Values("Accept"); len(v) != 1 || v[0] != "text/csv" || got[1].Get("X-Tenant") != "t1" {
+		t.Errorf("defaults not restored on the next call: %v", got[1])
+	}
+	if got[1].Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", got[1].Get("Content-Type"))
+	}
+}
@@ -0,0 +1,103 @@
+package metahttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// DefaultHealthTimeout bounds a health check unless WithHealthTimeout says
+// otherwise.
+const DefaultHealthTimeout = 2 * time.Second
+
+// HealthResult is the outcome of a health check.
+type HealthResult struct {
+	Healthy    bool
+	StatusCode int
+	Latency    time.Duration
+	CheckedAt  time.Time
+	// Err is set when no response was received.
+	Err error
+}
+
+type healthOptions struct {
+	method  string
+	timeout time.Duration
+	healthy func(status int) bool
+}
+
+type HealthOption func(*healthOptions)
+
+// WithHealthMethod probes with method, typically http.MethodHead, instead
+// of GET.
+func WithHealthMethod(method string) HealthOption {
+	return func(o *healthOptions) {
+		o.method = method
+	}
+}
+
+// WithHealthTimeout bounds the probe, DefaultHealthTimeout by default.
+func WithHealthTimeout(timeout time.Duration) HealthOption {
+	return func(o *healthOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithHealthyStatus decides which statuses count as healthy, 2xx by default.
+func WithHealthyStatus(healthy func(status int) bool) HealthOption {
+	return func(o *healthOptions) {
+		o.healthy = healthy
+	}
+}
+
+type noRetryKey struct{}
+
+// Healthy probes path once, without retries nor cache, and discards the
+// response body. It never fails: an unreachable upstream is reported through
+// the result, and so is a probe the client does not make, being closed, not
+// allowed the method or shedding load.
+func (c *client) Healthy(ctx context.Context, path string, opts ...HealthOption) HealthResult {
+	o := healthOptions{
+		method:  http.MethodGet,
+		timeout: DefaultHealthTimeout,
+		healthy: func(status int) bool { return status >= 200 && status < 300 },
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	result := HealthResult{CheckedAt: time.Now()}
+	if c.closed.Load() {
+		result.Err = models.ErrClientClosed
+		return result
+	}
+	if err := c.admit(ctx, o.method, path); err != nil {
+		result.Err = err
+		return result
+	}
+	caller := ctx
+	ctx = context.WithValue(ctx, noCacheKey{}, true)
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, noRetryKey{}, true), o.timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, o.method, path, nil, nil, &callOptions{})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	res, err := c.HTTPClient.Do(req)
+	result.Latency = time.Since(result.CheckedAt)
+	if err != nil {
+		result.Err = categorize(c.explainTimeout(caller, ctx, result.CheckedAt, err))
+		return result
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	result.StatusCode = res.StatusCode
+	result.Healthy = o.healthy(res.StatusCode)
+	return result
+}
@@ -0,0 +1,94 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestHealthy(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/ready":
+			rw.WriteHeader(http.StatusOK)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClientWithRetry(server.URL, logger, 5*time.Second, models.Retry{
+		MaxRetries:        3,
+		DelayBetweenRetry: time.Millisecond,
+		Validator:         func(status int) bool { return status < 500 },
+	})
+
+	if res := client.Healthy(context.Background(), "/ready", metahttp.WithHealthMethod(http.MethodHead)); !res.Healthy || res.StatusCode != http.StatusOK || res.Err != nil {
+		t.Errorf("expected healthy, got %+v", res)
+	}
+
+	calls.Store(0)
+	if res := client.Healthy(context.Background(), "/down"); res.Healthy || res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected unhealthy, got %+v", res)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("health checks must not be retried, got %d calls", n)
+	}
+
+	degraded := func(status int) bool { return status < 500 || status == http.StatusServiceUnavailable }
+	if res := client.Healthy(context.Background(), "/down", metahttp.WithHealthyStatus(degraded)); !res.Healthy {
+		t.Errorf("expected custom criteria to pass, got %+v", res)
+	}
+
+	res := client.Healthy(context.Background(), "/slow", metahttp.WithHealthTimeout(20*time.Millisecond))
+	if res.Healthy || models.CategoryOf(res.Err) != models.CategoryTimeout {
+		t.Errorf("expected timeout, got %+v", res)
+	}
+}
+
+func TestHealthyAdmission(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	shedding := atomic.Bool{}
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithAllowedMethods(http.MethodGet),
+		metahttp.WithLoadShedding(func(metahttp.ShedRequest) bool { return shedding.Load() }))
+	ctx := context.Background()
+
+	if res := client.Healthy(ctx, "/ready"); !res.Healthy {
+		t.Errorf("expected healthy, got %+v", res)
+	}
+	if res := client.Healthy(ctx, "/ready", metahttp.WithHealthMethod(http.MethodHead)); res.Healthy || !errors.Is(res.Err, models.ErrMethodNotAllowed) {
+		t.Errorf("expected the method to be refused, got %+v", res)
+	}
+	shedding.Store(true)
+	if res := client.Healthy(ctx, "/ready"); res.Healthy || !errors.Is(res.Err, models.ErrLoadShed) {
+		t.Errorf("expected the probe to be shed, got %+v", res)
+	}
+	shedding.Store(false)
+	client.Close(ctx)
+	if res := client.Healthy(ctx, "/ready"); res.Healthy || !errors.Is(res.Err, models.ErrClientClosed) {
+		t.Errorf("expected the closed client to refuse the probe, got %+v", res)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected only the admitted probe to be sent, got %d calls", n)
+	}
+}
@@ -0,0 +1,39 @@
+package metahttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestInformationalResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Link", "</app.css>; rel=preload; as=style")
+		rw.WriteHeader(http.StatusEarlyHints)
+		rw.Header().Set("Link", "</app.js>; rel=preload; as=script")
+		rw.WriteHeader(http.StatusEarlyHints)
+		rw.Header().Del("Link")
+		rw.Write([]byte(`{"name":"meta"}`))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	var hints []string
+	var res merchant
+	data, err := client.Get(context.Background(), "/page", nil, &res, metahttp.WithInformational(func(status int, header http.Header) {
+		if status != http.StatusEarlyHints {
+			t.Errorf("status = %d", status)
+		}
+		hints = append(hints, header.Get("Link"))
+	}))
+	if err != nil || data.StatusCode != http.StatusOK || res.Name != "meta" {
+		t.Fatalf("call: %+v %v %+v", data, err, res)
+	}
+	if len(hints) != 2 || hints[0] != "</app.css>; rel=preload; as=style" || hints[1] != "</app.js>; rel=preload; as=script" {
+		t.Errorf("hints = %q", hints)
+	}
+}
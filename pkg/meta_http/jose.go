@@ -0,0 +1,292 @@
+package metahttp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// JOSEMediaType is the media type of compact JWE and JWS bodies.
+const JOSEMediaType = "application/jose"
+
+// ErrJWSVerification is returned when a response signature does not verify.
+var ErrJWSVerification = errors.New("jws verification failed")
+
+// JOSEKeyProvider supplies the keys of a JOSE codec. It is asked on every
+// body, so that keys can rotate.
+type JOSEKeyProvider interface {
+	// EncryptionKey returns the key request payloads are encrypted to, with
+	// its key id: an *rsa.PublicKey for RSA-OAEP-256, a 16 or 32 byte []byte
+	// for "dir".
+	EncryptionKey() (kid string, key interface{}, err error)
+	// VerificationKey returns the key verifying response signatures made
+	// with kid and alg: an *rsa.PublicKey for RS256 and PS256, an
+	// *ecdsa.PublicKey for ES256, an ed25519.PublicKey for EdDSA and a
+	// []byte for HS256.
+	VerificationKey(kid, alg string) (interface{}, error)
+}
+
+// StaticJOSEKeys is a JOSEKeyProvider of fixed keys.
+type StaticJOSEKeys struct {
+	EncryptionKeyID string
+	Encryption      interface{}
+	// Verification is keyed by key id. The "" entry verifies signatures
+	// without one.
+	Verification map[string]interface{}
+}
+
+func (k StaticJOSEKeys) EncryptionKey() (string, interface{}, error) {
+	if k.Encryption == nil {
+		return "", nil, errors.New("jose: no encryption key")
+	}
+	return k.EncryptionKeyID, k.Encryption, nil
+}
+
+func (k StaticJOSEKeys) VerificationKey(kid, _ string) (interface{}, error) {
+	key, ok := k.Verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("jose: no verification key %q", kid)
+	}
+	return key, nil
+}
+
+// JOSE configures the codec of NewJOSECodec.
+type JOSE struct {
+	Keys JOSEKeyProvider
+	// KeyAlgorithm wraps the content key of request JWEs, "RSA-OAEP-256"
+	// by default, or "dir" to encrypt with a shared key directly.
+	KeyAlgorithm string
+	// ContentEncryption is "A256GCM" by default, or "A128GCM".
+	ContentEncryption string
+	// SignatureAlgorithms are the response JWS algorithms accepted, by
+	// default RS256, PS256, ES256 and EdDSA. HS256 must be listed to be
+	// accepted; "none" never is.
+	SignatureAlgorithms []string
+}
+
+// NewJOSECodec returns a codec encrypting request payloads as compact JWE
+// (RFC 7516) around their JSON encoding, and verifying compact JWS (RFC
+// 7515) responses before decoding their JSON payload. Register it for
+// JOSEMediaType and send calls with it:
+//
+//	client := metahttp.NewClient(url, log, timeout, metahttp.WithCodec(metahttp.JOSEMediaType, metahttp.NewJOSECodec(cfg)))
+//	client.Post(ctx, "/payments", nil, payment, &res,
+//		metahttp.WithContentType(metahttp.JOSEMediaType), metahttp.WithAccept(metahttp.JOSEMediaType))
+//
+// Responses to calls accepting JOSEMediaType, WithAccept or through the
+// default headers, must then be signed JWS whatever their Content-Type.
+func NewJOSECodec(cfg JOSE) Codec {
+	if cfg.KeyAlgorithm == "" {
+		cfg.KeyAlgorithm = "RSA-OAEP-256"
+	}
+	if cfg.ContentEncryption == "" {
+		cfg.ContentEncryption = "A256GCM"
+	}
+	if cfg.SignatureAlgorithms == nil {
+		cfg.SignatureAlgorithms = []string{"RS256", "PS256", "ES256", "EdDSA"}
+	}
+	return joseCodec{cfg: cfg}
+}
+
+type joseCodec struct {
+	cfg JOSE
+}
+
+var joseBase64 = base64.RawURLEncoding
+
+func (c joseCodec) Encode(w io.Writer, v interface{}) error {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	kid, key, err := c.cfg.Keys.EncryptionKey()
+	if err != nil {
+		return err
+	}
+
+	var cekSize int
+	switch c.cfg.ContentEncryption {
+	case "A128GCM":
+		cekSize = 16
+	case "A256GCM":
+		cekSize = 32
+	default:
+		return fmt.Errorf("jose: unsupported content encryption %q", c.cfg.ContentEncryption)
+	}
+	var cek, encryptedKey []byte
+	switch c.cfg.KeyAlgorithm {
+	case "dir":
+		shared, ok := key.([]byte)
+		if !ok || len(shared) != cekSize {
+			return fmt.Errorf("jose: dir with %s needs a %d byte key", c.cfg.ContentEncryption, cekSize)
+		}
+		cek = shared
+	case "RSA-OAEP-256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("jose: RSA-OAEP-256 needs an *rsa.PublicKey, got %T", key)
+		}
+		cek = make([]byte, cekSize)
+		if _, err := rand.Read(cek); err != nil {
+			return err
+		}
+		if encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, cek, nil); err != nil {
+			return fmt.Errorf("jose: wrapping content key: %w", err)
+		}
+	default:
+		return fmt.Errorf("jose: unsupported key algorithm %q", c.cfg.KeyAlgorithm)
+	}
+
+	header := map[string]string{"alg": c.cfg.KeyAlgorithm, "enc": c.cfg.ContentEncryption, "cty": "application/json"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	protected := joseBase64.EncodeToString(rawHeader)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	_, err = io.WriteString(w, strings.Join([]string{
+		protected,
+		joseBase64.EncodeToString(encryptedKey),
+		joseBase64.EncodeToString(iv),
+		joseBase64.EncodeToString(ciphertext),
+		joseBase64.EncodeToString(tag),
+	}, "."))
+	return err
+}
+
+func (c joseCodec) Decode(r io.Reader, v interface{}) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(string(bytes.TrimSpace(body)), ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: not a compact JWS", ErrJWSVerification)
+	}
+	rawHeader, err := joseBase64.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("%w: header: %v", ErrJWSVerification, err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return fmt.Errorf("%w: header: %v", ErrJWSVerification, err)
+	}
+	if !slices.Contains(c.cfg.SignatureAlgorithms, header.Alg) || header.Alg == "none" {
+		return fmt.Errorf("%w: algorithm %q not accepted", ErrJWSVerification, header.Alg)
+	}
+	payload, err := joseBase64.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("%w: payload: %v", ErrJWSVerification, err)
+	}
+	sig, err := joseBase64.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: signature: %v", ErrJWSVerification, err)
+	}
+	key, err := c.cfg.Keys.VerificationKey(header.Kid, header.Alg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrJWSVerification, err)
+	}
+	if err := verifyJWS(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return err
+	}
+	return jsonCodec{}.Decode(bytes.NewReader(payload), v)
+}
+
+func verifyJWS(alg string, key interface{}, signingInput, sig []byte) error {
+	digest := sha256.Sum256(signingInput)
+	ok := false
+	switch alg {
+	case "RS256", "PS256":
+		pub, isRSA := key.(*rsa.PublicKey)
+		if !isRSA {
+			return fmt.Errorf("%w: %s needs an *rsa.PublicKey, got %T", ErrJWSVerification, alg, key)
+		}
+		if alg == "RS256" {
+			ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+		} else {
+			ok = rsa.VerifyPSS(pub, crypto.SHA256, digest[:], sig, nil) == nil
+		}
+	case "ES256":
+		pub, isEC := key.(*ecdsa.PublicKey)
+		if !isEC {
+			return fmt.Errorf("%w: ES256 needs an *ecdsa.PublicKey, got %T", ErrJWSVerification, key)
+		}
+		// JWS encodes the signature as R and S, 32 bytes each.
+		if len(sig) == 64 {
+			ok = ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+		}
+	case "EdDSA":
+		pub, isEd := key.(ed25519.PublicKey)
+		if !isEd {
+			return fmt.Errorf("%w: EdDSA needs an ed25519.PublicKey, got %T", ErrJWSVerification, key)
+		}
+		ok = ed25519.Verify(pub, signingInput, sig)
+	case "HS256":
+		secret, isSecret := key.([]byte)
+		if !isSecret {
+			return fmt.Errorf("%w: HS256 needs a []byte key, got %T", ErrJWSVerification, key)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signingInput)
+		ok = hmac.Equal(mac.Sum(nil), sig)
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrJWSVerification, alg)
+	}
+	if !ok {
+		return fmt.Errorf("%w: invalid signature", ErrJWSVerification)
+	}
+	return nil
+}
+
+// joseCodecFor returns the JOSE codec registered for one of the media types
+// of accept. Responses to calls asking for JOSE are decoded with it whatever
+// their Content-Type, so that an unsigned body served as JSON is rejected
+// rather than trusted.
+func joseCodecFor(codecs map[string]Codec, accept string) (Codec, bool) {
+	if accept == "" {
+		return nil, false
+	}
+	for _, mediaType := range strings.Split(accept, ",") {
+		codec, ok := codecFor(codecs, strings.TrimSpace(mediaType))
+		if _, isJOSE := codec.(joseCodec); ok && isJOSE {
+			return codec, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,156 @@
+package metahttp_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+var jwsB64 = base64.RawURLEncoding
+
+// decryptJWE opens a compact RSA-OAEP-256 or dir JWE the way a partner
+// would.
+func decryptJWE(t *testing.T, compact string, priv *rsa.PrivateKey, shared []byte) (map[string]string, []byte) {
+	t.Helper()
+	parts := strings.Split(compact, ".")
+	if len(parts) != 5 {
+		t.Fatalf("expected a compact JWE, got %q", compact)
+	}
+	var header map[string]string
+	raw, _ := jwsB64.DecodeString(parts[0])
+	json.Unmarshal(raw, &header)
+	cek := shared
+	if priv != nil {
+		encryptedKey, _ := jwsB64.DecodeString(parts[1])
+		var err error
+		if cek, err = rsa.DecryptOAEP(sha256.New(), nil, priv, encryptedKey, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	iv, _ := jwsB64.DecodeString(parts[2])
+	ciphertext, _ := jwsB64.DecodeString(parts[3])
+	tag, _ := jwsB64.DecodeString(parts[4])
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header, plaintext
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, payload []byte) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": kid})
+	input := jwsB64.EncodeToString(header) + "." + jwsB64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return input + "." + jwsB64.EncodeToString(sig)
+}
+
+func TestJOSECodec(t *testing.T) {
+	partnerKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	signingKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	var header map[string]string
+	var payload []byte
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		header, payload = decryptJWE(t, string(body), partnerKey, nil)
+		rw.Header().Set("Content-Type", metahttp.JOSEMediaType)
+		response := signES256(t, signingKey, "resp-1", []byte(`{"status":"accepted"}`))
+		if r.URL.Path == "/unsigned" {
+			rw.Header().Set("Content-Type", "application/json")
+			rw.Write([]byte(`{"status":"accepted"}`))
+			return
+		}
+		if r.URL.Path == "/tampered" {
+			parts := strings.Split(response, ".")
+			parts[1] = jwsB64.EncodeToString([]byte(`{"status":"rejected"}`))
+			response = strings.Join(parts, ".")
+		}
+		rw.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	codec := metahttp.NewJOSECodec(metahttp.JOSE{Keys: metahttp.StaticJOSEKeys{
+		EncryptionKeyID: "enc-1",
+		Encryption:      &partnerKey.PublicKey,
+		Verification:    map[string]interface{}{"resp-1": &signingKey.PublicKey},
+	}})
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(), metahttp.WithCodec(metahttp.JOSEMediaType, codec))
+	jose := []metahttp.CallOption{metahttp.WithContentType(metahttp.JOSEMediaType), metahttp.WithAccept(metahttp.JOSEMediaType)}
+
+	var res struct{ Status string }
+	if _, err := client.Post(context.Background(), "/payments", nil, map[string]int{"amount": 100}, &res, jose...); err != nil {
+		t.Fatal(err)
+	}
+	if header["alg"] != "RSA-OAEP-256" || header["enc"] != "A256GCM" || header["kid"] != "enc-1" || string(payload) != `{"amount":100}` {
+		t.Errorf("unexpected JWE %v, %s", header, payload)
+	}
+	if res.Status != "accepted" {
+		t.Errorf("expected the verified payload to be decoded, got %+v", res)
+	}
+
+	_, err := client.Post(context.Background(), "/tampered", nil, map[string]int{"amount": 100}, &res, jose...)
+	if err == nil || !strings.Contains(err.Error(), "jws verification failed: invalid signature") {
+		t.Errorf("expected a tampered response to be rejected, got %v", err)
+	}
+
+	res.Status = ""
+	_, err = client.Post(context.Background(), "/unsigned", nil, map[string]int{"amount": 100}, &res, jose...)
+	if err == nil || !strings.Contains(err.Error(), "not a compact JWS") || res.Status != "" {
+		t.Errorf("expected an unsigned JSON response to be rejected, got %v, %+v", err, res)
+	}
+}
+
+func TestJOSECodecDirectAndAlgorithms(t *testing.T) {
+	shared := make([]byte, 16)
+	rand.Read(shared)
+	codec := metahttp.NewJOSECodec(metahttp.JOSE{
+		Keys:                metahttp.StaticJOSEKeys{Encryption: shared, Verification: map[string]interface{}{"": []byte("secret")}},
+		KeyAlgorithm:        "dir",
+		ContentEncryption:   "A128GCM",
+		SignatureAlgorithms: []string{"ES256"},
+	})
+
+	var body strings.Builder
+	if err := codec.Encode(&body, map[string]string{"iban": "DE89"}); err != nil {
+		t.Fatal(err)
+	}
+	header, payload := decryptJWE(t, body.String(), nil, shared)
+	if header["alg"] != "dir" || header["enc"] != "A128GCM" || string(payload) != `{"iban":"DE89"}` {
+		t.Errorf("unexpected JWE %v, %s", header, payload)
+	}
+
+	// Neither "none" nor algorithms outside the configured ones are accepted.
+	for _, alg := range []string{"none", "HS256"} {
+		h, _ := json.Marshal(map[string]string{"alg": alg})
+		jws := jwsB64.EncodeToString(h) + "." + jwsB64.EncodeToString([]byte(`{}`)) + "."
+		var v map[string]interface{}
+		if err := codec.Decode(strings.NewReader(jws), &v); err == nil || !strings.Contains(err.Error(), "not accepted") {
+			t.Errorf("expected %s to be rejected, got %v", alg, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package metahttp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+type lazyClient struct {
+	once   sync.Once
+	build  func() Requests
+	client Requests
+	built  atomic.Bool
+}
+
+// Lazy returns a client that calls build on first use, once, however many
+// goroutines use it concurrently. Every method but Close and
+// CloseIdleConnections, SetDefaultHeaders and Stats included, counts as a
+// use.
+func Lazy(build func() Requests) Requests {
+	return &lazyClient{build: build}
+}
+
+func (l *lazyClient) get() Requests {
+	l.once.Do(func() {
+		l.client = l.build()
+		l.built.Store(true)
+	})
+	return l.client
+}
+
+func (l *lazyClient) SetDefaultHeaders(headers map[string]string) {
+	l.get().SetDefaultHeaders(headers)
+}
+
+func (l *lazyClient) Get(ctx context.Context, path string, headers map[string]string, v interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	return l.get().Get(ctx, path, headers, v, opts...)
+}
+
+func (l *lazyClient) Post(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	return l.get().Post(ctx, path, headers, v, res, opts...)
+}
+
+func (l *lazyClient) Put(ctx context.Context, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	return l.get().Put(ctx, path, headers, v, res, opts...)
+}
+
+func (l *lazyClient) Do(ctx context.Context, method string, path string, headers map[string]string, v interface{}, res interface{}, opts ...CallOption) (*models.ResponseData, error) {
+	return l.get().Do(ctx, method, path, headers, v, res, opts...)
+}
+
+func (l *lazyClient) GetConfig() RequestOptions {
+	return l.get().GetConfig()
+}
+
+func (l *lazyClient) Stats() Stats {
+	return l.get().Stats()
+}
+
+func (l *lazyClient) SnapshotAndReset() StatsWindow {
+	return l.get().SnapshotAndReset()
+}
+
+func (l *lazyClient) Healthy(ctx context.Context, path string, opts ...HealthOption) HealthResult {
+	return l.get().Healthy(ctx, path, opts...)
+}
+
+func (l *lazyClient) Schedule(ctx context.Context, at time.Time, req ScheduledRequest) (string, error) {
+	return l.get().Schedule(ctx, at, req)
+}
+
+func (l *lazyClient) WarmUp(ctx context.Context, n int) error {
+	return l.get().WarmUp(ctx, n)
+}
+
+func (l *lazyClient) DialWebSocket(ctx context.Context, path string, headers map[string]string) (*WebSocketConn, *models.ResponseData, error) {
+	return l.get().DialWebSocket(ctx, path, headers)
+}
+
+func (l *lazyClient) AsHTTPClient() *http.Client {
+	return l.get().AsHTTPClient()
+}
+
+// CloseIdleConnections is a no-op until the client is built.
+func (l *lazyClient) CloseIdleConnections() {
+	if l.built.Load() {
+		l.client.CloseIdleConnections()
+	}
+}
+
+// Close closes the client if it was built, building it otherwise would be
+// wasted work.
+func (l *lazyClient) Close(ctx context.Context) error {
+	if !l.built.Load() {
+		return nil
+	}
+	return l.client.Close(ctx)
+}
+
+// clientOf returns the client behind c, nil for other implementations of
+// Requests such as mocks.
+func clientOf(c Requests) *client {
+	switch c := c.(type) {
+	case *client:
+		return c
+	case *lazyClient:
+		return clientOf(c.get())
+	}
+	return nil
+}
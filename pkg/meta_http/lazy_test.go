@@ -0,0 +1,50 @@
+package metahttp_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestLazy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var built atomic.Int32
+	client := metahttp.Lazy(func() metahttp.Requests {
+		built.Add(1)
+		return metahttp.NewClient(server.URL, slog.New(slog.NewJSONHandler(os.Stderr, nil)), 5*time.Second)
+	})
+	if built.Load() != 0 {
+		t.Fatal("client built before first use")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := built.Load(); n != 1 {
+		t.Errorf("expected one build, got %d", n)
+	}
+	if s := client.Stats(); s.Requests != 8 {
+		t.Errorf("expected 8 requests on the built client, got %d", s.Requests)
+	}
+}
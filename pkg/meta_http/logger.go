@@ -0,0 +1,108 @@
+package metahttp
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sync/atomic"
+)
+
+// Logger is the logging interface used by clients. *slog.Logger implements
+// it directly; see the zaplog and logruslog packages for zap and logrus.
+// args follow slog conventions: alternating keys and values, or slog.Attr.
+type Logger interface {
+	DebugContext(ctx context.Context, msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	WarnContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+type noopLogger struct{}
+
+func (noopLogger) DebugContext(context.Context, string, ...any) {}
+func (noopLogger) InfoContext(context.Context, string, ...any)  {}
+func (noopLogger) WarnContext(context.Context, string, ...any)  {}
+func (noopLogger) ErrorContext(context.Context, string, ...any) {}
+
+// orDefault returns slog.Default() when log is nil, including a typed nil
+// such as a nil *slog.Logger left over from dependency wiring.
+func orDefault(log Logger) Logger {
+	if log == nil {
+		return slog.Default()
+	}
+	if v := reflect.ValueOf(log); v.Kind() == reflect.Ptr && v.IsNil() {
+		return slog.Default()
+	}
+	return log
+}
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a context whose calls log to log instead of the
+// client's logger, so request-scoped fields such as tenant or order ID reach
+// the round-trip logs.
+func ContextWithLogger(ctx context.Context, log Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, log)
+}
+
+// loggerFor returns the logger carried by ctx, or fallback. Clients created
+// WithoutLogging always use their no-op fallback.
+func loggerFor(ctx context.Context, fallback Logger) Logger {
+	if _, disabled := fallback.(noopLogger); disabled {
+		return fallback
+	}
+	if log, ok := ctx.Value(loggerKey{}).(Logger); ok && log != nil {
+		return withTraceIDs(orDefault(log))
+	}
+	return fallback
+}
+
+// withArgs returns a logger adding args to every entry.
+func withArgs(log Logger, args ...any) Logger {
+	if l, ok := log.(*slog.Logger); ok {
+		return l.With(args...)
+	}
+	return argsLogger{next: log, args: args}
+}
+
+type argsLogger struct {
+	next Logger
+	args []any
+}
+
+func (l argsLogger) with(args []any) []any {
+	return append(append(make([]any, 0, len(args)+len(l.args)), args...), l.args...)
+}
+
+func (l argsLogger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.next.DebugContext(ctx, msg, l.with(args)...)
+}
+
+func (l argsLogger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.next.InfoContext(ctx, msg, l.with(args)...)
+}
+
+func (l argsLogger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.next.WarnContext(ctx, msg, l.with(args)...)
+}
+
+func (l argsLogger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.next.ErrorContext(ctx, msg, l.with(args)...)
+}
+
+type attemptCounterKey struct{}
+
+// attemptCounter numbers the round trips of one logical request, retries
+// and redirects included.
+type attemptCounter struct {
+	n atomic.Int32
+}
+
+// nextAttempt returns the 1-based number of the round trip about to be made,
+// or 0 outside of a client call.
+func nextAttempt(ctx context.Context) int {
+	if counter, ok := ctx.Value(attemptCounterKey{}).(*attemptCounter); ok {
+		return int(counter.n.Add(1))
+	}
+	return 0
+}
@@ -0,0 +1,126 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestRequestScopedLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var clientLogs, scopedLogs bytes.Buffer
+	client := metahttp.NewClient(server.URL, slog.New(slog.NewJSONHandler(&clientLogs, &slog.HandlerOptions{Level: slog.LevelDebug})), 5*time.Second)
+	scoped := slog.New(slog.NewJSONHandler(&scopedLogs, &slog.HandlerOptions{Level: slog.LevelDebug})).With("order_id", "ord_1")
+
+	if _, err := client.Get(context.Background(), "/", nil, nil, metahttp.WithLogger(scoped)); err != nil {
+		t.Fatal(err)
+	}
+	ctx := metahttp.ContextWithLogger(context.Background(), scoped)
+	if _, err := client.Get(ctx, "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if clientLogs.Len() != 0 {
+		t.Errorf("client logger should not be used: %s", clientLogs.String())
+	}
+	if n := strings.Count(scopedLogs.String(), `"order_id":"ord_1"`); n != 6 {
+		t.Errorf("expected 6 scoped log lines, got %d: %s", n, scopedLogs.String())
+	}
+}
+
+func TestNilAndDisabledLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var nilLogger *slog.Logger
+	for _, client := range []metahttp.Requests{
+		metahttp.NewClient(server.URL, nil, 5*time.Second),
+		metahttp.NewClient(server.URL, nilLogger, 5*time.Second),
+	} {
+		if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var logs bytes.Buffer
+	scoped := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	quiet := metahttp.NewClient(server.URL, scoped, 5*time.Second, metahttp.WithoutLogging())
+	if _, err := quiet.Get(context.Background(), "/", nil, nil, metahttp.WithLogger(scoped)); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no logs, got: %s", logs.String())
+	}
+}
+
+func TestLogsNumberAttempts(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := metahttp.NewClientWithRetry(server.URL, log, 5*time.Second, models.Retry{
+		MaxRetries:        3,
+		DelayBetweenRetry: time.Millisecond,
+		Validator:         func(status int) bool { return status < 500 },
+	})
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var ended []map[string]any
+	var summary map[string]any
+	dec := json.NewDecoder(&logs)
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		switch line["msg"] {
+		case "Call Ended":
+			ended = append(ended, line)
+		case "Request Ended":
+			if summary != nil {
+				t.Errorf("expected a single summary line, got another: %v", line)
+			}
+			summary = line
+		}
+	}
+	if len(ended) != 3 {
+		t.Fatalf("expected 3 attempts to be logged, got %d", len(ended))
+	}
+	for i, line := range ended {
+		if line["attempt"] != float64(i+1) {
+			t.Errorf("attempt %d logged as %v", i+1, line["attempt"])
+		}
+	}
+	if summary == nil {
+		t.Fatal("missing summary line")
+	}
+	if summary["attempts"] != float64(3) || summary["status"] != float64(http.StatusNoContent) {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+}
@@ -0,0 +1,143 @@
+package metahttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// LongPollConfig describes a long-poll change feed.
+type LongPollConfig[T any] struct {
+	Path    string
+	Headers map[string]string
+	// CursorParam is the query parameter carrying the position in the feed,
+	// e.g. "since". It is omitted while the cursor is empty.
+	CursorParam   string
+	InitialCursor string
+	// Cursor extracts the cursor for the next request from a response.
+	// When nil the cursor never changes.
+	Cursor func(T) string
+	// TimeoutParam is the query parameter asking the server to hold the
+	// request open for Timeout, sent in whole seconds. The client timeout
+	// must be longer than Timeout.
+	TimeoutParam string
+	Timeout      time.Duration
+	// Backoff paces retries after failed requests and after empty responses
+	// returned early, DefaultBackoff when nil.
+	Backoff Backoff
+	// OnError is told about every failed request before backing off.
+	// Returning a non-nil error stops polling with that error.
+	OnError func(err error) error
+}
+
+// LongPoll issues GET requests against a long-poll endpoint back to back,
+// handing every response to handle and advancing the cursor, until ctx is
+// done or handle returns an error. Responses with status 204 mean the wait
+// elapsed without changes and are not handed to handle. Those returned before
+// Timeout elapsed, or without a Timeout, are followed by a pause from
+// Backoff, so that a server not holding requests open is not polled in a
+// tight loop.
+func LongPoll[T any](ctx context.Context, c Requests, cfg LongPollConfig[T], handle func(T) error) error {
+	backoff := cfg.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff()
+	}
+
+	cursor := cfg.InitialCursor
+	failures, empty := 0, 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		query := url.Values{}
+		if cfg.CursorParam != "" && cursor != "" {
+			query.Set(cfg.CursorParam, cursor)
+		}
+		if cfg.TimeoutParam != "" && cfg.Timeout > 0 {
+			query.Set(cfg.TimeoutParam, strconv.Itoa(int(cfg.Timeout/time.Second)))
+		}
+
+		var res T
+		started := time.Now()
+		resp, err := c.Get(ctx, cfg.Path, cfg.Headers, &res, WithQuery(query))
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+				return ctxErr
+			}
+			if cfg.OnError != nil {
+				if stop := cfg.OnError(err); stop != nil {
+					return stop
+				}
+			}
+			failures++
+			if err := sleepContext(ctx, backoff.Next(failures)); err != nil {
+				return err
+			}
+			continue
+		}
+		failures = 0
+
+		if resp.StatusCode == http.StatusNoContent {
+			if cfg.Timeout > 0 && time.Since(started) >= cfg.Timeout {
+				empty = 0
+				continue
+			}
+			empty++
+			if err := sleepContext(ctx, backoff.Next(empty)); err != nil {
+				return err
+			}
+			continue
+		}
+		empty = 0
+		if err := handle(res); err != nil {
+			return err
+		}
+		if cfg.Cursor != nil {
+			cursor = cfg.Cursor(res)
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// PollUntil GETs path until the decoded response satisfies done, waiting
+// between attempts according to backoff (DefaultBackoff when nil). Failed
+// requests are retried as well. It returns the first satisfying response,
+// or the last decoded response together with ctx's error joined with the
+// last request error once ctx is done.
+func PollUntil[T any](ctx context.Context, c Requests, path string, headers map[string]string, done func(T) bool, backoff Backoff, opts ...CallOption) (T, error) {
+	if backoff == nil {
+		backoff = DefaultBackoff()
+	}
+
+	var last T
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		var res T
+		_, err := c.Get(ctx, path, headers, &res, opts...)
+		if err == nil {
+			last = res
+			if done(res) {
+				return res, nil
+			}
+		}
+		lastErr = err
+
+		if err := sleepContext(ctx, backoff.Next(attempt)); err != nil {
+			return last, errors.Join(err, lastErr)
+		}
+	}
+}
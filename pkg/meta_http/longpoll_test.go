@@ -0,0 +1,132 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+type changeFeed struct {
+	Cursor  string   `json:"cursor"`
+	Changes []string `json:"changes"`
+}
+
+func TestLongPoll(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		n := calls.Add(1)
+		switch {
+		case n == 2:
+			rw.WriteHeader(http.StatusBadGateway)
+		case n == 3:
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			if req.URL.Query().Get("wait") != "30" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(rw, `{"cursor":"c%d","changes":["since=%s"]}`, n, req.URL.Query().Get("since"))
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	var seen []string
+	stop := errors.New("done")
+	err := metahttp.LongPoll(context.Background(), metaHttpClient, metahttp.LongPollConfig[changeFeed]{
+		Path:          "/changes",
+		CursorParam:   "since",
+		InitialCursor: "c0",
+		Cursor:        func(f changeFeed) string { return f.Cursor },
+		TimeoutParam:  "wait",
+		Timeout:       30 * time.Second,
+		Backoff:       metahttp.ConstantBackoff(10 * time.Millisecond),
+	}, func(f changeFeed) error {
+		seen = append(seen, f.Changes...)
+		if len(seen) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 2 || seen[0] != "since=c0" || seen[1] != "since=c1" {
+		t.Errorf("unexpected changes: %v", seen)
+	}
+}
+
+func TestLongPollEmptyResponsesReturnedEarly(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 10*time.Second, metahttp.WithoutLogging())
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := metahttp.LongPoll(ctx, client, metahttp.LongPollConfig[changeFeed]{
+		Path:         "/changes",
+		TimeoutParam: "wait",
+		Timeout:      30 * time.Second,
+		Backoff:      metahttp.ConstantBackoff(50 * time.Millisecond),
+	}, func(changeFeed) error {
+		t.Error("empty responses must not be handled")
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := calls.Load(); n < 2 || n > 5 {
+		t.Errorf("expected the server to be polled every 50ms, got %d calls in 200ms", n)
+	}
+}
+
+func TestPollUntil(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) < 3 {
+			rw.Write([]byte(`{"status":"pending"}`))
+			return
+		}
+		rw.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	type payment struct {
+		Status string `json:"status"`
+	}
+	settled := func(p payment) bool { return p.Status != "pending" }
+
+	p, err := metahttp.PollUntil(context.Background(), metaHttpClient, "/payments/1", nil, settled, metahttp.ConstantBackoff(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != "success" || calls.Load() != 3 {
+		t.Errorf("unexpected result: %+v after %d calls", p, calls.Load())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	never := func(p payment) bool { return false }
+	_, err = metahttp.PollUntil(ctx, metaHttpClient, "/payments/1", nil, never, metahttp.ConstantBackoff(10*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package metahttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+)
+
+type memoKey struct{}
+
+type memo struct {
+	mu    sync.Mutex
+	calls map[[sha256.Size]byte]*coalescedCall
+}
+
+// ContextWithMemo returns a context within which identical GETs, same URL
+// and credentials, are sent once: later and concurrent ones get a copy of
+// the first response instead of calling the upstream again. Derive it from
+// the context of an incoming request, see MemoMiddleware, so that the
+// responses live as long as that request and aggregation endpoints stop
+// fetching the same resource several times. Calls failing without a response
+// and responses over 1MiB are not memoized: the calls waiting on them are
+// sent themselves.
+func ContextWithMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, memoKey{}, &memo{calls: map[[sha256.Size]byte]*coalescedCall{}})
+}
+
+// MemoMiddleware gives every request handled by next a context from
+// ContextWithMemo.
+func MemoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(rw, r.WithContext(ContextWithMemo(r.Context())))
+	})
+}
+
+// memoRoundTripper answers GETs from the memo of their context, if any.
+type memoRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (m memoRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	memo, ok := r.Context().Value(memoKey{}).(*memo)
+	if !ok || r.Method != http.MethodGet || r.Context().Value(noCacheKey{}) != nil {
+		return m.next.RoundTrip(r)
+	}
+	key := requestKey(r, defaultCacheVary)
+
+	for {
+		memo.mu.Lock()
+		call, ok := memo.calls[key]
+		if !ok {
+			break
+		}
+		memo.mu.Unlock()
+		select {
+		case <-call.done:
+			if call.unshared {
+				continue
+			}
+			return call.response(r)
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+	}
+	call := &coalescedCall{done: make(chan struct{}), err: errCoalescedIncomplete}
+	memo.calls[key] = call
+	memo.mu.Unlock()
+
+	// Deferred so that the waiting callers are released even on panics.
+	defer func() {
+		if call.err != nil || call.unshared {
+			memo.mu.Lock()
+			delete(memo.calls, key)
+			memo.mu.Unlock()
+		}
+		close(call.done)
+	}()
+
+	res, err := m.next.RoundTrip(r)
+	if err != nil {
+		call.err, call.unshared = err, true
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxCoalescedBody+1))
+	if err != nil {
+		res.Body.Close()
+		call.err, call.unshared = err, true
+		return nil, err
+	}
+	if len(body) > maxCoalescedBody {
+		call.unshared = true
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+		return res, nil
+	}
+	res.Body.Close()
+	call.res, call.body, call.err = res, body, nil
+	return call.response(r)
+}
@@ -0,0 +1,108 @@
+package metahttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestContextMemo(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		rw.Write([]byte(`{"id":"` + r.URL.Path + `"}`))
+	}))
+	defer upstream.Close()
+	client := metahttp.NewClient(upstream.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+
+	aggregate := metahttp.MemoMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var wg sync.WaitGroup
+		for _, path := range []string{"/users/1", "/users/1", "/users/1", "/users/2"} {
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+				var res struct{ ID string }
+				if _, err := client.Get(r.Context(), path, nil, &res); err != nil || res.ID != path {
+					t.Errorf("%s: unexpected result %+v, %v", path, res, err)
+				}
+			}(path)
+		}
+		wg.Wait()
+		var res struct{ ID string }
+		client.Get(r.Context(), "/users/1", nil, &res)
+		client.Get(r.Context(), "/users/1", nil, &res, metahttp.WithoutCache())
+	}))
+
+	aggregate.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 upstream calls within the request, got %d", calls.Load())
+	}
+
+	aggregate.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	if calls.Load() != 6 {
+		t.Errorf("expected responses not to outlive their request, got %d calls", calls.Load())
+	}
+
+	client.Get(context.Background(), "/users/1", nil, nil)
+	client.Get(context.Background(), "/users/1", nil, nil)
+	if calls.Load() != 8 {
+		t.Errorf("expected calls without a memo to reach the upstream, got %d calls", calls.Load())
+	}
+}
+
+func TestContextMemoUnsharedOutcomes(t *testing.T) {
+	large := strings.Repeat("x", 2<<20)
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		if r.URL.Path == "/exports/1" {
+			rw.Write([]byte(large))
+			return
+		}
+		rw.Write([]byte(`{"id":"1"}`))
+	}))
+	defer upstream.Close()
+	client := metahttp.NewClient(upstream.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	ctx := metahttp.ContextWithMemo(context.Background())
+
+	leader, cancel := context.WithCancel(ctx)
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := client.Get(leader, "/users/1", nil, nil)
+		leaderErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	var user struct{ ID string }
+	if _, err := client.Get(ctx, "/users/1", nil, &user); err != nil || user.ID != "1" {
+		t.Fatalf("follower failed with the leader's cancellation: %+v %v", user, err)
+	}
+	if err := <-leaderErr; err == nil {
+		t.Error("expected the leader to be canceled")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected the follower to send the call itself, got %d calls", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		var body string
+		if _, err := client.Get(ctx, "/exports/1", nil, &body); err != nil || len(body) != len(large) {
+			t.Fatalf("got %d bytes, %v", len(body), err)
+		}
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("expected large responses not to be memoized, got %d calls", n)
+	}
+}
@@ -0,0 +1,141 @@
+package metahttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxNonceAttempts bounds the candidates a NonceGenerator draws before giving
+// up, which only happens with a broken Generate or store.
+const maxNonceAttempts = 5
+
+// ErrNonceExhausted is returned by a NonceGenerator that could not find an
+// unused nonce.
+var ErrNonceExhausted = errors.New("no unused nonce found")
+
+// NonceSource hands out nonces for signing schemes requiring them unique.
+type NonceSource interface {
+	Nonce(ctx context.Context) (string, error)
+}
+
+// NonceStore remembers the nonces handed out while the partner may still
+// reject them as replays. Back it with a shared, persistent store (e.g.
+// Redis SET NX with an expiry) so that restarts and other instances do not
+// reuse nonces within the window.
+type NonceStore interface {
+	// Claim records nonce as used until expires, returning false when it
+	// already was.
+	Claim(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// MemoryNonceStore is a process local NonceStore, forgetting nonces on
+// restart.
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	used      map[string]time.Time
+	nextPrune time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{used: map[string]time.Time{}}
+}
+
+func (s *MemoryNonceStore) Claim(_ context.Context, nonce string, expires time.Time) (bool, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.After(s.nextPrune) {
+		for n, exp := range s.used {
+			if now.After(exp) {
+				delete(s.used, n)
+			}
+		}
+		s.nextPrune = now.Add(time.Minute)
+	}
+	if exp, ok := s.used[nonce]; ok && !now.After(exp) {
+		return false, nil
+	}
+	s.used[nonce] = expires
+	return true, nil
+}
+
+// NonceGenerator is a NonceSource claiming every nonce in a NonceStore for
+// the replay window of the partner, so that none is handed out twice within
+// it. Random nonces practically never collide; the store matters for
+// schemes with short or predictable nonces, e.g. counters.
+type NonceGenerator struct {
+	// Window is how long the partner remembers the nonces it has seen.
+	Window time.Duration
+	Store  NonceStore
+	// Generate returns candidate nonces, 16 random bytes hex encoded when
+	// nil.
+	Generate func() (string, error)
+}
+
+// NewNonceGenerator returns a generator of random nonces claimed in store
+// for window, a MemoryNonceStore when store is nil.
+func NewNonceGenerator(window time.Duration, store NonceStore) *NonceGenerator {
+	if store == nil {
+		store = NewMemoryNonceStore()
+	}
+	return &NonceGenerator{Window: window, Store: store}
+}
+
+func (g *NonceGenerator) Nonce(ctx context.Context) (string, error) {
+	generate := g.Generate
+	if generate == nil {
+		generate = randomNonce
+	}
+	for i := 0; i < maxNonceAttempts; i++ {
+		nonce, err := generate()
+		if err != nil {
+			return "", fmt.Errorf("generating nonce: %w", err)
+		}
+		if g.Store == nil {
+			return nonce, nil
+		}
+		ok, err := g.Store.Claim(ctx, nonce, time.Now().Add(g.Window))
+		if err != nil {
+			return "", fmt.Errorf("claiming nonce: %w", err)
+		}
+		if ok {
+			return nonce, nil
+		}
+	}
+	return "", ErrNonceExhausted
+}
+
+func randomNonce() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// NonceSigner sets a fresh nonce from Source in Header of every attempt, for
+// schemes sending it in a header of its own. Register it with WithSigner
+// before the signer covering the header.
+type NonceSigner struct {
+	Header string
+	Source NonceSource
+}
+
+func NewNonceSigner(header string, source NonceSource) *NonceSigner {
+	return &NonceSigner{Header: header, Source: source}
+}
+
+func (s *NonceSigner) Sign(r *http.Request) error {
+	nonce, err := s.Source.Nonce(r.Context())
+	if err != nil {
+		return err
+	}
+	r.Header.Set(s.Header, nonce)
+	return nil
+}
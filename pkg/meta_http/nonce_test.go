@@ -0,0 +1,93 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestNonceGenerator(t *testing.T) {
+	ctx := context.Background()
+	store := metahttp.NewMemoryNonceStore()
+
+	// A counter restarting from zero, as after a process restart, skips the
+	// nonces still claimed in the store.
+	counter := func() func() (string, error) {
+		n := 0
+		return func() (string, error) {
+			n++
+			return strconv.Itoa(n), nil
+		}
+	}
+	first := &metahttp.NonceGenerator{Window: time.Minute, Store: store, Generate: counter()}
+	for _, want := range []string{"1", "2"} {
+		if got, err := first.Nonce(ctx); err != nil || got != want {
+			t.Fatalf("expected nonce %s, got %q, %v", want, got, err)
+		}
+	}
+	restarted := &metahttp.NonceGenerator{Window: time.Minute, Store: store, Generate: counter()}
+	if got, err := restarted.Nonce(ctx); err != nil || got != "3" {
+		t.Errorf("expected the claimed nonces to be skipped, got %q, %v", got, err)
+	}
+
+	stuck := &metahttp.NonceGenerator{Window: time.Minute, Store: store, Generate: func() (string, error) { return "1", nil }}
+	if _, err := stuck.Nonce(ctx); !errors.Is(err, metahttp.ErrNonceExhausted) {
+		t.Errorf("expected ErrNonceExhausted, got %v", err)
+	}
+
+	// Nonces are free again once the window is over.
+	expiring := metahttp.NewMemoryNonceStore()
+	if ok, _ := expiring.Claim(ctx, "n", time.Now().Add(-time.Second)); !ok {
+		t.Fatal("expected a first claim to succeed")
+	}
+	if ok, _ := expiring.Claim(ctx, "n", time.Now().Add(time.Minute)); !ok {
+		t.Error("expected an expired nonce to be claimable again")
+	}
+
+	random := metahttp.NewNonceGenerator(time.Minute, nil)
+	a, _ := random.Nonce(ctx)
+	b, _ := random.Nonce(ctx)
+	if len(a) != 32 || a == b {
+		t.Errorf("expected distinct random nonces, got %q and %q", a, b)
+	}
+}
+
+func TestNonceSigners(t *testing.T) {
+	var nonces, inputs []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		nonces = append(nonces, r.Header.Get("X-Nonce"))
+		inputs = append(inputs, r.Header.Get("Signature-Input"))
+		if len(nonces) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	generator := metahttp.NewNonceGenerator(5*time.Minute, nil)
+	signer := metahttp.NewHTTPMessageSigner("key-1", metahttp.NewHMACSHA256Key([]byte("secret")), "@method", "x-nonce")
+	signer.Nonces = generator
+	client := metahttp.NewClientWithRetry(server.URL, nil, 5*time.Second, models.Retry{
+		MaxRetries: 2,
+		Validator:  func(status int) bool { return status < 500 },
+	}, metahttp.WithoutLogging(), metahttp.WithSigner(metahttp.NewNonceSigner("X-Nonce", generator)), metahttp.WithSigner(signer))
+
+	if _, err := client.Get(context.Background(), "/balances", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(nonces) != 2 || nonces[0] == "" || nonces[0] == nonces[1] {
+		t.Errorf("expected a fresh nonce per attempt, got %q", nonces)
+	}
+	if !strings.Contains(inputs[0], ";nonce=") || inputs[0] == inputs[1] {
+		t.Errorf("expected the signature parameters to carry a fresh nonce, got %q", inputs)
+	}
+}
@@ -0,0 +1,86 @@
+package metahttp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+	"github.com/onmetahq/meta-http/pkg/openapi"
+)
+
+// NoBody is used as the request type of operations that take no payload.
+type NoBody struct{}
+
+// PathParams fills the {name} placeholders of an operation's path template.
+type PathParams map[string]string
+
+// Operation is a typed wrapper around a single OpenAPI operation.
+type Operation[Req, Res any] struct {
+	client Requests
+	spec   *openapi.Operation
+}
+
+// BindOperation binds the operation with the given operationId to c. Use
+// NoBody as Req for operations without a request body.
+func BindOperation[Req, Res any](c Requests, spec *openapi.Spec, operationID string) (*Operation[Req, Res], error) {
+	op, ok := spec.Operation(operationID)
+	if !ok {
+		return nil, fmt.Errorf("openapi: unknown operation %q", operationID)
+	}
+	return &Operation[Req, Res]{client: c, spec: op}, nil
+}
+
+// MustBindOperation is like BindOperation but panics on unknown operations,
+// for package level wiring.
+func MustBindOperation[Req, Res any](c Requests, spec *openapi.Spec, operationID string) *Operation[Req, Res] {
+	op, err := BindOperation[Req, Res](c, spec, operationID)
+	if err != nil {
+		panic(err)
+	}
+	return op
+}
+
+func (o *Operation[Req, Res]) Spec() *openapi.Operation {
+	return o.spec
+}
+
+func (o *Operation[Req, Res]) Call(ctx context.Context, params PathParams, body Req, headers map[string]string, opts ...CallOption) (Res, *models.ResponseData, error) {
+	var res Res
+	path, err := expandPathTemplate(o.spec.PathTemplate, params)
+	if err != nil {
+		return res, nil, err
+	}
+
+	var payload interface{}
+	if _, none := any(body).(NoBody); !none {
+		payload = body
+	}
+	resp, err := o.client.Do(ctx, o.spec.Method, path, headers, payload, &res, opts...)
+	return res, resp, err
+}
+
+func expandPathTemplate(template string, params PathParams) (string, error) {
+	var b strings.Builder
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("openapi: malformed path template %q", template)
+		}
+		name := rest[start+1 : start+end]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("openapi: missing path parameter %q for %s", name, template)
+		}
+		b.WriteString(rest[:start])
+		b.WriteString(url.PathEscape(value))
+		rest = rest[start+end+1:]
+	}
+}
@@ -0,0 +1,86 @@
+package metahttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/openapi"
+)
+
+const ordersSpec = `{
+	"openapi": "3.0.3",
+	"paths": {
+		"/orders/{orderId}": {
+			"parameters": [{"name": "orderId", "in": "path", "required": true}],
+			"get": {
+				"operationId": "getOrder",
+				"responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Order"}}}}}
+			},
+			"patch": {
+				"operationId": "updateOrder",
+				"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Order"}}}},
+				"responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Order"}}}}}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"Order": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}, "status": {"type": "string"}}}
+		}
+	}
+}`
+
+type order struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestOpenAPIOperations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		o := order{ID: req.URL.EscapedPath()[len("/orders/"):], Status: "created"}
+		if req.Method == http.MethodPatch {
+			json.NewDecoder(req.Body).Decode(&o)
+		}
+		bytes, _ := json.Marshal(o)
+		rw.Write(bytes)
+	}))
+	defer server.Close()
+
+	spec, err := openapi.Parse([]byte(ordersSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	getOrder := metahttp.MustBindOperation[metahttp.NoBody, order](metaHttpClient, spec, "getOrder")
+	updateOrder := metahttp.MustBindOperation[order, order](metaHttpClient, spec, "updateOrder")
+
+	o, _, err := getOrder.Call(context.Background(), metahttp.PathParams{"orderId": "ord 1"}, metahttp.NoBody{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ID != "ord%201" || o.Status != "created" {
+		t.Errorf("unexpected order: %+v", o)
+	}
+
+	o, _, err = updateOrder.Call(context.Background(), metahttp.PathParams{"orderId": "ord_1"}, order{ID: "ord_1", Status: "paid"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != "paid" {
+		t.Errorf("unexpected order: %+v", o)
+	}
+
+	if _, _, err := getOrder.Call(context.Background(), metahttp.PathParams{}, metahttp.NoBody{}, nil); err == nil {
+		t.Error("missing path parameter should fail")
+	}
+}
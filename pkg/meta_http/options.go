@@ -0,0 +1,680 @@
+package metahttp
+
+import (
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/jsonschema"
+	"github.com/onmetahq/meta-http/pkg/masking"
+	"github.com/onmetahq/meta-http/pkg/models"
+	"github.com/onmetahq/meta-http/pkg/utils"
+)
+
+// Option configures optional behaviour of a client created through
+// NewClient or NewClientWithRetry.
+type Option func(*options)
+
+type options struct {
+	signers      []Signer
+	codecs       map[string]Codec
+	strictPaths  bool
+	validator    StructValidator
+	resValidator StructValidator
+	har          *HARRecorder
+	fixtureDir   string
+	timeouts     Timeouts
+	rateLimit    *RateLimit
+	usage        *usageCounters
+	shadow       *Shadow
+	encodings    []string
+	compression  *requestCompression
+	ssrf         *ssrfGuard
+	policies     []DestinationPolicy
+	// redirectStrip is nil until WithRedirectStripHeaders is used.
+	redirectStrip []string
+	redirectMode  RedirectMode
+	// responseHeaders is nil unless WithResponseHeaderAllowlist is used.
+	responseHeaders    headerSet
+	logHeaders         bool
+	logScrub           []string
+	audit              AuditRecorder
+	masker             *masking.Masker
+	methods            map[string]bool
+	slowThreshold      time.Duration
+	onSlow             func(SlowRequest)
+	onRetryAttempt     func(RetryAttempt)
+	truncationAttempts int
+	truncationDelay    time.Duration
+	skewThreshold      time.Duration
+	onSkew             func(ClockSkew)
+	noLogging          bool
+	envelope           string
+	coalesceWindow     time.Duration
+	scheduleStore      ScheduleStore
+	onScheduled        func(ScheduledRequest, *models.ResponseData, error)
+	routes             *router
+	deadlineReserve    float64
+	sticky             *StickyRouting
+	concurrency        *ConcurrencyLimit
+	shed               func(ShedRequest) bool
+	timeoutWarnings    bool
+	dryRun             bool
+	usageInterval      time.Duration
+	onUsage            func([]EndpointUsage)
+	cache              *ResponseCache
+}
+
+// WithSigner signs every attempt of outgoing requests with the given signer,
+// retries included, so that timestamp-bound signatures are regenerated
+// instead of replayed. Signers see a fresh copy of the request each time and
+// run in the order they were configured, before the logging layer.
+func WithSigner(signer Signer) Option {
+	return func(o *options) {
+		o.signers = append(o.signers, signer)
+	}
+}
+
+// WithTokenProvider authenticates every outgoing request with a token fetched
+// from provider, sent in the Authorization header. Wrap slow providers with
+// NewCachedTokenProvider.
+func WithTokenProvider(provider TokenProvider) Option {
+	return func(o *options) {
+		o.signers = append(o.signers, tokenSigner{provider: provider})
+	}
+}
+
+// WithCodec registers codec for responses whose Content-Type has the given
+// media type (e.g. "application/vnd.api+json"), and for request bodies sent
+// WithContentType it, replacing any built-in codec for it.
+func WithCodec(mediaType string, codec Codec) Option {
+	return func(o *options) {
+		if o.codecs == nil {
+			o.codecs = map[string]Codec{}
+		}
+		o.codecs[strings.ToLower(mediaType)] = codec
+	}
+}
+
+// WithStrictPaths rejects request paths containing dot segments, backslashes
+// or control characters with models.ErrUnsafePath instead of sending them.
+// Build paths from user input with utils.JoinPath.
+func WithStrictPaths() Option {
+	return func(o *options) {
+		o.strictPaths = true
+	}
+}
+
+// WithRequestValidator validates struct payloads of Post and Put calls before
+// they are marshaled, failing the call with a *models.ValidationError.
+func WithRequestValidator(validator StructValidator) Option {
+	return func(o *options) {
+		o.validator = validator
+	}
+}
+
+// WithResponseValidator validates the struct targets of successful calls
+// once decoded, and each struct of slice targets, failing the call with a
+// *models.ResponseValidationError so that invalid payloads do not reach
+// business logic. money.Validate checks monetary fields.
+func WithResponseValidator(validator StructValidator) Option {
+	return func(o *options) {
+		o.resValidator = validator
+	}
+}
+
+// WithHARRecorder records every request attempt and its response into rec.
+func WithHARRecorder(rec *HARRecorder) Option {
+	return func(o *options) {
+		o.har = rec
+	}
+}
+
+// WithFixtures serves every response from fixture files under dir instead
+// of calling the network, for running services locally without access to
+// partner sandboxes. Requests without a fixture fail with
+// models.ErrFixtureNotFound. See fixtureTransport for the file layout.
+func WithFixtures(dir string) Option {
+	return func(o *options) {
+		o.fixtureDir = dir
+	}
+}
+
+// WithTimeouts sets per-phase timeouts, e.g. connect within 2s while allowing
+// a slow 60s body as long as bytes keep flowing.
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(o *options) {
+		o.timeouts = timeouts
+	}
+}
+
+// WithRateLimit delays requests exceeding limit until the limiter allows
+// them. Calls whose context ends first fail with models.ErrRateLimited, and
+// so do those the limiter never allows, e.g. with a zero rate.
+func WithRateLimit(limit RateLimit) Option {
+	return func(o *options) {
+		o.rateLimit = &limit
+	}
+}
+
+// WithConcurrencyLimit bounds the requests of the client in flight at once,
+// queueing the others as limit says. Retry attempts queue again.
+func WithConcurrencyLimit(limit ConcurrencyLimit) Option {
+	return func(o *options) {
+		o.concurrency = &limit
+	}
+}
+
+// WithLoadShedding asks shed about every call before it is made, failing
+// those it sheds with models.ErrLoadShed without sending them, e.g.
+// (&ResourceShedder{MaxGoroutines: 50000}).Shed to drop low priority calls
+// while the process is under pressure. shed runs on the path of every call
+// and must be cheap and safe for concurrent use.
+func WithLoadShedding(shed func(ShedRequest) bool) Option {
+	return func(o *options) {
+		o.shed = shed
+	}
+}
+
+// WithShadowTraffic asynchronously mirrors shadow.Percent of requests to
+// shadow.BaseURL, ignoring the responses.
+func WithShadowTraffic(shadow Shadow) Option {
+	return func(o *options) {
+		o.shadow = &shadow
+	}
+}
+
+// WithAcceptEncoding advertises encodings (EncodingBrotli, EncodingZstd,
+// EncodingGzip) in the Accept-Encoding header, in order of preference, unless
+// the request sets the header itself. Responses in any of them are decoded
+// whether or not this option is used.
+func WithAcceptEncoding(encodings ...string) Option {
+	return func(o *options) {
+		o.encodings = append(o.encodings, encodings...)
+	}
+}
+
+// WithRequestCompression compresses request payloads of at least minSize
+// bytes with encoding (EncodingGzip, EncodingBrotli or EncodingZstd) and sets
+// Content-Encoding accordingly. Streaming bodies are always compressed since
+// their size is not known upfront.
+func WithRequestCompression(encoding string, minSize int) Option {
+	return func(o *options) {
+		o.compression = &requestCompression{encoding: encoding, minSize: minSize}
+	}
+}
+
+// WithSSRFProtection refuses to connect to private, loopback, link-local
+// (including cloud metadata endpoints) and other reserved addresses, failing
+// with models.ErrBlockedDestination. Use it when URLs come from user input.
+// Addresses within allow are permitted anyway. Proxies from the environment
+// are ignored while the guard is active.
+func WithSSRFProtection(allow ...netip.Prefix) Option {
+	return func(o *options) {
+		o.ssrf = &ssrfGuard{allowed: allow}
+	}
+}
+
+// WithDestinationPolicy checks every request URL, including redirect targets,
+// against policy before dialing, e.g. AllowHosts("*.partner.com"). Several
+// policies must all pass.
+func WithDestinationPolicy(policy DestinationPolicy) Option {
+	return func(o *options) {
+		o.policies = append(o.policies, policy)
+	}
+}
+
+// WithRedirectMode decides what calls answered with a 3xx do,
+// FollowRedirects by default.
+func WithRedirectMode(mode RedirectMode) Option {
+	return func(o *options) {
+		o.redirectMode = mode
+	}
+}
+
+// WithRedirectStripHeaders replaces the headers removed from redirects that
+// leave the original host, by default Authorization, Proxy-Authorization,
+// cookies, x-api-key, apikey and HTTP message signatures. Signers are not run
+// for such redirects either. Calling it without headers forwards everything
+// net/http itself does not strip and signs every hop.
+func WithRedirectStripHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.redirectStrip = append([]string{}, headers...)
+	}
+}
+
+// WithResponseHeaderAllowlist copies only the named response headers into
+// ResponseData.Header; all other headers are dropped before reaching the
+// caller.
+func WithResponseHeaderAllowlist(headers ...string) Option {
+	return func(o *options) {
+		o.responseHeaders = newHeaderSet(headers...)
+	}
+}
+
+// WithHeaderLogging adds request and response headers to the "Call Ended"
+// debug log. Values of scrub are replaced by "[REDACTED]" in addition to
+// credentials and cookies, which are always scrubbed.
+func WithHeaderLogging(scrub ...string) Option {
+	return func(o *options) {
+		o.logHeaders = true
+		o.logScrub = append(o.logScrub, scrub...)
+	}
+}
+
+// WithAuditRecorder hands an AuditRecord for every request attempt, retries
+// and redirects included, to recorder. Failures to record are logged and do
+// not fail the call.
+func WithAuditRecorder(recorder AuditRecorder) Option {
+	return func(o *options) {
+		o.audit = recorder
+	}
+}
+
+// WithMasking redacts request and response bodies with masker before they
+// are written to the HAR recorder, and includes the masked bodies in audit
+// records, which otherwise only carry digests.
+func WithMasking(masker *masking.Masker) Option {
+	return func(o *options) {
+		o.masker = masker
+	}
+}
+
+// WithAllowedMethods restricts the client to methods, e.g. GET and HEAD for a
+// read-only reporting client. Other calls are logged and fail with
+// models.ErrMethodNotAllowed without being sent.
+func WithAllowedMethods(methods ...string) Option {
+	return func(o *options) {
+		if o.methods == nil {
+			o.methods = map[string]bool{}
+		}
+		for _, m := range methods {
+			o.methods[strings.ToUpper(m)] = true
+		}
+	}
+}
+
+// WithSlowRequestThreshold logs a warning with a timing breakdown for every
+// attempt taking threshold or longer, body read included, and passes it to
+// onSlow when that is not nil.
+func WithSlowRequestThreshold(threshold time.Duration, onSlow func(SlowRequest)) Option {
+	return func(o *options) {
+		o.slowThreshold = threshold
+		o.onSlow = onSlow
+	}
+}
+
+// WithTruncationRetries sends GET and HEAD calls again, up to maxAttempts
+// attempts in all and delay apart, when the connection drops mid-body. Their
+// successful responses are then read in full before being decoded, so it
+// suits bounded payloads rather than streams. Calls still truncated after
+// the last attempt fail with models.ErrTruncatedBody, as all calls do
+// without this option. The attempts count as retries in Stats and are made
+// within each attempt of the retry policy.
+func WithTruncationRetries(maxAttempts int, delay time.Duration) Option {
+	return func(o *options) {
+		o.truncationAttempts = maxAttempts
+		o.truncationDelay = delay
+	}
+}
+
+// WithRetryObserver passes every attempt of the calls of the client to
+// observe, retried or not, before the delay preceding the next one. observe
+// runs on the path of the call and must be safe for concurrent use. Health
+// checks are not observed.
+func WithRetryObserver(observe func(RetryAttempt)) Option {
+	return func(o *options) {
+		o.onRetryAttempt = observe
+	}
+}
+
+// WithClockSkewThreshold logs a warning for every response whose Date header
+// is threshold or more away from the local clock, and passes it to onSkew
+// when that is not nil. Skewed clocks are the usual cause of rejected
+// signatures and expired tokens. ResponseData.ClockSkew is set with or
+// without this option.
+func WithClockSkewThreshold(threshold time.Duration, onSkew func(ClockSkew)) Option {
+	return func(o *options) {
+		o.skewThreshold = threshold
+		o.onSkew = onSkew
+	}
+}
+
+// WithResponseEnvelope decodes JSON responses from their top-level field
+// instead of the whole document, so {"data": {...}} decodes straight into
+// the caller's target. Responses lacking the field fail to decode. Raw
+// string and []byte targets, BodyDecoders and error responses are left
+// alone.
+func WithResponseEnvelope(field string) Option {
+	return func(o *options) {
+		o.envelope = field
+	}
+}
+
+// WithIdempotencyCoalescing sends concurrent calls carrying the same
+// Idempotency-Key header, method and URL upstream once and hands each of them
+// the response, so a double-submitted form creates one payment. Calls with
+// the same key arriving within window after the first completed get its
+// response too. Responses are buffered in memory up to 1MiB; calls waiting
+// on a larger response, or on a call whose own context ended, are sent
+// themselves.
+func WithIdempotencyCoalescing(window time.Duration) Option {
+	return func(o *options) {
+		o.coalesceWindow = window
+	}
+}
+
+// WithScheduleStore persists requests passed to Schedule in store, and
+// schedules again the requests it holds when the client is created. onDone,
+// when not nil, receives the final outcome of every scheduled request, once
+// it succeeded, failed for good or ran out of attempts.
+func WithScheduleStore(store ScheduleStore, onDone func(ScheduledRequest, *models.ResponseData, error)) Option {
+	return func(o *options) {
+		o.scheduleStore = store
+		o.onScheduled = onDone
+	}
+}
+
+// WithResponseCache caches GET responses in memory as configured by cache,
+// for reference data lookups. Cache hits skip retries, logging and every
+// other stage of the call, and are marked with CacheStatusHeader.
+func WithResponseCache(cache ResponseCache) Option {
+	return func(o *options) {
+		o.cache = &cache
+	}
+}
+
+// WithUsageReporter aggregates calls per endpoint, method and caller tag
+// over interval, DefaultUsageInterval when not positive, and passes the
+// summaries of each interval to report once it is over, e.g. to log them or
+// write them to a table. Endpoints are labelled by route, see
+// WithRouteTemplates. report is called from a timer goroutine, one interval
+// at a time, and Close reports the calls of the ongoing interval.
+func WithUsageReporter(interval time.Duration, report func([]EndpointUsage)) Option {
+	return func(o *options) {
+		o.usageInterval = interval
+		o.onUsage = report
+	}
+}
+
+// WithRouteTemplates labels logs and slow-call reports with the
+// matching template, e.g. "/users/{id}/orders", instead of the raw path
+// whose IDs explode metric cardinality. Templates are matched against the
+// path passed to the call, segment by segment, in the order given; paths
+// matching none are labeled by NormalizeIDs.
+func WithRouteTemplates(templates ...string) Option {
+	return func(o *options) {
+		if o.routes == nil {
+			o.routes = &router{}
+		}
+		for _, t := range templates {
+			o.routes.templates = append(o.routes.templates, strings.Split(strings.Trim(t, "/"), "/"))
+		}
+	}
+}
+
+// WithRouteNormalizer labels the paths no route template matches with
+// normalize(path) instead of NormalizeIDs.
+func WithRouteNormalizer(normalize func(path string) string) Option {
+	return func(o *options) {
+		if o.routes == nil {
+			o.routes = &router{}
+		}
+		o.routes.normalize = normalize
+	}
+}
+
+// WithDeadlineBudget bounds each attempt of calls whose context has a
+// deadline by the time left before it, less reserve, the fraction kept for
+// the caller, e.g. 0.2 to leave a fifth of the remaining time for
+// processing or a fallback. Attempts cut short fail with a
+// models.TimeoutError from models.TimeoutAttempt. reserve must be in (0, 1).
+func WithDeadlineBudget(reserve float64) Option {
+	return func(o *options) {
+		o.deadlineReserve = reserve
+	}
+}
+
+// WithStickyRouting routes the calls of the client to the node of their
+// key, see StickyRouting. Every attempt goes to the node, so retries of a
+// failing node are not moved to another one.
+func WithStickyRouting(routing StickyRouting) Option {
+	return func(o *options) {
+		o.sticky = &routing
+	}
+}
+
+// WithTimeoutWarnings logs a warning, at most once a minute, when more than
+// 1% of the recent calls of the client used over 80% of their timeout, with
+// the timeout Stats.Timeouts suggests.
+func WithTimeoutWarnings() Option {
+	return func(o *options) {
+		o.timeoutWarnings = true
+	}
+}
+
+// WithDryRun prepares every call as usual, headers, signatures and
+// compression included, then logs it at info level instead of sending it,
+// with credentials redacted as WithHeaderLogging does and the body masked
+// by WithMasking. Calls get an empty 204 response with DryRunHeader set, so
+// their targets are left as they are. Shadow traffic is not mirrored,
+// WarmUp does nothing and DialWebSocket fails with models.ErrDryRun.
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// WithoutLogging drops every log entry of the client, including warnings
+// and recovered panics, and removes the logging layer from the transport for
+// benchmark-sensitive paths. Loggers passed to individual calls are ignored
+// as well.
+func WithoutLogging() Option {
+	return func(o *options) {
+		o.noLogging = true
+	}
+}
+
+// CallOption configures a single Get, Post or Put call.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	header         http.Header
+	query          url.Values
+	responseSchema *jsonschema.Schema
+	streamBody     bool
+	contentType    string
+	accept         string
+	discardBody    bool
+	caller         string
+	noCache        bool
+	timeout        time.Duration
+	retry          *models.Retry
+	expectedStatus []int
+	logger         Logger
+	informational  func(status int, header http.Header)
+	priority       *Priority
+	err            error
+}
+
+// noCallOptions is shared by the calls made without options. It must never
+// be modified.
+var noCallOptions callOptions
+
+// WithHeaderValues sends every value of each header in header, e.g. repeated
+// Forwarded entries. Keys present here replace the same keys coming from the
+// headers map, default headers and context.
+func WithHeaderValues(header http.Header) CallOption {
+	return func(co *callOptions) {
+		if co.header == nil {
+			co.header = http.Header{}
+		}
+		for k, values := range header {
+			for _, v := range values {
+				co.header.Add(k, v)
+			}
+		}
+	}
+}
+
+// WithInformational calls fn with the status and headers of every 1xx
+// response received before the final one, e.g. the Link headers of 103
+// Early Hints, so that the referenced resources can be fetched while the
+// server is still working. fn runs on the connection's goroutine and must
+// not block. Calls served from the cache or sharing the call of another do
+// not report them.
+func WithInformational(fn func(status int, header http.Header)) CallOption {
+	return func(co *callOptions) {
+		co.informational = fn
+	}
+}
+
+// WithTimeout bounds the call, retries included, to timeout. It can only
+// shorten the timeout of the client.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(co *callOptions) {
+		co.timeout = timeout
+	}
+}
+
+type retryPolicyKey struct{}
+
+// WithRetry retries the call as retry says instead of following the policy
+// of the client, if any. The call fails with models.ErrInvalidConfig when
+// retry is invalid.
+func WithRetry(retry models.Retry) CallOption {
+	return func(co *callOptions) {
+		if err := validateRetry(retry); err != nil {
+			co.err = err
+			return
+		}
+		co.retry = &retry
+	}
+}
+
+// WithExpectedStatus lists the statuses the call succeeds with, instead of
+// any 2xx. Others fail the call with a *models.HttpClientErrorResponse. Non
+// 2xx expected statuses, e.g. a 404 meaning "not registered yet", succeed
+// without decoding the body.
+func WithExpectedStatus(statuses ...int) CallOption {
+	return func(co *callOptions) {
+		co.expectedStatus = statuses
+	}
+}
+
+// WithContentType sends the request body as mediaType instead of JSON. The
+// body is encoded with the codec registered for mediaType, or sent as-is
+// when it is a string or []byte and no codec is, e.g. for application/jose
+// payloads.
+func WithContentType(mediaType string) CallOption {
+	return func(co *callOptions) {
+		co.contentType = mediaType
+	}
+}
+
+// WithAccept asks for mediaType instead of JSON, e.g. text/csv exports. A
+// response without a Content-Type is then decoded as the first media type
+// of the list rather than as JSON.
+func WithAccept(mediaType string) CallOption {
+	return func(co *callOptions) {
+		co.accept = mediaType
+	}
+}
+
+// DiscardBody drains and closes successful responses without decoding them,
+// whatever the target, for fire-and-forget calls such as webhook pings or
+// cache invalidations whose responses may be empty or opaque. Error
+// responses are still reported as usual.
+func DiscardBody() CallOption {
+	return func(co *callOptions) {
+		co.discardBody = true
+	}
+}
+
+// WithQuery appends query parameters to the request URL. params is either
+// url.Values or a struct encoded with utils.EncodeQuery.
+func WithQuery(params interface{}) CallOption {
+	return func(co *callOptions) {
+		values, ok := params.(url.Values)
+		if !ok {
+			var err error
+			if values, err = utils.EncodeQuery(params); err != nil {
+				co.err = err
+				return
+			}
+		}
+		if co.query == nil {
+			co.query = url.Values{}
+		}
+		for k, vs := range values {
+			co.query[k] = append(co.query[k], vs...)
+		}
+	}
+}
+
+// WithResponseSchema validates successful response bodies against schema
+// before decoding them, failing the call with a *jsonschema.ValidationError
+// listing every violation.
+func WithResponseSchema(schema *jsonschema.Schema) CallOption {
+	return func(co *callOptions) {
+		co.responseSchema = schema
+	}
+}
+
+// WithStreamingBody encodes the request payload directly into the connection
+// using chunked transfer encoding rather than buffering it first, bounding
+// memory for multi-MB batch payloads. Retries re-encode the payload, so it
+// must not change while the call is in flight. Only JSON bodies are
+// streamed, others are buffered as usual.
+func WithStreamingBody() CallOption {
+	return func(co *callOptions) {
+		co.streamBody = true
+	}
+}
+
+// WithLogger logs this call to log instead of the client's logger. See
+// ContextWithLogger to set it for every call made with a context.
+func WithLogger(log Logger) CallOption {
+	return func(co *callOptions) {
+		co.logger = log
+	}
+}
+
+// WithIfNoneMatch makes a GET conditional on the resource no longer
+// carrying etag, the ResponseData.ETag of the cached copy. When it still
+// does, the call succeeds without decoding anything and
+// ResponseData.NotModified reports it.
+func WithIfNoneMatch(etag string) CallOption {
+	return func(co *callOptions) {
+		if co.header == nil {
+			co.header = http.Header{}
+		}
+		co.header.Set("If-None-Match", etag)
+	}
+}
+
+// WithIfModifiedSince is WithIfNoneMatch for upstreams that only send
+// Last-Modified.
+func WithIfModifiedSince(t time.Time) CallOption {
+	return func(co *callOptions) {
+		if co.header == nil {
+			co.header = http.Header{}
+		}
+		co.header.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// WithIfMatch makes the call conditional on the resource still carrying
+// etag, typically ResponseData.ETag of the GET it was read with. If the
+// resource changed meanwhile the call fails with an error matching
+// models.ErrConflict.
+func WithIfMatch(etag string) CallOption {
+	return func(co *callOptions) {
+		if co.header == nil {
+			co.header = http.Header{}
+		}
+		co.header.Set("If-Match", etag)
+	}
+}
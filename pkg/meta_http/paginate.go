@@ -0,0 +1,88 @@
+package metahttp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Page is one page of a paginated listing.
+type Page[T any] struct {
+	Items []T
+	// TotalPages is only read from the first page.
+	TotalPages int
+}
+
+// FetchAllPages fetches page 1, learns the number of pages from it, then
+// fetches the remaining pages with at most concurrency calls in flight and
+// returns the items of every page in page order. Pages are numbered from 1.
+// The first failing page cancels the others and its error is returned.
+//
+//	orders, err := metahttp.FetchAllPages(ctx, 4, func(ctx context.Context, page int) (metahttp.Page[Order], error) {
+//		var res OrdersPage
+//		_, err := client.Get(ctx, "/orders", nil, &res, metahttp.WithQuery(url.Values{"page": {strconv.Itoa(page)}}))
+//		return metahttp.Page[Order]{Items: res.Orders, TotalPages: res.Pages}, err
+//	})
+func FetchAllPages[T any](ctx context.Context, concurrency int, fetch func(ctx context.Context, page int) (Page[T], error)) ([]T, error) {
+	first, err := fetch(ctx, 1)
+	if err != nil {
+		return nil, fmt.Errorf("page 1: %w", err)
+	}
+	if first.TotalPages <= 1 {
+		return first.Items, nil
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pages := make([][]T, first.TotalPages)
+	pages[0] = first.Items
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for page := 2; page <= first.TotalPages; page++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(page int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, err := fetch(ctx, page)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("page %d: %w", page, err)
+					cancel()
+				})
+				return
+			}
+			pages[page-1] = res.Items
+		}(page)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var items []T
+	for _, p := range pages {
+		items = append(items, p...)
+	}
+	return items, nil
+}
@@ -0,0 +1,79 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestFetchAllPages(t *testing.T) {
+	var inflight, maxInflight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			m := maxInflight.Load()
+			if n <= m || maxInflight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		// Later pages answer first to exercise ordering.
+		time.Sleep(time.Duration(10-page) * 5 * time.Millisecond)
+		if page == 0 || page > 7 {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"pages":7,"items":[` + strconv.Itoa(page*10) + `,` + strconv.Itoa(page*10+1) + `]}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second)
+	fetch := func(ctx context.Context, page int) (metahttp.Page[int], error) {
+		var res struct {
+			Pages int   `json:"pages"`
+			Items []int `json:"items"`
+		}
+		_, err := client.Get(ctx, "/items", nil, &res, metahttp.WithQuery(url.Values{"page": {strconv.Itoa(page)}}))
+		return metahttp.Page[int]{Items: res.Items, TotalPages: res.Pages}, err
+	}
+
+	items, err := metahttp.FetchAllPages(context.Background(), 3, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 14 {
+		t.Fatalf("expected 14 items, got %v", items)
+	}
+	for i, item := range items {
+		if want := (i/2+1)*10 + i%2; item != want {
+			t.Fatalf("items out of order: %v", items)
+		}
+	}
+	if m := maxInflight.Load(); m > 3 || m < 2 {
+		t.Errorf("expected up to 3 concurrent pages, got %d", m)
+	}
+
+	boom := errors.New("boom")
+	_, err = metahttp.FetchAllPages(context.Background(), 3, func(ctx context.Context, page int) (metahttp.Page[int], error) {
+		if page == 4 {
+			return metahttp.Page[int]{}, boom
+		}
+		return fetch(ctx, page)
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("expected the failing page's error, got %v", err)
+	}
+}
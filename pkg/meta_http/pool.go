@@ -0,0 +1,124 @@
+package metahttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// requestBody produces fresh readers over an encoded payload so that retries
+// and redirects can resend it. length is -1 when unknown upfront and
+// encoding names the Content-Encoding applied to the payload, if any.
+type requestBody struct {
+	getBody  func() (io.ReadCloser, error)
+	length   int64
+	encoding string
+}
+
+// streamJSONBody encodes v straight into the request through a pipe instead
+// of materialising the whole document in memory. Every call re-encodes v.
+func streamJSONBody(v interface{}) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(json.NewEncoder(pw).Encode(v))
+		}()
+		return pr, nil
+	}
+}
+
+// maxPooledBufferSize keeps the occasional multi-MB payload from pinning
+// memory in the pool.
+const maxPooledBufferSize = 1 << 20
+
+var errBufferReleased = errors.New("request body already released")
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// pooledBuffer is a reference counted buffer from bufferPool. The client
+// holds one reference for the duration of the call and every body handed to
+// the transport holds another, since transports may close request bodies
+// asynchronously after RoundTrip returns.
+type pooledBuffer struct {
+	buf  *bytes.Buffer
+	refs atomic.Int32
+}
+
+func encodeJSONBody(v interface{}) (*pooledBuffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		bufferPool.Put(buf)
+		return nil, err
+	}
+	// Encode terminates the document with a newline Marshal would not emit.
+	buf.Truncate(buf.Len() - 1)
+
+	pb := &pooledBuffer{buf: buf}
+	pb.refs.Store(1)
+	return pb, nil
+}
+
+// encodeBody encodes v with codec into a pooled buffer.
+func encodeBody(codec Codec, v interface{}) (*pooledBuffer, error) {
+	if _, ok := codec.(jsonCodec); ok {
+		return encodeJSONBody(v)
+	}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := codec.Encode(buf, v); err != nil {
+		bufferPool.Put(buf)
+		return nil, err
+	}
+	pb := &pooledBuffer{buf: buf}
+	pb.refs.Store(1)
+	return pb, nil
+}
+
+func (pb *pooledBuffer) Len() int {
+	return pb.buf.Len()
+}
+
+// Bytes exposes the encoded payload; it is only valid while a reference is
+// held.
+func (pb *pooledBuffer) Bytes() []byte {
+	return pb.buf.Bytes()
+}
+
+// NewReader returns a body reading the payload, holding a reference until
+// it is closed. It fails once every reference has been released.
+func (pb *pooledBuffer) NewReader() (io.ReadCloser, error) {
+	if pb.refs.Add(1) == 1 {
+		pb.refs.Add(-1)
+		return nil, errBufferReleased
+	}
+	return &pooledReader{Reader: bytes.NewReader(pb.buf.Bytes()), owner: pb}, nil
+}
+
+func (pb *pooledBuffer) Release() {
+	if pb.refs.Add(-1) != 0 {
+		return
+	}
+	if pb.buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(pb.buf)
+	}
+	pb.buf = nil
+}
+
+type pooledReader struct {
+	*bytes.Reader
+	owner *pooledBuffer
+	once  sync.Once
+}
+
+func (r *pooledReader) Close() error {
+	r.once.Do(r.owner.Release)
+	return nil
+}
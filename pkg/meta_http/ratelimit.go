@@ -0,0 +1,82 @@
+package metahttp
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit bounds outgoing requests to RequestsPerSecond on average with
+// bursts of up to Burst requests. Each retry attempt counts as a request.
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+func (rl RateLimit) limiter() *rate.Limiter {
+	burst := rl.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(rl.RequestsPerSecond), burst)
+}
+
+// Usage counts the requests a client sent through its rate limiter.
+type Usage struct {
+	// Requests is the number of attempts let through.
+	Requests int64
+	// Throttled is the number of attempts that had to wait for the limiter.
+	Throttled int64
+	// Rejected is the number of attempts failed with models.ErrRateLimited
+	// because the context ended before the limiter allowed them, or the
+	// limiter never would.
+	Rejected int64
+}
+
+type usageCounters struct {
+	requests  atomic.Int64
+	throttled atomic.Int64
+	rejected  atomic.Int64
+}
+
+func (u *usageCounters) snapshot() Usage {
+	return Usage{
+		Requests:  u.requests.Load(),
+		Throttled: u.throttled.Load(),
+		Rejected:  u.rejected.Load(),
+	}
+}
+
+type rateLimitRoundTripper struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+	usage   *usageCounters
+}
+
+func (rl rateLimitRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	reservation := rl.limiter.Reserve()
+	if !reservation.OK() {
+		// No wait would do, e.g. with a zero rate once the burst is spent.
+		rl.usage.rejected.Add(1)
+		return nil, fmt.Errorf("%w: %v requests per second", models.ErrRateLimited, rl.limiter.Limit())
+	}
+	if delay := reservation.Delay(); delay > 0 {
+		rl.usage.throttled.Add(1)
+		if deadline, ok := r.Context().Deadline(); ok && time.Until(deadline) < delay {
+			reservation.Cancel()
+			rl.usage.rejected.Add(1)
+			return nil, models.ErrRateLimited
+		}
+		if err := sleepContext(r.Context(), delay); err != nil {
+			reservation.Cancel()
+			rl.usage.rejected.Add(1)
+			return nil, fmt.Errorf("%w: %v", models.ErrRateLimited, err)
+		}
+	}
+	rl.usage.requests.Add(1)
+	return rl.next.RoundTrip(r)
+}
@@ -0,0 +1,35 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestZeroRateLimitFailsFast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithRateLimit(metahttp.RateLimit{RequestsPerSecond: 0, Burst: 1}))
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	// The burst is spent and a zero rate never refills it: waiting for the
+	// client timeout would be pointless.
+	started := time.Now()
+	_, err := client.Get(context.Background(), "/", nil, nil)
+	if !errors.Is(err, models.ErrRateLimited) || models.CategoryOf(err) != models.CategoryRequest {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+	if time.Since(started) > time.Second {
+		t.Errorf("expected the call to fail fast, took %s", time.Since(started))
+	}
+}
@@ -0,0 +1,84 @@
+package metahttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+const maxRedirects = 10
+
+// defaultRedirectStripHeaders are credentials removed when a redirect leaves
+// the original host. net/http only strips Authorization and cookies, and
+// only for hosts outside the original domain.
+var defaultRedirectStripHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Cookie2",
+	string(models.MerchantAPIKey), string(models.APIContextKey),
+	"Signature", "Signature-Input",
+}
+
+// RedirectMode decides what a client does with 3xx responses.
+type RedirectMode int
+
+const (
+	// FollowRedirects follows redirects, up to 10 of them. 3xx responses
+	// that cannot be followed, such as 300 Multiple Choices, are returned
+	// like with ReturnRedirects.
+	FollowRedirects RedirectMode = iota
+	// ReturnRedirects never follows redirects: calls succeed without
+	// decoding and ResponseData.Redirect describes the redirection.
+	ReturnRedirects
+	// RejectRedirects fails calls answered with a 3xx with an error
+	// wrapping models.ErrUnexpectedRedirect.
+	RejectRedirects
+)
+
+type redirectPolicy struct {
+	strip []string
+	mode  RedirectMode
+}
+
+func (p redirectPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if p.mode != FollowRedirects {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+	if crossHostRedirect(req) {
+		for _, h := range p.strip {
+			req.Header.Del(h)
+		}
+	}
+	return nil
+}
+
+// crossHostRedirect reports whether r is a redirect hop to a host other than
+// the one the call was originally sent to. Ports count as part of the host.
+func crossHostRedirect(r *http.Request) bool {
+	original := r
+	for original.Response != nil && original.Response.Request != nil {
+		original = original.Response.Request
+	}
+	return original != r && !strings.EqualFold(original.URL.Host, r.URL.Host)
+}
+
+// redirection describes res if it is a 3xx response, 304 Not Modified
+// excepted.
+func redirection(res *http.Response) *models.RedirectionResult {
+	if res.StatusCode < 300 || res.StatusCode >= 400 || res.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	location, _ := res.Location()
+	return &models.RedirectionResult{StatusCode: res.StatusCode, Location: location}
+}
+
+func describeRedirect(r *models.RedirectionResult) string {
+	if r.Location == nil {
+		return fmt.Sprintf("status %d without location", r.StatusCode)
+	}
+	return fmt.Sprintf("status %d to %s", r.StatusCode, r.Location.Redacted())
+}
@@ -0,0 +1,123 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestRedirectStripsCredentials(t *testing.T) {
+	var seen http.Header
+	target := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Clone()
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+	otherHost := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+
+	var sameHostAuth string
+	origin := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cross":
+			http.Redirect(rw, r, otherHost+"/landing", http.StatusFound)
+		case "/same":
+			http.Redirect(rw, r, "/landing", http.StatusFound)
+		case "/landing":
+			sameHostAuth = r.Header.Get("Authorization")
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer origin.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	tokens := metahttp.TokenProviderFunc(func(ctx context.Context) (*models.Token, error) {
+		return &models.Token{AccessToken: "secret"}, nil
+	})
+	client := metahttp.NewClient(origin.URL, logger, 5*time.Second, metahttp.WithTokenProvider(tokens))
+	headers := map[string]string{"x-api-key": "merchant-key", "X-Trace": "abc"}
+
+	if _, err := client.Get(context.Background(), "/cross", headers, nil); err != nil {
+		t.Fatal(err)
+	}
+	if seen == nil {
+		t.Fatal("redirect target was not called")
+	}
+	if seen.Get("Authorization") != "" || seen.Get("x-api-key") != "" {
+		t.Errorf("credentials leaked across hosts: %v", seen)
+	}
+	if seen.Get("X-Trace") != "abc" {
+		t.Error("non sensitive headers should be forwarded")
+	}
+
+	if _, err := client.Get(context.Background(), "/same", headers, nil); err != nil {
+		t.Fatal(err)
+	}
+	if sameHostAuth != "Bearer secret" {
+		t.Errorf("same host redirects should keep credentials, got %q", sameHostAuth)
+	}
+
+	seen = nil
+	permissive := metahttp.NewClient(origin.URL, logger, 5*time.Second, metahttp.WithRedirectStripHeaders())
+	if _, err := permissive.Get(context.Background(), "/cross", headers, nil); err != nil {
+		t.Fatal(err)
+	}
+	if seen.Get("x-api-key") != "merchant-key" {
+		t.Error("headers should be forwarded when stripping is disabled")
+	}
+}
+
+func TestRedirectModes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(rw, r, "/landing?from=moved", http.StatusMovedPermanently)
+		case "/choices":
+			rw.WriteHeader(http.StatusMultipleChoices)
+		case "/landing":
+			rw.Write([]byte(`{"landed":true}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	follow := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	var res map[string]bool
+	data, err := follow.Get(ctx, "/moved", nil, &res)
+	if err != nil || !res["landed"] || data.Redirect != nil {
+		t.Errorf("expected the redirect to be followed, got %v, %v", res, err)
+	}
+	data, err = follow.Get(ctx, "/choices", nil, &res)
+	if err != nil || data.Redirect == nil || data.Redirect.Location != nil {
+		t.Errorf("expected an unfollowable redirect to be returned, got %+v, %v", data, err)
+	}
+
+	returning := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithRedirectMode(metahttp.ReturnRedirects))
+	res = nil
+	data, err = returning.Get(ctx, "/moved", nil, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != nil || data.Redirect == nil || data.Redirect.StatusCode != http.StatusMovedPermanently {
+		t.Fatalf("expected a redirection result, got %+v", data)
+	}
+	if want := server.URL + "/landing?from=moved"; data.Redirect.Location.String() != want {
+		t.Errorf("expected location %s, got %s", want, data.Redirect.Location)
+	}
+
+	rejecting := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithRedirectMode(metahttp.RejectRedirects))
+	_, err = rejecting.Get(ctx, "/moved", nil, &res)
+	if !errors.Is(err, models.ErrUnexpectedRedirect) || models.CategoryOf(err) != models.CategoryHTTP3xx {
+		t.Errorf("expected an unexpected redirect error, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package metahttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// ErrRetriesExhausted is matched by the errors of calls that failed on every
+// attempt their retry policy allowed.
+var ErrRetriesExhausted = errors.New("retries exhausted")
+
+// RetriesExhaustedError is returned by calls that failed on every attempt
+// their retry policy allowed, with the history of the attempts. It matches
+// ErrRetriesExhausted and the error of the last attempt, e.g. a
+// *models.HttpClientErrorResponse, with errors.Is and errors.As, and has the
+// category of the latter.
+type RetriesExhaustedError struct {
+	Attempts []RetryAttempt
+	Err      error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("retries exhausted after %d attempts: %v", len(e.Attempts), e.Err)
+}
+
+func (e *RetriesExhaustedError) Unwrap() []error {
+	return []error{ErrRetriesExhausted, e.Err}
+}
+
+func (e *RetriesExhaustedError) ErrorCategory() models.ErrorCategory {
+	return models.CategoryOf(e.Err)
+}
+
+type retryHistoryKey struct{}
+
+// retryHistory collects the attempts of a call for RetriesExhaustedError.
+type retryHistory struct {
+	mu        sync.Mutex
+	attempts  []RetryAttempt
+	exhausted bool
+}
+
+func retryHistoryFor(ctx context.Context) *retryHistory {
+	history, _ := ctx.Value(retryHistoryKey{}).(*retryHistory)
+	return history
+}
+
+// wrap returns err as a *RetriesExhaustedError when the retry policy of the
+// call ran out of attempts.
+func (h *retryHistory) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.exhausted {
+		return err
+	}
+	return &RetriesExhaustedError{Attempts: h.attempts, Err: err}
+}
+
+// RetryAttempt describes an attempt of a call as seen by the retry layer,
+// passed to the observer of WithRetryObserver once the layer has decided
+// whether to try again. The timeline of a call lets tests assert how it was
+// retried without measuring time.
+type RetryAttempt struct {
+	Method string
+	URL    string
+	// Route is the route label of the call, empty without route templates.
+	Route string
+	// Attempt counts from 1 for the first try.
+	Attempt int
+	// Status is 0 when the attempt failed without a response.
+	Status int
+	Err    error
+	// Duration is the time to the response headers.
+	Duration time.Duration
+	// Retrying reports that another attempt follows after Delay, unless the
+	// context of the call is done first.
+	Retrying bool
+	Delay    time.Duration
+}
+
+// observe passes an attempt to the observer, if any, and records it in the
+// history of the call. exhausted marks the last attempt the policy allowed
+// as failed.
+func (rrt retryRoundTripper) observe(r *http.Request, attempt int, res *http.Response, err error, started time.Time, retrying, exhausted bool) {
+	// Only the calls that end up exhausted need their history.
+	var history *retryHistory
+	if retrying || exhausted {
+		history = retryHistoryFor(r.Context())
+	}
+	if rrt.observer == nil && history == nil {
+		return
+	}
+	a := RetryAttempt{
+		Method:   r.Method,
+		URL:      r.URL.Redacted(),
+		Route:    routeFor(r.Context()),
+		Attempt:  attempt,
+		Err:      err,
+		Duration: time.Since(started),
+		Retrying: retrying,
+	}
+	if res != nil {
+		a.Status = res.StatusCode
+	}
+	if retrying {
+		a.Delay = rrt.delay
+	}
+	if history != nil {
+		history.mu.Lock()
+		history.attempts = append(history.attempts, a)
+		history.exhausted = exhausted
+		history.mu.Unlock()
+	}
+	if rrt.observer != nil {
+		rrt.observer(a)
+	}
+}
+
+// once sends r a single time, as the only attempt of its call.
+func (rrt retryRoundTripper) once(r *http.Request) (*http.Response, error) {
+	started := time.Now()
+	res, err := rrt.next.RoundTrip(r)
+	rrt.observe(r, 1, res, err, started, false, false)
+	return res, err
+}
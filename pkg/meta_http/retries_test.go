@@ -0,0 +1,67 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestRetriesExhausted(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if r.URL.Path == "/recovering" && n%3 == 0 {
+			return
+		}
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		rw.Write([]byte(`{"error":{"message":"maintenance"},"statusCode":503}`))
+	}))
+	defer server.Close()
+
+	retry := models.Retry{
+		MaxRetries:        3,
+		DelayBetweenRetry: time.Millisecond,
+		Validator:         func(status int) bool { return status < 500 },
+	}
+	client := metahttp.NewClientWithRetry(server.URL, nil, 5*time.Second, retry, metahttp.WithoutLogging())
+
+	_, err := client.Get(context.Background(), "/down", nil, nil)
+	var exhausted *metahttp.RetriesExhaustedError
+	if !errors.As(err, &exhausted) || !errors.Is(err, metahttp.ErrRetriesExhausted) {
+		t.Fatalf("err = %v, want retries exhausted", err)
+	}
+	var errRes *models.HttpClientErrorResponse
+	if !errors.As(err, &errRes) || errRes.StatusCode != http.StatusServiceUnavailable || models.CategoryOf(err) != models.CategoryHTTP5xx {
+		t.Errorf("last error = %v (%s)", err, models.CategoryOf(err))
+	}
+	if len(exhausted.Attempts) != 3 {
+		t.Fatalf("attempts = %+v", exhausted.Attempts)
+	}
+	for i, a := range exhausted.Attempts {
+		retrying := i < 2
+		if a.Attempt != i+1 || a.Status != http.StatusServiceUnavailable || a.Retrying != retrying || (a.Delay > 0) != retrying {
+			t.Errorf("attempt %d = %+v", i+1, a)
+		}
+	}
+
+	calls.Store(0)
+	if _, err := client.Get(context.Background(), "/recovering", nil, nil); err != nil {
+		t.Errorf("call succeeding on the last attempt: %v", err)
+	}
+
+	// Failures the policy does not retry are returned as they are.
+	_, err = client.Get(context.Background(), "/down", nil, nil, metahttp.WithRetry(models.Retry{
+		MaxRetries: 3,
+		Validator:  func(int) bool { return true },
+	}))
+	if err == nil || errors.Is(err, metahttp.ErrRetriesExhausted) {
+		t.Errorf("unretried failure: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package metahttp
+
+import (
+	"context"
+	"strings"
+)
+
+// router maps call paths to low-cardinality route labels for logs and
+// metrics, e.g. /users/42/orders to /users/{id}/orders.
+type router struct {
+	templates [][]string
+	normalize func(path string) string
+}
+
+func (rt *router) route(path string) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, template := range rt.templates {
+		if matchRoute(template, segments) {
+			return "/" + strings.Join(template, "/")
+		}
+	}
+	if rt.normalize != nil {
+		return rt.normalize(path)
+	}
+	return NormalizeIDs(path)
+}
+
+func matchRoute(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+	for i, t := range template {
+		if t != segments[i] && !isRouteParam(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func isRouteParam(segment string) bool {
+	return len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}'
+}
+
+// NormalizeIDs replaces the path segments that look like identifiers with
+// {id}: segments containing a digit and made of hex digits and dashes, such
+// as numbers and UUIDs, or of letters and digits past a prefix such as ord_.
+// It labels the paths no route template matches unless WithRouteNormalizer
+// says otherwise.
+func NormalizeIDs(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if looksLikeID(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func looksLikeID(segment string) bool {
+	prefixed := false
+	if i := strings.IndexByte(segment, '_'); i >= 0 {
+		segment, prefixed = segment[i+1:], true
+	}
+	digit := false
+	for _, c := range segment {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'a' && c <= 'f', c >= 'A' && c <= 'F', c == '-':
+		case prefixed && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'):
+		default:
+			return false
+		}
+	}
+	return digit
+}
+
+type routeKey struct{}
+
+// routeFor returns the route label of the call ctx belongs to, empty when
+// the client has no routes configured.
+func routeFor(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey{}).(string)
+	return route
+}
@@ -0,0 +1,77 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestRouteLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	var slow []metahttp.SlowRequest
+	client := metahttp.NewClient(server.URL+"/v1", logger, 5*time.Second,
+		metahttp.WithRouteTemplates("/users/{id}", "/users/{id}/orders/{order}"),
+		metahttp.WithSlowRequestThreshold(time.Nanosecond, func(s metahttp.SlowRequest) { slow = append(slow, s) }),
+	)
+
+	for path, want := range map[string]string{
+		"/users/alice?expand=1":        "/users/{id}",
+		"users/42/orders/ord_9Zk2":     "/users/{id}/orders/{order}",
+		"/users/42/wallet":             "/users/{id}/wallet",
+		"/payments/3fa85f64-5717-4562": "/payments/{id}",
+	} {
+		logs.Reset()
+		slow = nil
+		if _, err := client.Get(context.Background(), path, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		labeled := 0
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry["msg"] == "Initiating call" {
+				continue
+			}
+			labeled++
+			if entry["route"] != want {
+				t.Errorf("%s: %q logged with route %v, want %s", path, entry["msg"], entry["route"], want)
+			}
+		}
+		if labeled != 3 {
+			t.Errorf("%s: expected 3 labeled log lines, got %d", path, labeled)
+		}
+		if len(slow) != 1 || slow[0].Route != want {
+			t.Errorf("%s: unexpected slow requests %+v", path, slow)
+		}
+	}
+}
+
+func TestNormalizeIDs(t *testing.T) {
+	for path, want := range map[string]string{
+		"/v1/orders/12345":       "/v1/orders/{id}",
+		"/v2/oauth2/token":       "/v2/oauth2/token",
+		"/cards/pm_1NvXk2Lz/use": "/cards/{id}/use",
+		"/blobs/deadbeef42":      "/blobs/{id}",
+		"/feed":                  "/feed",
+	} {
+		if got := metahttp.NormalizeIDs(path); got != want {
+			t.Errorf("NormalizeIDs(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package metahttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+	"github.com/onmetahq/meta-http/pkg/utils"
+)
+
+// ScheduledRequest is a call made later by Requests.Schedule. It only holds
+// plain data so that a ScheduleStore can persist it.
+type ScheduledRequest struct {
+	// ID identifies the request in the store, generated when empty.
+	ID string
+	// At is when the call is made, set by Schedule.
+	At      time.Time
+	Method  string
+	Path    string
+	Headers map[string]string
+	// Body is sent as the JSON payload, no body when empty.
+	Body json.RawMessage
+	// Attempts counts the calls already made and failed with a transient
+	// error.
+	Attempts int
+}
+
+const (
+	// maxScheduledAttempts bounds the calls made for a scheduled request
+	// failing with transient errors, retried after scheduledRetryDelay
+	// doubling at every attempt.
+	maxScheduledAttempts = 5
+	scheduledRetryDelay  = time.Second
+)
+
+// ScheduleStore persists scheduled requests so that they survive restarts.
+type ScheduleStore interface {
+	Save(ctx context.Context, req ScheduledRequest) error
+	Delete(ctx context.Context, id string) error
+	// Pending returns every saved request not deleted yet.
+	Pending(ctx context.Context) ([]ScheduledRequest, error)
+}
+
+// MemoryScheduleStore is a process local ScheduleStore, scheduled requests
+// are lost on restart.
+type MemoryScheduleStore struct {
+	mu       sync.Mutex
+	requests map[string]ScheduledRequest
+}
+
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{requests: map[string]ScheduledRequest{}}
+}
+
+func (s *MemoryScheduleStore) Save(_ context.Context, req ScheduledRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[req.ID] = req
+	return nil
+}
+
+func (s *MemoryScheduleStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.requests, id)
+	return nil
+}
+
+func (s *MemoryScheduleStore) Pending(_ context.Context) ([]ScheduledRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make([]ScheduledRequest, 0, len(s.requests))
+	for _, req := range s.requests {
+		pending = append(pending, req)
+	}
+	return pending, nil
+}
+
+// scheduler makes the scheduled calls of a client from in-process timers.
+type scheduler struct {
+	store  ScheduleStore
+	onDone func(ScheduledRequest, *models.ResponseData, error)
+	logger Logger
+	// scrub holds the credential headers kept out of the store.
+	scrub headerSet
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func newScheduler(store ScheduleStore, onDone func(ScheduledRequest, *models.ResponseData, error), log Logger, scrub headerSet) *scheduler {
+	if store == nil {
+		store = NewMemoryScheduleStore()
+	}
+	return &scheduler{
+		store:  store,
+		onDone: onDone,
+		logger: log,
+		scrub:  scrub,
+		timers: map[string]*time.Timer{},
+	}
+}
+
+// Schedule makes the call described by req at at, or as soon as possible
+// when at has passed. Headers derived from ctx are captured now, as the
+// call is made outside of it, but for credentials such as Authorization,
+// which are not persisted: scheduled calls are authenticated by the signers
+// of the client. Calls failing with a transient error, e.g. a 503, are made
+// again with a backoff, up to 5 attempts. The returned ID identifies the
+// request in the ScheduleStore set with WithScheduleStore.
+func (c *client) Schedule(ctx context.Context, at time.Time, req ScheduledRequest) (string, error) {
+	if c.closed.Load() {
+		return "", models.ErrClientClosed
+	}
+	if req.ID == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		req.ID = hex.EncodeToString(b)
+	}
+	req.At = at
+	headers := utils.FetchHeadersFromContext(ctx)
+	for k, v := range req.Headers {
+		headers[k] = v
+	}
+	for k := range headers {
+		if c.scheduler.scrub[http.CanonicalHeaderKey(k)] {
+			delete(headers, k)
+		}
+	}
+	req.Headers = headers
+
+	if err := c.scheduler.store.Save(ctx, req); err != nil {
+		return "", err
+	}
+	c.scheduler.start(c, req)
+	return req.ID, nil
+}
+
+// resume starts the requests left in the store by a previous process.
+func (s *scheduler) resume(ctx context.Context, c *client) {
+	pending, err := s.store.Pending(ctx)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Loading scheduled requests failed", slog.Any("error", err.Error()))
+		return
+	}
+	for _, req := range pending {
+		s.start(c, req)
+	}
+}
+
+func (s *scheduler) start(c *client, req ScheduledRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.closed.Load() {
+		return
+	}
+	if _, ok := s.timers[req.ID]; ok {
+		return
+	}
+	s.timers[req.ID] = time.AfterFunc(time.Until(req.At), func() {
+		s.run(c, req)
+	})
+}
+
+func (s *scheduler) run(c *client, req ScheduledRequest) {
+	ctx := context.Background()
+	var body interface{}
+	if len(req.Body) > 0 {
+		body = req.Body
+	}
+	res, err := c.Do(ctx, req.Method, req.Path, req.Headers, body, nil)
+
+	s.mu.Lock()
+	delete(s.timers, req.ID)
+	s.mu.Unlock()
+	if errors.Is(err, models.ErrClientClosed) {
+		// Left in the store for the next client to resume.
+		return
+	}
+	if err != nil {
+		s.logger.WarnContext(ctx, "Scheduled call failed",
+			slog.String("id", req.ID),
+			slog.String("path", req.Path),
+			slog.Int("attempt", req.Attempts+1),
+			slog.Any("error", err.Error()),
+		)
+		if req.Attempts+1 < maxScheduledAttempts && transientError(err) {
+			req.Attempts++
+			req.At = time.Now().Add(scheduledRetryDelay << (req.Attempts - 1))
+			if err := s.store.Save(ctx, req); err != nil {
+				s.logger.WarnContext(ctx, "Saving scheduled request failed", slog.String("id", req.ID), slog.Any("error", err.Error()))
+			}
+			s.start(c, req)
+			return
+		}
+	}
+
+	if err := s.store.Delete(ctx, req.ID); err != nil {
+		s.logger.WarnContext(ctx, "Deleting scheduled request failed", slog.String("id", req.ID), slog.Any("error", err.Error()))
+	}
+	if s.onDone != nil {
+		s.onDone(req, res, err)
+	}
+}
+
+// transientError reports whether a call failing with err may succeed when
+// made again: connection errors, timeouts, 5xx, 408 and 429.
+func transientError(err error) bool {
+	switch models.CategoryOf(err) {
+	case models.CategoryConnection, models.CategoryDNS, models.CategoryTimeout, models.CategoryHTTP5xx:
+		return true
+	case models.CategoryHTTP4xx:
+		var errRes *models.HttpClientErrorResponse
+		return errors.As(err, &errRes) &&
+			(errRes.StatusCode == http.StatusRequestTimeout || errRes.StatusCode == http.StatusTooManyRequests)
+	}
+	return false
+}
+
+// stop cancels the timers of every scheduled call not started yet.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+}
@@ -0,0 +1,162 @@
+package metahttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestSchedule(t *testing.T) {
+	type call struct {
+		at       time.Time
+		path     string
+		tenantID string
+		body     string
+	}
+	calls := make(chan call, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		calls <- call{at: time.Now(), path: r.URL.Path, tenantID: r.Header.Get(string(models.TenantID)), body: string(b)}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := metahttp.NewMemoryScheduleStore()
+	// Left over by a previous process.
+	store.Save(context.Background(), metahttp.ScheduledRequest{ID: "old", At: time.Now().Add(-time.Minute), Method: http.MethodPost, Path: "/settlements/old/retry"})
+
+	done := make(chan *models.ResponseData, 2)
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithScheduleStore(store, func(req metahttp.ScheduledRequest, res *models.ResponseData, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		done <- res
+	}))
+
+	select {
+	case c := <-calls:
+		if c.path != "/settlements/old/retry" {
+			t.Errorf("unexpected resumed call %+v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending request was not resumed")
+	}
+	<-done
+
+	ctx := context.WithValue(context.Background(), models.TenantID, "tenant-1")
+	scheduled := time.Now()
+	body, _ := json.Marshal(map[string]string{"settlement": "stl_1"})
+	id, err := client.Schedule(ctx, scheduled.Add(50*time.Millisecond), metahttp.ScheduledRequest{
+		Method: http.MethodPost,
+		Path:   "/settlements/stl_1/retry",
+		Body:   body,
+	})
+	if err != nil || id == "" {
+		t.Fatalf("schedule failed: %q, %v", id, err)
+	}
+	if pending, _ := store.Pending(context.Background()); len(pending) != 1 || pending[0].ID != id {
+		t.Errorf("expected the request to be persisted, got %+v", pending)
+	}
+
+	select {
+	case c := <-calls:
+		if c.at.Sub(scheduled) < 50*time.Millisecond {
+			t.Errorf("call made too early, after %s", c.at.Sub(scheduled))
+		}
+		if c.tenantID != "tenant-1" || c.body != string(body) {
+			t.Errorf("unexpected call %+v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("scheduled call was not made")
+	}
+	if res := <-done; res == nil || res.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if pending, _ := store.Pending(context.Background()); len(pending) != 0 {
+		t.Errorf("expected completed requests to be deleted, got %+v", pending)
+	}
+}
+
+func TestScheduleRetriesAndKeepsCredentialsOut(t *testing.T) {
+	var calls atomic.Int32
+	auth := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		auth <- r.Header.Get("Authorization")
+		if calls.Add(1) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := metahttp.NewMemoryScheduleStore()
+	done := make(chan error, 1)
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithSigner(metahttp.SignerFunc(func(r *http.Request) error {
+			r.Header.Set("Authorization", "Bearer fresh")
+			return nil
+		})),
+		metahttp.WithScheduleStore(store, func(req metahttp.ScheduledRequest, res *models.ResponseData, err error) {
+			done <- err
+		}))
+
+	_, err := client.Schedule(context.Background(), time.Now(), metahttp.ScheduledRequest{
+		Method:  http.MethodPost,
+		Path:    "/payouts/po_1/retry",
+		Headers: map[string]string{"Authorization": "Bearer captured", "X-Reason": "manual"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := store.Pending(context.Background()); len(pending) != 1 || pending[0].Headers["Authorization"] != "" || pending[0].Headers["X-Reason"] != "manual" {
+		t.Errorf("unexpected persisted request %+v", pending)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected the retried call to succeed, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("scheduled call was not retried")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 calls, got %d", n)
+	}
+	if a := <-auth; a != "Bearer fresh" {
+		t.Errorf("call authenticated with %q", a)
+	}
+	if pending, _ := store.Pending(context.Background()); len(pending) != 0 {
+		t.Errorf("expected the request to be deleted, got %+v", pending)
+	}
+}
+
+func TestScheduleAfterClose(t *testing.T) {
+	store := metahttp.NewMemoryScheduleStore()
+	client := metahttp.NewClient("http://127.0.0.1:1", nil, time.Second, metahttp.WithoutLogging(), metahttp.WithScheduleStore(store, nil))
+
+	if _, err := client.Schedule(context.Background(), time.Now().Add(20*time.Millisecond), metahttp.ScheduledRequest{Method: http.MethodPost, Path: "/later"}); err != nil {
+		t.Fatal(err)
+	}
+	client.Close(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	if pending, _ := store.Pending(context.Background()); len(pending) != 1 {
+		t.Errorf("expected the request to be kept for the next client, got %+v", pending)
+	}
+	if _, err := client.Schedule(context.Background(), time.Now(), metahttp.ScheduledRequest{Method: http.MethodPost, Path: "/later"}); !errors.Is(err, models.ErrClientClosed) {
+		t.Errorf("schedule on a closed client: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/jsonschema"
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestResponseSchemaValidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/drifted" {
+			rw.Write([]byte(`{"id":"ord_1","amount":"12.50","status":"unknown"}`))
+			return
+		}
+		rw.Write([]byte(`{"id":"ord_1","amount":12.5,"status":"success"}`))
+	}))
+	defer server.Close()
+
+	schema := jsonschema.MustCompile([]byte(`{
+		"type": "object",
+		"required": ["id", "amount", "status"],
+		"properties": {
+			"id": {"type": "string"},
+			"amount": {"type": "number", "minimum": 0},
+			"status": {"enum": ["success", "failed"]}
+		}
+	}`))
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	var res map[string]any
+	if _, err := metaHttpClient.Get(context.Background(), "/order", map[string]string{}, &res, metahttp.WithResponseSchema(schema)); err != nil {
+		t.Fatal(err)
+	}
+	if res["id"] != "ord_1" {
+		t.Error("Response body is not as expected")
+	}
+
+	_, err := metaHttpClient.Get(context.Background(), "/drifted", map[string]string{}, &res, metahttp.WithResponseSchema(schema))
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected schema validation error, got: %v", err)
+	}
+	if len(schemaErr.Violations) != 2 {
+		t.Errorf("expected 2 violations, got: %v", schemaErr.Violations)
+	}
+}
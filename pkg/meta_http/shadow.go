@@ -0,0 +1,146 @@
+package metahttp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+const defaultShadowTimeout = 30 * time.Second
+
+// Shadow mirrors a share of production requests to a secondary endpoint,
+// e.g. a new vendor or a rewritten service, without affecting callers:
+// mirrored requests are sent asynchronously and their responses and errors
+// are only logged.
+type Shadow struct {
+	// BaseURL replaces the client's base URL for mirrored requests.
+	BaseURL string
+	// Percent of logical requests (0-100) to mirror. Retries are not
+	// mirrored.
+	Percent float64
+	// Timeout bounds each mirrored request, 30s by default.
+	Timeout time.Duration
+	// StripHeaders are removed from mirrored requests, e.g. production
+	// credentials the shadow endpoint must not see. Mirrored requests are
+	// not signed by the signers of the client.
+	StripHeaders []string
+}
+
+type shadowRoundTripper struct {
+	next      http.RoundTripper
+	shadow    *http.Transport
+	logger    Logger
+	basePath  string
+	target    *url.URL
+	percent   float64
+	timeout   time.Duration
+	stripKeys []string
+}
+
+func newShadowRoundTripper(cfg Shadow, baseUrl string, log Logger, next http.RoundTripper) http.RoundTripper {
+	target, err := url.Parse(cfg.BaseURL)
+	if err != nil || target.Host == "" {
+		log.ErrorContext(context.Background(), "Ignoring invalid shadow base url", slog.String("url", cfg.BaseURL))
+		return next
+	}
+	basePath := ""
+	if base, err := url.Parse(baseUrl); err == nil {
+		basePath = strings.TrimSuffix(base.EscapedPath(), "/")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultShadowTimeout
+	}
+	return &shadowRoundTripper{
+		next:      next,
+		shadow:    defaultPooledTransport(),
+		logger:    log,
+		basePath:  basePath,
+		target:    target,
+		percent:   cfg.Percent,
+		timeout:   timeout,
+		stripKeys: cfg.StripHeaders,
+	}
+}
+
+func (s *shadowRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if s.percent > 0 && rand.Float64()*100 < s.percent {
+		if mirror, cancel, ok := s.mirror(r); ok {
+			go s.send(mirror, cancel)
+		}
+	}
+	return s.next.RoundTrip(r)
+}
+
+// mirror copies r onto the shadow endpoint. Requests whose body cannot be
+// replayed are not mirrored.
+func (s *shadowRoundTripper) mirror(r *http.Request) (*http.Request, context.CancelFunc, bool) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), s.timeout)
+	mirror := r.Clone(ctx)
+	mirror.Host = ""
+	if r.Body != nil && r.Body != http.NoBody {
+		if r.GetBody == nil {
+			cancel()
+			return nil, nil, false
+		}
+		body, err := r.GetBody()
+		if err != nil {
+			cancel()
+			return nil, nil, false
+		}
+		mirror.Body = body
+	}
+
+	u := *r.URL
+	u.Scheme = s.target.Scheme
+	u.Host = s.target.Host
+	// Paths are joined escaped, so that encoded segments such as %2F are
+	// mirrored as sent.
+	u.RawPath = joinPaths(s.target.EscapedPath(), strings.TrimPrefix(r.URL.EscapedPath(), s.basePath))
+	if path, err := url.PathUnescape(u.RawPath); err == nil {
+		u.Path = path
+	}
+	mirror.URL = &u
+
+	for _, k := range s.stripKeys {
+		mirror.Header.Del(k)
+	}
+	return mirror, cancel, true
+}
+
+func (s *shadowRoundTripper) send(r *http.Request, cancel context.CancelFunc) {
+	defer cancel()
+
+	started := time.Now()
+	res, err := s.shadow.RoundTrip(r)
+	if err != nil {
+		loggerFor(r.Context(), s.logger).DebugContext(
+			r.Context(),
+			"Shadow call failed",
+			slog.String("path", r.URL.Path),
+			slog.String("host", r.URL.Host),
+			slog.Int64("duration", time.Since(started).Milliseconds()),
+			slog.Any("error", err.Error()),
+			slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))),
+		)
+		return
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	loggerFor(r.Context(), s.logger).DebugContext(
+		r.Context(),
+		"Shadow call ended",
+		slog.String("path", r.URL.Path),
+		slog.String("host", r.URL.Host),
+		slog.Int64("duration", time.Since(started).Milliseconds()),
+		slog.Int("status", res.StatusCode),
+		slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))),
+	)
+}
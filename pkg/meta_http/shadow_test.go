@@ -0,0 +1,74 @@
+package metahttp_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestShadowTraffic(t *testing.T) {
+	type mirrored struct {
+		path, auth, body string
+	}
+	received := make(chan mirrored, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- mirrored{path: r.URL.EscapedPath(), auth: r.Header.Get("Authorization"), body: string(body)}
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+
+	primary := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"ok":true}`))
+	}))
+	defer primary.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(primary.URL+"/v1", logger, 5*time.Second, metahttp.WithShadowTraffic(metahttp.Shadow{
+		BaseURL:      shadow.URL + "/v2",
+		Percent:      100,
+		StripHeaders: []string{"Authorization"},
+	}))
+
+	var res map[string]bool
+	_, err := client.Post(context.Background(), "/orders", map[string]string{"Authorization": "Bearer prod"}, map[string]int{"amount": 10}, &res)
+	if err != nil || !res["ok"] {
+		t.Fatalf("primary call should be unaffected by shadow failures, res: %v, err: %v", res, err)
+	}
+
+	select {
+	case m := <-received:
+		if m.path != "/v2/orders" {
+			t.Errorf("unexpected shadow path: %s", m.path)
+		}
+		if m.auth != "" {
+			t.Error("authorization header should be stripped")
+		}
+		if m.body != `{"amount":10}` {
+			t.Errorf("unexpected shadow body: %s", m.body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not mirrored")
+	}
+
+	// Encoded segments are mirrored as sent.
+	if _, err := client.Get(context.Background(), "/files/a%2Fb", nil, &res); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-received:
+		if m.path != "/v2/files/a%2Fb" {
+			t.Errorf("unexpected shadow path: %s", m.path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not mirrored")
+	}
+}
@@ -0,0 +1,103 @@
+package metahttp
+
+import (
+	"context"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// Priority orders calls for load shedding. The zero value is PriorityNormal.
+type Priority int
+
+const (
+	PriorityLow      Priority = -1
+	PriorityNormal   Priority = 0
+	PriorityCritical Priority = 1
+)
+
+type priorityKey struct{}
+
+// ContextWithPriority sets the priority of the calls made with ctx.
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority set by ContextWithPriority,
+// PriorityNormal when there is none.
+func PriorityFromContext(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
+// WithPriority sets the priority of a single call, replacing that of its
+// context.
+func WithPriority(priority Priority) CallOption {
+	return func(co *callOptions) {
+		co.priority = &priority
+	}
+}
+
+// ShedRequest describes a call about to be made, for a load shedder to
+// decide on.
+type ShedRequest struct {
+	Method string
+	Path   string
+	// Route is the route label of the call, empty without route templates.
+	Route    string
+	Caller   string
+	Priority Priority
+}
+
+// ResourceShedder sheds calls below a priority while the process is under
+// pressure, so that it sheds its own optional work before falling over.
+// Its Shed method is a predicate for WithLoadShedding.
+type ResourceShedder struct {
+	// MaxGoroutines and MaxHeapBytes are the thresholds past which the
+	// process is under pressure. Zero disables a threshold.
+	MaxGoroutines int
+	MaxHeapBytes  uint64
+	// Below is the priority from which calls are kept, PriorityNormal by
+	// default: only low priority calls are shed.
+	Below Priority
+	// Interval is how long a reading of the heap is reused, one second by
+	// default.
+	Interval time.Duration
+
+	mu     sync.Mutex
+	readAt time.Time
+	heap   uint64
+}
+
+// Shed reports whether the call must be shed.
+func (s *ResourceShedder) Shed(req ShedRequest) bool {
+	if req.Priority >= s.Below {
+		return false
+	}
+	if s.MaxGoroutines > 0 && runtime.NumGoroutine() > s.MaxGoroutines {
+		return true
+	}
+	return s.MaxHeapBytes > 0 && s.heapBytes() > s.MaxHeapBytes
+}
+
+// heapBytes reads the bytes of live and not yet swept heap objects, without
+// stopping the world as runtime.ReadMemStats does.
+func (s *ResourceShedder) heapBytes() uint64 {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.readAt) < interval {
+		return s.heap
+	}
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		s.heap = sample[0].Value.Uint64()
+	}
+	s.readAt = time.Now()
+	return s.heap
+}
@@ -0,0 +1,64 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestLoadShedding(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	var seen []metahttp.ShedRequest
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithLoadShedding(func(req metahttp.ShedRequest) bool {
+			seen = append(seen, req)
+			return req.Priority < metahttp.PriorityNormal
+		}))
+
+	low := metahttp.ContextWithPriority(metahttp.ContextWithCaller(context.Background(), "recommendations"), metahttp.PriorityLow)
+	_, err := client.Get(low, "/suggestions", nil, nil)
+	if !errors.Is(err, models.ErrLoadShed) || models.CategoryOf(err) != models.CategoryRequest || calls.Load() != 0 {
+		t.Errorf("low priority call: %v, %d sent", err, calls.Load())
+	}
+	if len(seen) != 1 || seen[0].Caller != "recommendations" || seen[0].Method != http.MethodGet || seen[0].Path != "/suggestions" {
+		t.Errorf("shed request = %+v", seen)
+	}
+	if _, err := client.Get(low, "/suggestions", nil, nil, metahttp.WithPriority(metahttp.PriorityCritical)); err != nil || calls.Load() != 1 {
+		t.Errorf("critical call: %v", err)
+	}
+	if _, err := client.Get(context.Background(), "/orders", nil, nil); err != nil || calls.Load() != 2 {
+		t.Errorf("normal call: %v", err)
+	}
+}
+
+func TestResourceShedder(t *testing.T) {
+	for _, shedder := range []*metahttp.ResourceShedder{
+		{MaxGoroutines: 1},
+		{MaxHeapBytes: 1},
+		{MaxGoroutines: 1, Below: metahttp.PriorityCritical},
+	} {
+		low := metahttp.ShedRequest{Priority: metahttp.PriorityLow}
+		normal := metahttp.ShedRequest{Priority: metahttp.PriorityNormal}
+		if !shedder.Shed(low) {
+			t.Errorf("%+v kept a low priority call under pressure", shedder)
+		}
+		if got, want := shedder.Shed(normal), shedder.Below > metahttp.PriorityNormal; got != want {
+			t.Errorf("%+v shed normal priority call: %v, want %v", shedder, got, want)
+		}
+	}
+	if relaxed := (&metahttp.ResourceShedder{MaxGoroutines: 1 << 20, MaxHeapBytes: 1 << 50}); relaxed.Shed(metahttp.ShedRequest{Priority: metahttp.PriorityLow}) {
+		t.Error("shed without pressure")
+	}
+}
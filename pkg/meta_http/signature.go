@@ -0,0 +1,349 @@
+package metahttp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer adds authentication material to an outgoing request. Implementations
+// may set headers on r but must not replace its body.
+type Signer interface {
+	Sign(r *http.Request) error
+}
+
+// SignerFunc adapts a plain function to the Signer interface.
+type SignerFunc func(r *http.Request) error
+
+func (f SignerFunc) Sign(r *http.Request) error {
+	return f(r)
+}
+
+type signingRoundTripper struct {
+	next    http.RoundTripper
+	signers []Signer
+	// skipCrossHost leaves redirect hops to other hosts unsigned so that
+	// credentials are not handed to them.
+	skipCrossHost bool
+}
+
+func (s signingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if s.skipCrossHost && crossHostRedirect(r) {
+		return s.next.RoundTrip(r)
+	}
+	req := r.Clone(r.Context())
+	for _, signer := range s.signers {
+		if err := signer.Sign(req); err != nil {
+			return nil, fmt.Errorf("signing request: %w", err)
+		}
+	}
+	return s.next.RoundTrip(req)
+}
+
+// SigningKey is the key material used by HTTPMessageSigner. Algorithm returns
+// the RFC 9421 algorithm name, e.g. "hmac-sha256" or "ed25519".
+type SigningKey interface {
+	Algorithm() string
+	Sign(data []byte) ([]byte, error)
+}
+
+type hmacSHA256Key []byte
+
+// NewHMACSHA256Key returns a "hmac-sha256" signing key for the shared secret.
+func NewHMACSHA256Key(secret []byte) SigningKey {
+	return hmacSHA256Key(secret)
+}
+
+func (k hmacSHA256Key) Algorithm() string {
+	return "hmac-sha256"
+}
+
+func (k hmacSHA256Key) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+type ed25519Key ed25519.PrivateKey
+
+// NewEd25519Key returns an "ed25519" signing key.
+func NewEd25519Key(key ed25519.PrivateKey) SigningKey {
+	return ed25519Key(key)
+}
+
+func (k ed25519Key) Algorithm() string {
+	return "ed25519"
+}
+
+func (k ed25519Key) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(k), data), nil
+}
+
+type ecdsaP256Key struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewECDSAP256Key returns an "ecdsa-p256-sha256" signing key.
+func NewECDSAP256Key(key *ecdsa.PrivateKey) SigningKey {
+	return ecdsaP256Key{key: key}
+}
+
+func (k ecdsaP256Key) Algorithm() string {
+	return "ecdsa-p256-sha256"
+}
+
+func (k ecdsaP256Key) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, k.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	// RFC 9421 requires the raw r||s encoding rather than ASN.1.
+	out := make([]byte, 64)
+	r.FillBytes(out[:32])
+	s.FillBytes(out[32:])
+	return out, nil
+}
+
+type rsaPSSKey struct {
+	key *rsa.PrivateKey
+}
+
+// NewRSAPSSKey returns an "rsa-pss-sha512" signing key.
+func NewRSAPSSKey(key *rsa.PrivateKey) SigningKey {
+	return rsaPSSKey{key: key}
+}
+
+func (k rsaPSSKey) Algorithm() string {
+	return "rsa-pss-sha512"
+}
+
+func (k rsaPSSKey) Sign(data []byte) ([]byte, error) {
+	digest := sha512.Sum512(data)
+	return rsa.SignPSS(rand.Reader, k.key, crypto.SHA512, digest[:], &rsa.PSSOptions{SaltLength: 64})
+}
+
+type rsaV15Key struct {
+	key *rsa.PrivateKey
+}
+
+// NewRSAV15Key returns an "rsa-v1_5-sha256" signing key.
+func NewRSAV15Key(key *rsa.PrivateKey) SigningKey {
+	return rsaV15Key{key: key}
+}
+
+func (k rsaV15Key) Algorithm() string {
+	return "rsa-v1_5-sha256"
+}
+
+func (k rsaV15Key) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, k.key, crypto.SHA256, digest[:])
+}
+
+// HTTPMessageSigner signs requests following RFC 9421 (HTTP Message
+// Signatures), emitting the Signature-Input and Signature headers.
+//
+// Components are derived component names ("@method", "@authority",
+// "@target-uri", "@path", "@query", "@scheme", "@request-target") or
+// lowercase header names. When "content-digest" is listed and the request
+// does not carry that header yet, it is computed (RFC 9530, sha-256) from the
+// request body.
+type HTTPMessageSigner struct {
+	Label            string
+	KeyID            string
+	Key              SigningKey
+	Components       []string
+	IncludeAlgorithm bool
+	Expires          time.Duration
+	Nonce            func() string
+	// Nonces, when set, is used in place of Nonce, e.g. a NonceGenerator
+	// guaranteeing nonces are never reused.
+	Nonces NonceSource
+	Tag    string
+	Clock  func() time.Time
+}
+
+// NewHTTPMessageSigner returns a signer labelled "sig1" covering the given
+// components, defaulting to "@method", "@target-uri" and "content-digest".
+func NewHTTPMessageSigner(keyID string, key SigningKey, components ...string) *HTTPMessageSigner {
+	if len(components) == 0 {
+		components = []string{"@method", "@target-uri", "content-digest"}
+	}
+	return &HTTPMessageSigner{
+		Label:            "sig1",
+		KeyID:            keyID,
+		Key:              key,
+		Components:       components,
+		IncludeAlgorithm: true,
+	}
+}
+
+func (s *HTTPMessageSigner) Sign(r *http.Request) error {
+	if s.Key == nil {
+		return fmt.Errorf("http message signature: missing key")
+	}
+
+	for _, c := range s.Components {
+		if c == "content-digest" && r.Header.Get("Content-Digest") == "" {
+			digest, err := contentDigest(r)
+			if err != nil {
+				return err
+			}
+			r.Header.Set("Content-Digest", digest)
+		}
+	}
+
+	nonce := ""
+	switch {
+	case s.Nonces != nil:
+		var err error
+		if nonce, err = s.Nonces.Nonce(r.Context()); err != nil {
+			return fmt.Errorf("http message signature: %w", err)
+		}
+	case s.Nonce != nil:
+		nonce = s.Nonce()
+	}
+	params := s.signatureParams(nonce)
+	base, err := signatureBase(r, s.Components, params)
+	if err != nil {
+		return err
+	}
+
+	sig, err := s.Key.Sign([]byte(base))
+	if err != nil {
+		return fmt.Errorf("http message signature: %w", err)
+	}
+
+	label := s.Label
+	if label == "" {
+		label = "sig1"
+	}
+	r.Header.Set("Signature-Input", label+"="+params)
+	r.Header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+func (s *HTTPMessageSigner) signatureParams(nonce string) string {
+	now := time.Now
+	if s.Clock != nil {
+		now = s.Clock
+	}
+	created := now()
+
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, c := range s.Components {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Quote(c))
+	}
+	b.WriteByte(')')
+	b.WriteString(";created=" + strconv.FormatInt(created.Unix(), 10))
+	if s.Expires > 0 {
+		b.WriteString(";expires=" + strconv.FormatInt(created.Add(s.Expires).Unix(), 10))
+	}
+	if nonce != "" {
+		b.WriteString(";nonce=" + strconv.Quote(nonce))
+	}
+	if s.IncludeAlgorithm {
+		b.WriteString(";alg=" + strconv.Quote(s.Key.Algorithm()))
+	}
+	if s.KeyID != "" {
+		b.WriteString(";keyid=" + strconv.Quote(s.KeyID))
+	}
+	if s.Tag != "" {
+		b.WriteString(";tag=" + strconv.Quote(s.Tag))
+	}
+	return b.String()
+}
+
+func signatureBase(r *http.Request, components []string, params string) (string, error) {
+	var b strings.Builder
+	for _, c := range components {
+		value, err := componentValue(r, c)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(strconv.Quote(c) + ": " + value + "\n")
+	}
+	b.WriteString("\"@signature-params\": " + params)
+	return b.String(), nil
+}
+
+func componentValue(r *http.Request, component string) (string, error) {
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+
+	switch component {
+	case "@method":
+		return strings.ToUpper(r.Method), nil
+	case "@authority":
+		return strings.ToLower(host), nil
+	case "@scheme":
+		return strings.ToLower(r.URL.Scheme), nil
+	case "@target-uri":
+		return strings.ToLower(r.URL.Scheme) + "://" + strings.ToLower(host) + r.URL.RequestURI(), nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		path := r.URL.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		return path, nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("http message signature: unsupported derived component %q", component)
+	}
+	values := r.Header.Values(component)
+	if len(values) == 0 {
+		return "", fmt.Errorf("http message signature: header %q not present", component)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+func contentDigest(r *http.Request) (string, error) {
+	var body []byte
+	if r.GetBody != nil {
+		rc, err := r.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		if body, err = io.ReadAll(rc); err != nil {
+			return "", err
+		}
+	} else if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return "", err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":", nil
+}
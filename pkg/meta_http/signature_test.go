@@ -0,0 +1,98 @@
+package metahttp_test
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// Test vector from RFC 9421 Appendix B.2.5.
+func TestHTTPMessageSignerRFCVector(t *testing.T) {
+	secret, _ := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	signer := metahttp.NewHTTPMessageSigner("test-shared-secret", metahttp.NewHMACSHA256Key(secret), "date", "@authority", "content-type")
+	signer.Label = "sig-b25"
+	signer.IncludeAlgorithm = false
+	signer.Clock = func() time.Time { return time.Unix(1618884473, 0) }
+
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := signer.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Signature-Input"); got != `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"` {
+		t.Errorf("unexpected signature input: %s", got)
+	}
+	if got := req.Header.Get("Signature"); got != "sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:" {
+		t.Errorf("unexpected signature: %s", got)
+	}
+}
+
+func TestClientWithSigner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Signature") == "" || req.Header.Get("Content-Digest") == "" {
+			rw.WriteHeader(http.StatusUnauthorized)
+		}
+		rw.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	signer := metahttp.NewHTTPMessageSigner("key-1", metahttp.NewHMACSHA256Key([]byte("secret")))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second, metahttp.WithSigner(signer))
+
+	var res map[string]any
+	_, err := metaHttpClient.Post(context.Background(), "/test", map[string]string{}, map[string]string{"hello": "world"}, &res)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestSignaturesRegeneratedPerAttempt(t *testing.T) {
+	var timestamps, signatures []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		timestamps = append(timestamps, r.Header.Get("X-Timestamp"))
+		signatures = append(signatures, r.Header.Get("Signature"))
+		if len(timestamps) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var now int64 = 1700000000
+	stamp := metahttp.SignerFunc(func(r *http.Request) error {
+		now++
+		r.Header.Add("X-Timestamp", strconv.FormatInt(now, 10))
+		return nil
+	})
+	signer := metahttp.NewHTTPMessageSigner("key-1", metahttp.NewHMACSHA256Key([]byte("secret")), "x-timestamp", "content-digest")
+	client := metahttp.NewClientWithRetry(server.URL, nil, 5*time.Second, models.Retry{
+		MaxRetries:        3,
+		DelayBetweenRetry: time.Millisecond,
+		Validator:         func(status int) bool { return status < 500 },
+	}, metahttp.WithoutLogging(), metahttp.WithSigner(stamp), metahttp.WithSigner(signer))
+
+	if _, err := client.Post(context.Background(), "/payouts", nil, map[string]int{"amount": 10}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Each attempt carries a single, fresh timestamp and its own signature.
+	if strings.Join(timestamps, ",") != "1700000001,1700000002,1700000003" {
+		t.Errorf("expected a timestamp per attempt, got %q", timestamps)
+	}
+	if signatures[0] == "" || signatures[0] == signatures[1] || signatures[1] == signatures[2] {
+		t.Errorf("expected a signature per attempt, got %q", signatures)
+	}
+}
@@ -0,0 +1,69 @@
+package metahttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+type sizeCounterKey struct{}
+
+// sizeCounter adds up the body bytes of the attempts of one call.
+type sizeCounter struct {
+	sent, received atomic.Int64
+}
+
+func sizeCounterFor(ctx context.Context) *sizeCounter {
+	counter, _ := ctx.Value(sizeCounterKey{}).(*sizeCounter)
+	return counter
+}
+
+// sizeRoundTripper counts the request and response body bytes crossing the
+// wire, i.e. compressed, for the call they belong to and the client stats.
+// Headers are not counted.
+type sizeRoundTripper struct {
+	next  http.RoundTripper
+	stats *clientStats
+}
+
+func (s sizeRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	counter := sizeCounterFor(r.Context())
+	if r.Body != nil && r.Body != http.NoBody {
+		// RoundTrippers must not modify the request they are given.
+		r = r.WithContext(r.Context())
+		r.Body = &countingBody{ReadCloser: r.Body, total: &s.stats.bytesSent, call: counterField(counter, true)}
+	}
+	res, err := s.next.RoundTrip(r)
+	if err != nil || res.Body == nil || res.Body == http.NoBody {
+		return res, err
+	}
+	res.Body = &countingBody{ReadCloser: res.Body, total: &s.stats.bytesReceived, call: counterField(counter, false)}
+	return res, nil
+}
+
+func counterField(counter *sizeCounter, sent bool) *atomic.Int64 {
+	switch {
+	case counter == nil:
+		return nil
+	case sent:
+		return &counter.sent
+	}
+	return &counter.received
+}
+
+type countingBody struct {
+	io.ReadCloser
+	total, call *atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.total.Add(int64(n))
+		if b.call != nil {
+			b.call.Add(int64(n))
+		}
+	}
+	return n, err
+}
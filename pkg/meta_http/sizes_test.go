@@ -0,0 +1,59 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestSizeAccounting(t *testing.T) {
+	payload := `{"name":"` + strings.Repeat("meta", 500) + `"}`
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(payload))
+	gz.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		rw.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/compressed" {
+			rw.Header().Set("Content-Encoding", "gzip")
+			rw.Write(compressed.Bytes())
+			return
+		}
+		rw.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithAcceptEncoding(metahttp.EncodingGzip))
+	var res merchant
+	data, err := client.Post(context.Background(), "/plain", nil, map[string]string{"name": "meta"}, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.BytesSent != int64(len(`{"name":"meta"}`)) || data.BytesReceived != int64(len(payload)) {
+		t.Errorf("plain: sent %d, received %d", data.BytesSent, data.BytesReceived)
+	}
+
+	data, err = client.Get(context.Background(), "/compressed", nil, &res)
+	if err != nil || len(res.Name) != 2000 {
+		t.Fatal(err)
+	}
+	if data.BytesSent != 0 || data.BytesReceived != int64(compressed.Len()) {
+		t.Errorf("compressed: sent %d, received %d, want %d on the wire", data.BytesSent, data.BytesReceived, compressed.Len())
+	}
+
+	stats := client.Stats()
+	if stats.BytesSent != 15 || stats.BytesReceived != int64(len(payload)+compressed.Len()) {
+		t.Errorf("stats: sent %d, received %d", stats.BytesSent, stats.BytesReceived)
+	}
+}
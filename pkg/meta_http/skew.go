@@ -0,0 +1,76 @@
+package metahttp
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// ClockSkew describes a response whose Date header is off from the local
+// clock by the threshold of WithClockSkewThreshold or more.
+type ClockSkew struct {
+	Method string
+	URL    string
+	// Route is the route label of the call, empty without route templates.
+	Route      string
+	ServerTime time.Time
+	// Skew is positive when the server clock is ahead of the local one.
+	Skew time.Duration
+}
+
+// clockSkew estimates how far the server clock is ahead of the local one
+// from the Date header of res, for a call sent at sent and answered at
+// received. Date has a one second precision and was set somewhere in
+// between, so only the distance out of [sent-1s, received] counts as skew.
+// Responses served from the cache carry the Date of the original response
+// and tell nothing.
+func clockSkew(res *http.Response, sent, received time.Time) (time.Duration, time.Time, bool) {
+	value := res.Header.Get("Date")
+	if value == "" {
+		return 0, time.Time{}, false
+	}
+	if status := res.Header.Get(CacheStatusHeader); status == CacheHit || status == CacheStale {
+		return 0, time.Time{}, false
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	switch {
+	case date.Before(sent.Add(-time.Second)):
+		return date.Sub(sent.Add(-time.Second)), date, true
+	case date.After(received):
+		return date.Sub(received), date, true
+	}
+	return 0, date, true
+}
+
+// checkClockSkew warns about a skew reaching the threshold of the client.
+func (c *client) checkClockSkew(req *http.Request, date time.Time, skew time.Duration) {
+	if c.skewThreshold <= 0 || (skew < c.skewThreshold && -skew < c.skewThreshold) {
+		return
+	}
+	ctx := req.Context()
+	route := routeFor(ctx)
+	loggerFor(ctx, c.logger).WarnContext(
+		ctx,
+		"Clock skew with the upstream",
+		slog.String("path", req.URL.Path),
+		slog.String("host", req.URL.Host),
+		slog.String("route", route),
+		slog.Int64("skew", skew.Milliseconds()),
+		slog.String("server_time", date.Format(time.RFC3339)),
+		slog.String(string(models.RequestID), req.Header.Get(string(models.RequestID))),
+	)
+	if c.onSkew != nil {
+		c.onSkew(ClockSkew{
+			Method:     req.Method,
+			URL:        req.URL.Redacted(),
+			Route:      route,
+			ServerTime: date,
+			Skew:       skew,
+		})
+	}
+}
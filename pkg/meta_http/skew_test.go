@@ -0,0 +1,55 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestClockSkew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ahead":
+			rw.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		case "/behind":
+			rw.Header().Set("Date", time.Now().Add(-90*time.Second).UTC().Format(http.TimeFormat))
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	var skews []metahttp.ClockSkew
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithClockSkewThreshold(time.Minute, func(s metahttp.ClockSkew) {
+		skews = append(skews, s)
+	}))
+	ctx := context.Background()
+
+	data, err := client.Get(ctx, "/synced", nil, nil)
+	if err != nil || data.ClockSkew != 0 {
+		t.Fatalf("expected no skew with the server's own Date, got %v, %v", data, err)
+	}
+	data, err = client.Get(ctx, "/ahead", nil, nil)
+	if err != nil || data.ClockSkew < 59*time.Minute || data.ClockSkew > time.Hour {
+		t.Fatalf("expected the server to be an hour ahead, got %v, %v", data, err)
+	}
+	data, err = client.Get(ctx, "/behind", nil, nil)
+	if err != nil || data.ClockSkew > -88*time.Second || data.ClockSkew < -91*time.Second {
+		t.Fatalf("expected the server to be 90s behind, got %v, %v", data, err)
+	}
+
+	if len(skews) != 2 || skews[0].URL != server.URL+"/ahead" || skews[1].Skew != data.ClockSkew {
+		t.Errorf("unexpected skews %+v", skews)
+	}
+	if strings.Count(logs.String(), `"msg":"Clock skew with the upstream"`) != 2 {
+		t.Errorf("expected two warnings: %s", logs.String())
+	}
+}
@@ -0,0 +1,107 @@
+package metahttp
+
+import (
+	"slices"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// latencySamples is the number of recent calls latency percentiles are
+// computed over.
+const latencySamples = 1024
+
+// EndpointStats describes the calls made through an Endpoint.
+type EndpointStats struct {
+	Calls  int64 `json:"calls"`
+	Errors int64 `json:"errors"`
+	// SuccessRate is the share of calls without errors, 1 before any call.
+	SuccessRate float64 `json:"success_rate"`
+	// P50, P95 and P99 are latency percentiles over the last 1024 calls.
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+	// SLO is set for endpoints defined with one.
+	SLO *SLOStats `json:"slo,omitempty"`
+}
+
+// SLOStats tracks an endpoint against its SLO since the client was created.
+type SLOStats struct {
+	Target float64 `json:"target"`
+	Good   int64   `json:"good"`
+	Bad    int64   `json:"bad"`
+	// BudgetRemaining is the share of the error budget, the bad calls the
+	// target allows, left: 1 when no call was bad, 0 when the allowed
+	// number of bad calls is reached and negative past it.
+	BudgetRemaining float64 `json:"budget_remaining"`
+}
+
+type endpointStats struct {
+	slo     *SLO
+	calls   int64
+	errors  int64
+	good    int64
+	bad     int64
+	samples [latencySamples]time.Duration
+}
+
+// recordEndpoint must be called with the mutex of s held.
+func (s *clientStats) recordEndpoint(ep *endpoint, d time.Duration, err error) {
+	es, ok := s.endpoints[ep.name]
+	if !ok {
+		es = &endpointStats{slo: ep.slo}
+		s.endpoints[ep.name] = es
+	}
+	es.samples[es.calls%latencySamples] = d
+	es.calls++
+	if err != nil {
+		es.errors++
+	}
+	if es.slo == nil {
+		return
+	}
+	if burnsBudget(err) || (es.slo.Latency > 0 && d > es.slo.Latency) {
+		es.bad++
+	} else {
+		es.good++
+	}
+}
+
+// burnsBudget reports whether err is the upstream's fault rather than the
+// caller's.
+func burnsBudget(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch models.CategoryOf(err) {
+	case models.CategoryHTTP4xx, models.CategoryRequest, models.CategoryCanceled:
+		return false
+	}
+	return true
+}
+
+func (es *endpointStats) snapshot() EndpointStats {
+	stats := EndpointStats{Calls: es.calls, Errors: es.errors, SuccessRate: 1}
+	if es.calls > 0 {
+		stats.SuccessRate = float64(es.calls-es.errors) / float64(es.calls)
+		samples := slices.Clone(es.samples[:min(es.calls, latencySamples)])
+		slices.Sort(samples)
+		stats.P50 = percentile(samples, 0.50)
+		stats.P95 = percentile(samples, 0.95)
+		stats.P99 = percentile(samples, 0.99)
+	}
+	if es.slo != nil {
+		stats.SLO = &SLOStats{Target: es.slo.Target, Good: es.good, Bad: es.bad, BudgetRemaining: 1}
+		if total := es.good + es.bad; total > 0 {
+			allowed := (1 - es.slo.Target) * float64(total)
+			stats.SLO.BudgetRemaining = 1 - float64(es.bad)/allowed
+		}
+	}
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted samples, nearest rank.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
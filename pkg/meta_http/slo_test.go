@@ -0,0 +1,73 @@
+package metahttp_test
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestEndpointSLOStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("outcome") {
+		case "fail":
+			rw.WriteHeader(http.StatusBadGateway)
+		case "missing":
+			rw.WriteHeader(http.StatusNotFound)
+		case "slow":
+			time.Sleep(80 * time.Millisecond)
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	endpoints, err := metahttp.NewEndpoints(client,
+		metahttp.Endpoint{
+			Name:   "quote",
+			Method: http.MethodGet,
+			Path:   "/quotes",
+			SLO:    &metahttp.SLO{Target: 0.9, Latency: 50 * time.Millisecond},
+		},
+		metahttp.Endpoint{Name: "rates", Method: http.MethodGet, Path: "/rates"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	outcomes := []string{"ok", "ok", "ok", "ok", "ok", "ok", "ok", "ok", "fail", "missing", "slow"}
+	for _, outcome := range outcomes {
+		endpoints.Call(ctx, "quote", nil, nil, nil, metahttp.WithQuery(url.Values{"outcome": {outcome}}))
+	}
+	endpoints.Call(ctx, "rates", nil, nil, nil)
+	client.Get(ctx, "/other", nil, nil)
+
+	stats := client.Stats().Endpoints
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for the 2 endpoints only, got %v", stats)
+	}
+	quote := stats["quote"]
+	if quote.Calls != 11 || quote.Errors != 2 || math.Abs(quote.SuccessRate-9.0/11) > 1e-9 {
+		t.Errorf("unexpected counts %+v", quote)
+	}
+	if quote.P99 < 80*time.Millisecond || quote.P50 >= 50*time.Millisecond {
+		t.Errorf("unexpected latencies p50 %s, p99 %s", quote.P50, quote.P99)
+	}
+	// The 404 is the caller's fault: only the 502 and the slow call are bad.
+	slo := quote.SLO
+	if slo == nil || slo.Good != 9 || slo.Bad != 2 {
+		t.Fatalf("unexpected SLO stats %+v", slo)
+	}
+	if want := 1 - 2/(0.1*11); math.Abs(slo.BudgetRemaining-want) > 1e-9 {
+		t.Errorf("expected %v of the budget left, got %v", want, slo.BudgetRemaining)
+	}
+	if rates := stats["rates"]; rates.Calls != 1 || rates.SuccessRate != 1 || rates.SLO != nil {
+		t.Errorf("unexpected stats %+v", rates)
+	}
+}
@@ -0,0 +1,161 @@
+package metahttp
+
+import (
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// Timing breaks down where the time of a request attempt went. Phases that
+// did not happen, e.g. dialing on a reused connection, are zero.
+type Timing struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// TimeToFirstByte runs from writing the request to the first response
+	// byte.
+	TimeToFirstByte time.Duration
+	// Total runs until the response body was closed.
+	Total  time.Duration
+	Reused bool
+}
+
+// SlowRequest describes an attempt that exceeded the slow-request threshold.
+type SlowRequest struct {
+	Method string
+	URL    string
+	// Route is the route label of the call, empty without route templates.
+	Route string
+	// Status is 0 when the attempt failed without a response.
+	Status int
+	Err    error
+	Timing Timing
+}
+
+type slowRoundTripper struct {
+	next      http.RoundTripper
+	threshold time.Duration
+	onSlow    func(SlowRequest)
+	logger    Logger
+}
+
+func (s slowRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	t := &attemptTiming{started: time.Now()}
+	res, err := s.next.RoundTrip(r.WithContext(httptrace.WithClientTrace(r.Context(), t.trace())))
+	if err != nil {
+		s.check(r, 0, err, t.finish())
+		return res, err
+	}
+	res.Body = &timedBody{ReadCloser: res.Body, done: func() {
+		s.check(r, res.StatusCode, nil, t.finish())
+	}}
+	return res, nil
+}
+
+func (s slowRoundTripper) check(r *http.Request, status int, err error, timing Timing) {
+	if timing.Total < s.threshold {
+		return
+	}
+	attrs := []any{
+		slog.String("path", r.URL.Path),
+		slog.String("host", r.URL.Host),
+		slog.Int64("duration", timing.Total.Milliseconds()),
+		slog.Int64("dns", timing.DNS.Milliseconds()),
+		slog.Int64("connect", timing.Connect.Milliseconds()),
+		slog.Int64("tls", timing.TLSHandshake.Milliseconds()),
+		slog.Int64("ttfb", timing.TimeToFirstByte.Milliseconds()),
+		slog.Bool("reused", timing.Reused),
+		slog.Int("status", status),
+		slog.String(string(models.RequestID), r.Header.Get(string(models.RequestID))),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err.Error()))
+	}
+	route := routeFor(r.Context())
+	if route != "" {
+		attrs = append(attrs, slog.String("route", route))
+	}
+	loggerFor(r.Context(), s.logger).WarnContext(r.Context(), "Slow call", attrs...)
+
+	if s.onSlow != nil {
+		s.onSlow(SlowRequest{
+			Method: r.Method,
+			URL:    r.URL.Redacted(),
+			Route:  route,
+			Status: status,
+			Err:    err,
+			Timing: timing,
+		})
+	}
+}
+
+type attemptTiming struct {
+	mu                      sync.Mutex
+	started                 time.Time
+	dnsStart, dnsDone       time.Time
+	connStart, connDone     time.Time
+	tlsStart, tlsDone       time.Time
+	wroteRequest, firstByte time.Time
+	reused                  bool
+}
+
+func (t *attemptTiming) trace() *httptrace.ClientTrace {
+	now := func(dst *time.Time) {
+		t.mu.Lock()
+		*dst = time.Now()
+		t.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { now(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { now(&t.dnsDone) },
+		ConnectStart:         func(string, string) { now(&t.connStart) },
+		ConnectDone:          func(string, string, error) { now(&t.connDone) },
+		TLSHandshakeStart:    func() { now(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { now(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { now(&t.wroteRequest) },
+		GotFirstResponseByte: func() { now(&t.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.mu.Unlock()
+		},
+	}
+}
+
+func (t *attemptTiming) finish() Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	between := func(start, end time.Time) time.Duration {
+		if start.IsZero() || end.IsZero() {
+			return 0
+		}
+		return end.Sub(start)
+	}
+	return Timing{
+		DNS:             between(t.dnsStart, t.dnsDone),
+		Connect:         between(t.connStart, t.connDone),
+		TLSHandshake:    between(t.tlsStart, t.tlsDone),
+		TimeToFirstByte: between(t.wroteRequest, t.firstByte),
+		Total:           time.Since(t.started),
+		Reused:          t.reused,
+	}
+}
+
+// timedBody calls done once when the body is closed.
+type timedBody struct {
+	io.ReadCloser
+	done func()
+	once sync.Once
+}
+
+func (b *timedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,51 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestSlowRequestThreshold(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(60 * time.Millisecond)
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	var slow []metahttp.SlowRequest
+	client := metahttp.NewClient(server.URL, logger, 5*time.Second, metahttp.WithSlowRequestThreshold(40*time.Millisecond, func(sr metahttp.SlowRequest) {
+		slow = append(slow, sr)
+	}))
+
+	if _, err := client.Get(context.Background(), "/fast", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(context.Background(), "/slow", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(slow) != 1 {
+		t.Fatalf("expected one slow request, got %d", len(slow))
+	}
+	if slow[0].URL != server.URL+"/slow" || slow[0].Status != http.StatusNoContent {
+		t.Errorf("unexpected slow request: %+v", slow[0])
+	}
+	if slow[0].Timing.TimeToFirstByte < 60*time.Millisecond || slow[0].Timing.Total < slow[0].Timing.TimeToFirstByte {
+		t.Errorf("unexpected timing: %+v", slow[0].Timing)
+	}
+	if !strings.Contains(logs.String(), `"msg":"Slow call"`) || strings.Count(logs.String(), "Slow call") != 1 {
+		t.Errorf("expected a single warning: %s", logs.String())
+	}
+}
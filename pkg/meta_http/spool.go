@@ -0,0 +1,109 @@
+package metahttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+const defaultSpoolThreshold = 4 << 20
+
+// SpooledBody is a response target that keeps bodies up to Threshold bytes
+// in memory and streams larger ones to a temporary file, exposing either as
+// an io.ReadSeeker. Close removes the temporary file.
+//
+//	report := metahttp.NewSpooledBody(16 << 20)
+//	defer report.Close()
+//	_, err := client.Get(ctx, "/reports/daily", nil, report)
+type SpooledBody struct {
+	Threshold int64
+	// Dir is where temporary files are created, os.TempDir() when empty.
+	Dir string
+
+	size   int64
+	reader io.ReadSeeker
+	file   *os.File
+}
+
+func NewSpooledBody(threshold int64) *SpooledBody {
+	if threshold <= 0 {
+		threshold = defaultSpoolThreshold
+	}
+	return &SpooledBody{Threshold: threshold}
+}
+
+func (s *SpooledBody) DecodeBody(r io.Reader) error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, r, s.Threshold+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if n <= s.Threshold {
+		s.size = n
+		s.reader = bytes.NewReader(buf.Bytes())
+		return nil
+	}
+
+	f, err := os.CreateTemp(s.Dir, "metahttp-body-*")
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(f, io.MultiReader(&buf, r))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	s.file = f
+	s.size = written
+	s.reader = f
+	return nil
+}
+
+func (s *SpooledBody) Read(p []byte) (int, error) {
+	if s.reader == nil {
+		return 0, io.EOF
+	}
+	return s.reader.Read(p)
+}
+
+func (s *SpooledBody) Seek(offset int64, whence int) (int64, error) {
+	if s.reader == nil {
+		return 0, errors.New("spooled body is empty")
+	}
+	return s.reader.Seek(offset, whence)
+}
+
+// Size is the total number of body bytes received.
+func (s *SpooledBody) Size() int64 {
+	return s.size
+}
+
+// InMemory reports whether the body stayed below the threshold.
+func (s *SpooledBody) InMemory() bool {
+	return s.file == nil
+}
+
+func (s *SpooledBody) Close() error {
+	s.reader = nil
+	s.size = 0
+	if s.file == nil {
+		return nil
+	}
+	name := s.file.Name()
+	err := s.file.Close()
+	s.file = nil
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package metahttp_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestSpooledBody(t *testing.T) {
+	large := bytes.Repeat([]byte("a,b,c\n"), 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/csv")
+		if req.URL.Path == "/small" {
+			rw.Write([]byte("a,b,c\n"))
+			return
+		}
+		rw.Write(large)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	small := metahttp.NewSpooledBody(1024)
+	defer small.Close()
+	if _, err := metaHttpClient.Get(context.Background(), "/small", map[string]string{}, small); err != nil {
+		t.Fatal(err)
+	}
+	if !small.InMemory() || small.Size() != 6 {
+		t.Errorf("small body should stay in memory, size %d", small.Size())
+	}
+
+	report := metahttp.NewSpooledBody(1024)
+	defer report.Close()
+	if _, err := metaHttpClient.Get(context.Background(), "/large", map[string]string{}, report); err != nil {
+		t.Fatal(err)
+	}
+	if report.InMemory() || report.Size() != int64(len(large)) {
+		t.Errorf("large body should be spooled to disk, size %d", report.Size())
+	}
+
+	report.Seek(int64(len(large)-6), io.SeekStart)
+	tail, _ := io.ReadAll(report)
+	if string(tail) != "a,b,c\n" {
+		t.Errorf("unexpected tail: %q", tail)
+	}
+}
@@ -0,0 +1,37 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestSSRFProtection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient("", logger, 5*time.Second, metahttp.WithSSRFProtection())
+
+	for _, target := range []string{server.URL, "http://169.254.169.254/latest/meta-data", "http://10.0.0.1/", "http://[::1]:8080/"} {
+		if _, err := client.Get(context.Background(), target, nil, nil); !errors.Is(err, models.ErrBlockedDestination) {
+			t.Errorf("%s: expected blocked destination, got: %v", target, err)
+		}
+	}
+
+	allowed := metahttp.NewClient("", logger, 5*time.Second, metahttp.WithSSRFProtection(netip.MustParsePrefix("127.0.0.0/8")))
+	if _, err := allowed.Get(context.Background(), server.URL, nil, nil); err != nil {
+		t.Errorf("allowed range should be reachable: %v", err)
+	}
+}
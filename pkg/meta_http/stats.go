@@ -0,0 +1,306 @@
+package metahttp
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// Stats is a point-in-time view of a client's counters.
+type Stats struct {
+	// Requests counts calls made through Get, Post, Put and Do.
+	Requests int64 `json:"requests"`
+	// InFlight is the number of calls currently running.
+	InFlight int64 `json:"in_flight"`
+	// Attempts counts requests handed to the transport, including retries
+	// and redirects.
+	Attempts int64 `json:"attempts"`
+	Retries  int64 `json:"retries"`
+	// BytesSent and BytesReceived add up the request and response bodies
+	// as sent over the wire, retries included, see models.ResponseData.
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+	// Errors counts failed calls by category, e.g. models.CategoryTimeout.
+	Errors map[models.ErrorCategory]int64 `json:"errors"`
+	// Callers counts calls by caller tag, see ContextWithCaller. Untagged
+	// calls are not counted.
+	Callers map[string]int64 `json:"callers"`
+	// Endpoints describes the calls made through Endpoints, by name.
+	Endpoints map[string]EndpointStats `json:"endpoints"`
+	Pool      PoolStats                `json:"pool"`
+	// Timeouts tells how close recent calls came to their timeout.
+	Timeouts TimeoutReport `json:"timeouts"`
+}
+
+// PoolStats describes the connections of the client's transport.
+type PoolStats struct {
+	// OpenConns is the number of connections currently open, idle or not.
+	OpenConns   int64 `json:"open_conns"`
+	NewConns    int64 `json:"new_conns"`
+	ReusedConns int64 `json:"reused_conns"`
+}
+
+type clientStats struct {
+	requests      atomic.Int64
+	inFlight      atomic.Int64
+	attempts      atomic.Int64
+	retries       atomic.Int64
+	bytesSent     atomic.Int64
+	bytesReceived atomic.Int64
+	openConns     atomic.Int64
+	newConns      atomic.Int64
+	reusedConns   atomic.Int64
+
+	mu        sync.Mutex
+	errors    map[models.ErrorCategory]int64
+	callers   map[string]int64
+	endpoints map[string]*endpointStats
+
+	// timeout is the timeout of the client, which deadlines reports against.
+	timeout   time.Duration
+	deadlines deadlineTracker
+
+	// windowMu guards the start and the counters at the start of the
+	// current window of SnapshotAndReset.
+	windowMu    sync.Mutex
+	windowStart time.Time
+	baseline    Stats
+}
+
+func newClientStats() *clientStats {
+	return &clientStats{
+		errors:      map[models.ErrorCategory]int64{},
+		callers:     map[string]int64{},
+		endpoints:   map[string]*endpointStats{},
+		windowStart: time.Now(),
+	}
+}
+
+func (s *clientStats) snapshot() Stats {
+	s.mu.Lock()
+	errs := make(map[models.ErrorCategory]int64, len(s.errors))
+	for class, n := range s.errors {
+		errs[class] = n
+	}
+	callers := make(map[string]int64, len(s.callers))
+	for caller, n := range s.callers {
+		callers[caller] = n
+	}
+	endpoints := make(map[string]EndpointStats, len(s.endpoints))
+	for name, es := range s.endpoints {
+		endpoints[name] = es.snapshot()
+	}
+	s.mu.Unlock()
+
+	return Stats{
+		Requests:      s.requests.Load(),
+		InFlight:      s.inFlight.Load(),
+		Attempts:      s.attempts.Load(),
+		Retries:       s.retries.Load(),
+		BytesSent:     s.bytesSent.Load(),
+		BytesReceived: s.bytesReceived.Load(),
+		Errors:        errs,
+		Callers:       callers,
+		Endpoints:     endpoints,
+		Pool: PoolStats{
+			OpenConns:   s.openConns.Load(),
+			NewConns:    s.newConns.Load(),
+			ReusedConns: s.reusedConns.Load(),
+		},
+		Timeouts: s.deadlines.report(s.timeout),
+	}
+}
+
+// StatsWindow is the activity of a client over a window of time, see
+// SnapshotAndReset.
+type StatsWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Stats
+}
+
+// SnapshotAndReset returns the stats of the window since the previous call,
+// or since the client was created, and starts a new one, so that periodic
+// exporters can compute rates from the counters without keeping the
+// previous values. Counters count the window only, while InFlight and
+// Pool.OpenConns are current, latency percentiles cover the last calls and
+// SLO stats the lifetime of the client. Stats keeps counting from creation.
+func (c *client) SnapshotAndReset() StatsWindow {
+	return c.stats.window()
+}
+
+func (s *clientStats) window() StatsWindow {
+	s.windowMu.Lock()
+	defer s.windowMu.Unlock()
+	now := time.Now()
+	current := s.snapshot()
+	window := StatsWindow{Start: s.windowStart, End: now, Stats: current.since(s.baseline)}
+	s.windowStart, s.baseline = now, current
+	return window
+}
+
+// since returns the counters of st less those of prev, an earlier snapshot
+// of the same client. st is left as is.
+func (st Stats) since(prev Stats) Stats {
+	st.Requests -= prev.Requests
+	st.Attempts -= prev.Attempts
+	st.Retries -= prev.Retries
+	st.BytesSent -= prev.BytesSent
+	st.BytesReceived -= prev.BytesReceived
+	st.Pool.NewConns -= prev.Pool.NewConns
+	st.Pool.ReusedConns -= prev.Pool.ReusedConns
+	st.Errors = subtractCounts(st.Errors, prev.Errors)
+	st.Callers = subtractCounts(st.Callers, prev.Callers)
+	endpoints := make(map[string]EndpointStats, len(st.Endpoints))
+	for name, es := range st.Endpoints {
+		before := prev.Endpoints[name]
+		es.Calls -= before.Calls
+		es.Errors -= before.Errors
+		es.SuccessRate = 1
+		if es.Calls > 0 {
+			es.SuccessRate = float64(es.Calls-es.Errors) / float64(es.Calls)
+		}
+		endpoints[name] = es
+	}
+	st.Endpoints = endpoints
+	return st
+}
+
+// subtractCounts returns counts less before, without the keys left at zero.
+func subtractCounts[K comparable](counts, before map[K]int64) map[K]int64 {
+	diff := make(map[K]int64, len(counts))
+	for k, n := range counts {
+		if n -= before[k]; n != 0 {
+			diff[k] = n
+		}
+	}
+	return diff
+}
+
+// begin counts a call and returns the function recording its outcome,
+// given the endpoint it was made through, if any.
+func (s *clientStats) begin() func(ep *endpoint, err error) {
+	started := time.Now()
+	s.requests.Add(1)
+	s.inFlight.Add(1)
+	return func(ep *endpoint, err error) {
+		s.inFlight.Add(-1)
+		if err == nil && ep == nil {
+			return
+		}
+		s.mu.Lock()
+		if err != nil {
+			s.errors[models.CategoryOf(err)]++
+		}
+		if ep != nil {
+			s.recordEndpoint(ep, time.Since(started), err)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *clientStats) countCaller(caller string) {
+	if caller == "" {
+		return
+	}
+	s.mu.Lock()
+	s.callers[caller]++
+	s.mu.Unlock()
+}
+
+// countConns tracks connections dialed by transport.
+func (s *clientStats) countConns(transport *http.Transport) {
+	dial := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		s.openConns.Add(1)
+		s.newConns.Add(1)
+		return &countedConn{Conn: conn, open: &s.openConns}, nil
+	}
+}
+
+type countedConn struct {
+	net.Conn
+	open *atomic.Int64
+	once sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() { c.open.Add(-1) })
+	return c.Conn.Close()
+}
+
+type statsRoundTripper struct {
+	next  http.RoundTripper
+	stats *clientStats
+	// trace is shared by the attempts of requests carrying no trace of
+	// their own, sparing an allocation per attempt.
+	trace *httptrace.ClientTrace
+}
+
+func newStatsRoundTripper(stats *clientStats, next http.RoundTripper) statsRoundTripper {
+	return statsRoundTripper{next: next, stats: stats, trace: stats.trace()}
+}
+
+func (s statsRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	s.stats.attempts.Add(1)
+	trace := s.trace
+	// WithClientTrace merges an existing trace into the one it is given.
+	if httptrace.ContextClientTrace(r.Context()) != nil {
+		trace = s.stats.trace()
+	}
+	return s.next.RoundTrip(r.WithContext(httptrace.WithClientTrace(r.Context(), trace)))
+}
+
+func (s *clientStats) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				s.reusedConns.Add(1)
+			}
+		},
+	}
+}
+
+// expvarMu makes the check and the publication of PublishExpvar one step:
+// expvar.Publish panics on names published meanwhile.
+var expvarMu sync.Mutex
+
+// PublishExpvar publishes the stats of client under name in expvar, served
+// at /debug/vars by the expvar handler.
+func PublishExpvar(name string, client Requests) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return client.Stats()
+	}))
+	return nil
+}
+
+// StatsHandler serves the stats of clients as a JSON object keyed by name,
+// for mounting on an internal debug port.
+func StatsHandler(clients map[string]Requests) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		stats := make(map[string]Stats, len(clients))
+		for name, c := range clients {
+			stats[name] = c.Stats()
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(stats)
+	})
+}
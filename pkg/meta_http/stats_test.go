@@ -0,0 +1,100 @@
+package metahttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestClientStats(t *testing.T) {
+	flaky := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" {
+			flaky++
+		}
+		if flaky == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClientWithRetry(server.URL, logger, 5*time.Second, models.Retry{
+		MaxRetries:        3,
+		DelayBetweenRetry: time.Millisecond,
+		Validator:         func(status int) bool { return status < http.StatusInternalServerError },
+	})
+
+	client.Get(context.Background(), "/ok", nil, nil)
+	client.Get(context.Background(), "/flaky", nil, nil)
+	client.Get(context.Background(), "/missing", nil, nil)
+
+	stats := client.Stats()
+	if stats.Requests != 3 || stats.Attempts != 4 || stats.Retries != 1 || stats.InFlight != 0 {
+		t.Errorf("unexpected counters: %+v", stats)
+	}
+	if stats.Errors[models.CategoryHTTP4xx] != 1 || len(stats.Errors) != 1 {
+		t.Errorf("unexpected errors: %v", stats.Errors)
+	}
+	if stats.Pool.NewConns != 1 || stats.Pool.ReusedConns != 3 || stats.Pool.OpenConns != 1 {
+		t.Errorf("unexpected pool stats: %+v", stats.Pool)
+	}
+
+	name := fmt.Sprintf("metahttp_test_client_%d", time.Now().UnixNano())
+	if err := metahttp.PublishExpvar(name, client); err != nil {
+		t.Fatal(err)
+	}
+	if err := metahttp.PublishExpvar(name, client); err == nil {
+		t.Error("publishing twice should fail")
+	}
+	var published metahttp.Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &published); err != nil || published.Requests != 3 {
+		t.Errorf("unexpected expvar: %+v, err: %v", published, err)
+	}
+
+	rec := httptest.NewRecorder()
+	metahttp.StatsHandler(map[string]metahttp.Requests{"partner": client}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var served map[string]metahttp.Stats
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil || served["partner"].Attempts != 4 {
+		t.Errorf("unexpected handler output: %s", rec.Body.String())
+	}
+}
+
+func TestPublishExpvarConcurrently(t *testing.T) {
+	client := metahttp.NewClient("http://localhost", nil, time.Second, metahttp.WithoutLogging())
+	name := fmt.Sprintf("metahttp_test_concurrent_%d", time.Now().UnixNano())
+
+	var published atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := metahttp.PublishExpvar(name, client); err == nil {
+				published.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := published.Load(); n != 1 {
+		t.Errorf("expected a single publication, got %d", n)
+	}
+}
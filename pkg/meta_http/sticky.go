@@ -0,0 +1,121 @@
+package metahttp
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+const defaultStickyReplicas = 100
+
+// StickyRouting sends the calls sharing a key, e.g. a user id, to the same
+// node of a downstream keeping per-node caches. Keys are placed on a
+// consistent hash ring of the nodes, so that adding or removing a node only
+// moves the keys of the nodes next to it.
+type StickyRouting struct {
+	// Nodes are the base URLs of the nodes, scheme and host only, e.g.
+	// https://cache-1.internal:8443. They replace those of the base URL of
+	// the client, whose path is kept. Redirects to other hosts are
+	// followed as they are.
+	Nodes []string
+	// Key returns the routing key of a call, the user id of its context when
+	// nil. Calls without a key go to the base URL of the client.
+	Key func(ctx context.Context) string
+	// Replicas is the number of points of each node on the ring, 100 by
+	// default. More points spread the keys more evenly.
+	Replicas int
+}
+
+type stickyRoundTripper struct {
+	next http.RoundTripper
+	key  func(ctx context.Context) string
+	// host is that of the base URL, the only one routed to the nodes so
+	// that redirects to other hosts are followed as they are.
+	host   string
+	points []uint64
+	nodes  []*url.URL
+}
+
+func newStickyRoundTripper(cfg StickyRouting, baseUrl string, log Logger, next http.RoundTripper) http.RoundTripper {
+	replicas := cfg.Replicas
+	if replicas <= 0 {
+		replicas = defaultStickyReplicas
+	}
+	key := cfg.Key
+	if key == nil {
+		key = func(ctx context.Context) string { return contextString(ctx, models.UserID) }
+	}
+	type point struct {
+		hash uint64
+		node *url.URL
+	}
+	var ring []point
+	for _, raw := range cfg.Nodes {
+		node, err := url.Parse(raw)
+		if err != nil || node.Host == "" || (node.Scheme != "http" && node.Scheme != "https") {
+			log.ErrorContext(context.Background(), "Ignoring invalid sticky routing node", slog.String("url", raw))
+			continue
+		}
+		for i := 0; i < replicas; i++ {
+			ring = append(ring, point{hash: stickyHash(node.Host + "#" + strconv.Itoa(i)), node: node})
+		}
+	}
+	if len(ring) == 0 {
+		return next
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	s := &stickyRoundTripper{next: next, key: key}
+	if base, err := url.Parse(baseUrl); err == nil {
+		s.host = base.Host
+	}
+	for _, p := range ring {
+		s.points = append(s.points, p.hash)
+		s.nodes = append(s.nodes, p.node)
+	}
+	return s
+}
+
+// stickyHash is FNV-1a followed by the finalizer of SplitMix64, as FNV
+// alone leaves similar keys such as "user-1" and "user-2" close together
+// on the ring.
+func stickyHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// node returns the node owning key: the first point of the ring at or after
+// its hash.
+func (s *stickyRoundTripper) node(key string) *url.URL {
+	hash := stickyHash(key)
+	i := sort.Search(len(s.points), func(i int) bool { return s.points[i] >= hash })
+	if i == len(s.points) {
+		i = 0
+	}
+	return s.nodes[i]
+}
+
+func (s *stickyRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !strings.EqualFold(r.URL.Host, s.host) {
+		return s.next.RoundTrip(r)
+	}
+	key := s.key(r.Context())
+	if key == "" {
+		return s.next.RoundTrip(r)
+	}
+	node := s.node(key)
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = node.Scheme, node.Host
+	r.Host = ""
+	return s.next.RoundTrip(r)
+}
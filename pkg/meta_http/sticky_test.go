@@ -0,0 +1,112 @@
+package metahttp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestStickyRouting(t *testing.T) {
+	var mu sync.Mutex
+	served := map[string]string{}
+	var nodes []string
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("node-%d", i)
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			served[r.Header.Get(string(models.UserID))] = name
+			mu.Unlock()
+			if r.URL.Path != "/api/profile" {
+				t.Errorf("path = %s", r.URL.Path)
+			}
+		}))
+		defer server.Close()
+		nodes = append(nodes, server.URL)
+	}
+	base := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		served[""] = "base"
+		mu.Unlock()
+	}))
+	defer base.Close()
+
+	route := func(client metahttp.Requests, user string) string {
+		ctx := context.Background()
+		if user != "" {
+			ctx = context.WithValue(ctx, models.UserID, user)
+		}
+		if _, err := client.Get(ctx, "/profile", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return served[user]
+	}
+
+	client := metahttp.NewClient(base.URL+"/api", nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithStickyRouting(metahttp.StickyRouting{Nodes: nodes}))
+	owners := map[string]string{}
+	load := map[string]int{}
+	for i := 0; i < 60; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		owners[user] = route(client, user)
+		load[owners[user]]++
+		if again := route(client, user); again != owners[user] {
+			t.Errorf("%s routed to %s then %s", user, owners[user], again)
+		}
+	}
+	if len(load) != 3 {
+		t.Errorf("load = %v, want every node used", load)
+	}
+	if got := route(client, ""); got != "base" {
+		t.Errorf("call without key routed to %s", got)
+	}
+
+	// Removing a node only moves its own keys.
+	shrunk := metahttp.NewClient(base.URL+"/api", nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithStickyRouting(metahttp.StickyRouting{Nodes: nodes[:2]}))
+	for user, owner := range owners {
+		if got := route(shrunk, user); owner != "node-2" && got != owner {
+			t.Errorf("%s moved from %s to %s", user, owner, got)
+		}
+	}
+}
+
+func TestStickyRoutingRedirects(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("external"))
+	}))
+	defer external.Close()
+	node := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(rw, r, external.URL+"/landing", http.StatusFound)
+		case "/moved":
+			http.Redirect(rw, r, "/here", http.StatusFound)
+		default:
+			rw.Write([]byte("node"))
+		}
+	}))
+	defer node.Close()
+	base := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("base"))
+	}))
+	defer base.Close()
+
+	client := metahttp.NewClient(base.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithStickyRouting(metahttp.StickyRouting{Nodes: []string{node.URL}}))
+	ctx := context.WithValue(context.Background(), models.UserID, "user-1")
+	for path, want := range map[string]string{"/away": "external", "/moved": "node"} {
+		var got string
+		if _, err := client.Get(ctx, path, nil, &got); err != nil || got != want {
+			t.Errorf("%s served by %q, want %q (%v)", path, got, want, err)
+		}
+	}
+}
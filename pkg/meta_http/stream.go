@@ -0,0 +1,60 @@
+package metahttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// BodyDecoder is implemented by response targets that consume the raw body
+// themselves instead of going through a codec.
+type BodyDecoder interface {
+	DecodeBody(r io.Reader) error
+}
+
+// DecodeStream decodes a top-level JSON array from r one element at a time,
+// calling fn for each, so arbitrarily large arrays never need to be held in
+// memory. Returning an error from fn stops decoding and returns that error.
+func DecodeStream[T any](r io.Reader, fn func(T) error) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("decode stream: expected start of array, got %v", tok)
+	}
+
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	return nil
+}
+
+type arrayStream[T any] struct {
+	fn func(T) error
+}
+
+func (s arrayStream[T]) DecodeBody(r io.Reader) error {
+	return DecodeStream(r, s.fn)
+}
+
+// StreamArray returns a response target that feeds each element of a JSON
+// array response to fn as it is read off the wire:
+//
+//	client.Get(ctx, "/catalog", nil, metahttp.StreamArray(func(p Product) error {
+//		return store.Upsert(ctx, p)
+//	}))
+func StreamArray[T any](fn func(T) error) BodyDecoder {
+	return arrayStream[T]{fn: fn}
+}
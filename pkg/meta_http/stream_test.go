@@ -0,0 +1,47 @@
+package metahttp_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestStreamArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("["))
+		for i := 0; i < 1000; i++ {
+			if i > 0 {
+				rw.Write([]byte(","))
+			}
+			fmt.Fprintf(rw, `{"id":%d}`, i)
+		}
+		rw.Write([]byte("]"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second)
+
+	type product struct {
+		ID int `json:"id"`
+	}
+	count, sum := 0, 0
+	target := metahttp.StreamArray(func(p product) error {
+		count++
+		sum += p.ID
+		return nil
+	})
+	if _, err := metaHttpClient.Get(context.Background(), "/catalog", map[string]string{}, target); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1000 || sum != 999*1000/2 {
+		t.Errorf("unexpected stream result: count %d, sum %d", count, sum)
+	}
+}
@@ -0,0 +1,250 @@
+package metahttp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// TenantConfig describes how to reach a partner on behalf of one tenant.
+type TenantConfig struct {
+	BaseURL string
+	// Headers are sent as default headers on every request of the tenant.
+	Headers map[string]string
+	// Timeout overrides the factory timeout when non-zero.
+	Timeout time.Duration
+	// Retry overrides the factory retry policy when set.
+	Retry *models.Retry
+	// RateLimit bounds the tenant's outgoing requests so one tenant cannot
+	// exhaust a partner quota shared by all of them.
+	RateLimit *RateLimit
+	// Options are applied after the factory options, e.g. WithTokenProvider
+	// carrying the tenant's credentials.
+	Options []Option
+}
+
+// TenantConfigProvider looks up the configuration of a tenant, typically
+// from a database or secret store.
+type TenantConfigProvider interface {
+	TenantConfig(ctx context.Context, tenantID string) (*TenantConfig, error)
+}
+
+// TenantConfigProviderFunc adapts a plain function to the
+// TenantConfigProvider interface.
+type TenantConfigProviderFunc func(ctx context.Context, tenantID string) (*TenantConfig, error)
+
+func (f TenantConfigProviderFunc) TenantConfig(ctx context.Context, tenantID string) (*TenantConfig, error) {
+	return f(ctx, tenantID)
+}
+
+// TenantClients builds and caches one client per tenant. The tenant is taken
+// from the models.TenantID context value, so handlers can call
+// Client(ctx) without threading tenant IDs around.
+type TenantClients struct {
+	provider TenantConfigProvider
+	logger   Logger
+	timeout  time.Duration
+	retry    *models.Retry
+	opts     []Option
+
+	mu      sync.Mutex
+	clients map[string]Requests
+	// loading holds the configurations being looked up, so that a slow
+	// lookup only holds back the calls of its own tenant.
+	loading map[string]*tenantLoad
+	usage   map[string]*usageCounters
+}
+
+type tenantLoad struct {
+	done   chan struct{}
+	client Requests
+	err    error
+	// canceled is set when the lookup failed with the context of its
+	// caller, which the other callers then make again.
+	canceled bool
+}
+
+// NewTenantClients returns a factory whose clients share log, timeout and
+// opts, overridden per tenant by the provider's TenantConfig.
+func NewTenantClients(provider TenantConfigProvider, log Logger, timeout time.Duration, opts ...Option) *TenantClients {
+	return &TenantClients{
+		provider: provider,
+		logger:   orDefault(log),
+		timeout:  timeout,
+		opts:     opts,
+		clients:  map[string]Requests{},
+		loading:  map[string]*tenantLoad{},
+		usage:    map[string]*usageCounters{},
+	}
+}
+
+// NewTenantClientsWithRetry is NewTenantClients with a default retry policy.
+func NewTenantClientsWithRetry(provider TenantConfigProvider, log Logger, timeout time.Duration, retry models.Retry, opts ...Option) *TenantClients {
+	tc := NewTenantClients(provider, log, timeout, opts...)
+	tc.retry = &retry
+	return tc
+}
+
+// Client returns the client of the tenant stored in ctx, failing with
+// models.ErrMissingTenant when there is none.
+func (tc *TenantClients) Client(ctx context.Context) (Requests, error) {
+	tenantID, _ := ctx.Value(models.TenantID).(string)
+	if tenantID == "" {
+		return nil, models.ErrMissingTenant
+	}
+	return tc.ForTenant(ctx, tenantID)
+}
+
+// ForTenant returns the client of tenantID, building it on first use.
+// Concurrent calls for a tenant share the lookup of its configuration. The
+// client is meant for the calls at hand rather than to be kept: once
+// invalidated, it is closed as soon as none of its calls is in flight.
+func (tc *TenantClients) ForTenant(ctx context.Context, tenantID string) (Requests, error) {
+	for {
+		tc.mu.Lock()
+		if c, ok := tc.clients[tenantID]; ok {
+			tc.mu.Unlock()
+			return c, nil
+		}
+		load, ok := tc.loading[tenantID]
+		if !ok {
+			break
+		}
+		tc.mu.Unlock()
+		select {
+		case <-load.done:
+			if !load.canceled {
+				return load.client, load.err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	load := &tenantLoad{done: make(chan struct{}), err: fmt.Errorf("loading config of tenant %s did not complete", tenantID)}
+	tc.loading[tenantID] = load
+	tc.mu.Unlock()
+
+	// Deferred so that the waiting calls are released even on panics.
+	defer func() {
+		tc.mu.Lock()
+		delete(tc.loading, tenantID)
+		if load.err == nil {
+			tc.clients[tenantID] = load.client
+		}
+		tc.mu.Unlock()
+		close(load.done)
+	}()
+
+	cfg, err := tc.provider.TenantConfig(ctx, tenantID)
+	if err != nil {
+		load.err = fmt.Errorf("loading config of tenant %s: %w", tenantID, err)
+		load.canceled = ctx.Err() != nil
+		return nil, load.err
+	}
+	tc.mu.Lock()
+	load.client, load.err = tc.build(tenantID, cfg), nil
+	tc.mu.Unlock()
+	return load.client, nil
+}
+
+// Invalidate drops the cached client of tenantID so the next call reloads
+// its configuration, e.g. after credentials were rotated. The dropped client
+// is closed in the background once none of its calls is in flight, so that
+// calls made with it meanwhile complete; later ones fail with
+// models.ErrClientClosed.
+func (tc *TenantClients) Invalidate(tenantID string) {
+	tc.mu.Lock()
+	c, ok := tc.clients[tenantID]
+	delete(tc.clients, tenantID)
+	tc.mu.Unlock()
+	if ok {
+		go retire(c)
+	}
+}
+
+// retire closes r once no call of it is in flight. Close itself would
+// refuse the calls starting while the in-flight ones drain.
+func retire(r Requests) {
+	if c := clientOf(r); c != nil {
+		c.drain(context.Background())
+	}
+	r.Close(context.Background())
+}
+
+// Close closes the client of every tenant and drops them, see Requests.Close.
+// It returns the first error of any client.
+func (tc *TenantClients) Close(ctx context.Context) error {
+	tc.mu.Lock()
+	clients := tc.clients
+	tc.clients = map[string]Requests{}
+	tc.mu.Unlock()
+
+	var firstErr error
+	for _, c := range clients {
+		if err := c.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Usage returns the request counters of tenantID. Counters are only kept
+// for tenants with a RateLimit and survive Invalidate.
+func (tc *TenantClients) Usage(tenantID string) Usage {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if u, ok := tc.usage[tenantID]; ok {
+		return u.snapshot()
+	}
+	return Usage{}
+}
+
+// Usages returns the request counters of every rate limited tenant.
+func (tc *TenantClients) Usages() map[string]Usage {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	usages := make(map[string]Usage, len(tc.usage))
+	for tenantID, u := range tc.usage {
+		usages[tenantID] = u.snapshot()
+	}
+	return usages
+}
+
+func (tc *TenantClients) build(tenantID string, cfg *TenantConfig) Requests {
+	timeout := tc.timeout
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+	retry := tc.retry
+	if cfg.Retry != nil {
+		retry = cfg.Retry
+	}
+	opts := append(append([]Option{}, tc.opts...), cfg.Options...)
+	if cfg.RateLimit != nil {
+		usage, ok := tc.usage[tenantID]
+		if !ok {
+			usage = &usageCounters{}
+			tc.usage[tenantID] = usage
+		}
+		limit := *cfg.RateLimit
+		opts = append(opts, func(o *options) {
+			o.rateLimit = &limit
+			o.usage = usage
+		})
+	}
+	log := withArgs(tc.logger, slog.String(string(models.TenantID), tenantID))
+
+	c := newClient(cfg.BaseURL, log, timeout, retry, opts)
+	if len(cfg.Headers) > 0 {
+		headers := make(map[string]string, len(cfg.Headers))
+		for k, v := range cfg.Headers {
+			headers[k] = v
+		}
+		c.SetDefaultHeaders(headers)
+	}
+	return c
+}
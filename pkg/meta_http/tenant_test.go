@@ -0,0 +1,240 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestTenantClients(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"tenant":"` + r.Header.Get("X-Partner-Account") + `","path":"` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	loads := 0
+	provider := metahttp.TenantConfigProviderFunc(func(ctx context.Context, tenantID string) (*metahttp.TenantConfig, error) {
+		loads++
+		if tenantID == "unknown" {
+			return nil, errors.New("no such tenant")
+		}
+		return &metahttp.TenantConfig{
+			BaseURL: server.URL + "/" + tenantID,
+			Headers: map[string]string{"X-Partner-Account": "acct-" + tenantID},
+		}, nil
+	})
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	factory := metahttp.NewTenantClients(provider, logger, 5*time.Second)
+
+	if _, err := factory.Client(context.Background()); !errors.Is(err, models.ErrMissingTenant) {
+		t.Errorf("expected missing tenant error, got: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), models.TenantID, "t1")
+	for i := 0; i < 2; i++ {
+		c, err := factory.Client(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res map[string]string
+		if _, err := c.Get(ctx, "orders", nil, &res); err != nil {
+			t.Fatal(err)
+		}
+		if res["tenant"] != "acct-t1" || res["path"] != "/t1/orders" {
+			t.Errorf("unexpected response: %v", res)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("expected config to be loaded once, got %d", loads)
+	}
+
+	evicted, _ := factory.Client(ctx)
+	factory.Invalidate("t1")
+	if _, err := factory.Client(ctx); err != nil || loads != 2 {
+		t.Errorf("expected reload after invalidate, loads: %d, err: %v", loads, err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := evicted.Get(ctx, "orders", nil, nil)
+		if errors.Is(err, models.ErrClientClosed) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the evicted client to be closed, got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := factory.ForTenant(context.Background(), "unknown"); err == nil {
+		t.Error("expected provider error")
+	}
+}
+
+func TestTenantRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	provider := metahttp.TenantConfigProviderFunc(func(ctx context.Context, tenantID string) (*metahttp.TenantConfig, error) {
+		cfg := &metahttp.TenantConfig{BaseURL: server.URL}
+		if tenantID == "noisy" {
+			cfg.RateLimit = &metahttp.RateLimit{RequestsPerSecond: 1, Burst: 2}
+		}
+		return cfg, nil
+	})
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	factory := metahttp.NewTenantClients(provider, logger, 5*time.Second)
+
+	noisy, err := factory.ForTenant(context.Background(), "noisy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := noisy.Get(context.Background(), "/", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := noisy.Get(ctx, "/", nil, nil); !errors.Is(err, models.ErrRateLimited) {
+		t.Errorf("expected rate limit error, got: %v", err)
+	}
+
+	quiet, _ := factory.ForTenant(context.Background(), "quiet")
+	for i := 0; i < 5; i++ {
+		if _, err := quiet.Get(context.Background(), "/", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage := factory.Usage("noisy")
+	if usage.Requests != 2 || usage.Throttled != 1 || usage.Rejected != 1 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+	if _, ok := factory.Usages()["quiet"]; ok {
+		t.Error("tenants without a rate limit should not be counted")
+	}
+}
+
+func TestTenantSlowLookup(t *testing.T) {
+	release := make(chan struct{})
+	var loads atomic.Int32
+	provider := metahttp.TenantConfigProviderFunc(func(ctx context.Context, tenantID string) (*metahttp.TenantConfig, error) {
+		loads.Add(1)
+		if tenantID == "slow" {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return &metahttp.TenantConfig{BaseURL: "http://" + tenantID + ".example"}, nil
+	})
+	factory := metahttp.NewTenantClients(provider, nil, 5*time.Second, metahttp.WithoutLogging())
+	defer factory.Close(context.Background())
+
+	canceled, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := factory.ForTenant(canceled, "slow")
+		first <- err
+	}()
+	second := make(chan error, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, err := factory.ForTenant(context.Background(), "slow")
+		second <- err
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := factory.ForTenant(context.Background(), "fast")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a slow lookup held back another tenant")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled lookup: %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("expected the waiting lookup to be made again, got %v", err)
+	}
+	if n := loads.Load(); n != 3 {
+		t.Errorf("expected 3 lookups, got %d", n)
+	}
+}
+
+func TestTenantInvalidateWhileInFlight(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	provider := metahttp.TenantConfigProviderFunc(func(ctx context.Context, tenantID string) (*metahttp.TenantConfig, error) {
+		return &metahttp.TenantConfig{BaseURL: server.URL}, nil
+	})
+	factory := metahttp.NewTenantClients(provider, nil, 5*time.Second, metahttp.WithoutLogging())
+	defer factory.Close(context.Background())
+
+	ctx := context.Background()
+	c, err := factory.ForTenant(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow := make(chan error, 1)
+	go func() {
+		_, err := c.Get(ctx, "/slow", nil, nil)
+		slow <- err
+	}()
+	<-started
+
+	// The client fetched before Invalidate keeps serving while a call of
+	// it is in flight.
+	factory.Invalidate("t1")
+	if _, err := c.Get(ctx, "/fast", nil, nil); err != nil {
+		t.Errorf("expected a call during the drain to succeed, got %v", err)
+	}
+	if err := <-slow; err != nil {
+		t.Errorf("expected the in-flight call to succeed, got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := c.Get(ctx, "/fast", nil, nil)
+		if errors.Is(err, models.ErrClientClosed) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the invalidated client to be closed once drained, got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,164 @@
+package metahttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// Timeouts bounds the individual phases of a call, complementing the overall
+// client timeout. Zero values keep the transport defaults (Dial,
+// TLSHandshake) or disable the limit (ResponseHeader, BodyReadIdle,
+// WriteIdle).
+type Timeouts struct {
+	// Dial bounds establishing the TCP connection.
+	Dial time.Duration
+	// TLSHandshake bounds the TLS handshake.
+	TLSHandshake time.Duration
+	// ResponseHeader bounds the wait for response headers once the request
+	// has been written.
+	ResponseHeader time.Duration
+	// BodyReadIdle fails the call when no response body bytes arrive for
+	// this long, however long the whole body takes.
+	BodyReadIdle time.Duration
+	// WriteIdle fails the call when a write to the connection stalls for
+	// this long.
+	WriteIdle time.Duration
+}
+
+func (t Timeouts) apply(transport *http.Transport) {
+	if t.Dial > 0 || t.WriteIdle > 0 {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		if t.Dial > 0 {
+			dialer.Timeout = t.Dial
+		}
+		writeIdle := t.WriteIdle
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil || writeIdle <= 0 {
+				return conn, err
+			}
+			return &writeDeadlineConn{Conn: conn, timeout: writeIdle}, nil
+		}
+	}
+	if t.TLSHandshake > 0 {
+		transport.TLSHandshakeTimeout = t.TLSHandshake
+	}
+	if t.ResponseHeader > 0 {
+		transport.ResponseHeaderTimeout = t.ResponseHeader
+	}
+}
+
+// writeDeadlineConn pushes the write deadline forward before every write.
+// Read deadlines are left alone: idle pooled connections sit in a blocking
+// read and must not be killed by an idle timeout.
+type writeDeadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *writeDeadlineConn) Write(p []byte) (int, error) {
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(p)
+}
+
+type bodyIdleTimeoutRoundTripper struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (b bodyIdleTimeoutRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	res, err := b.next.RoundTrip(r)
+	if err != nil {
+		return res, err
+	}
+	body := &idleTimeoutBody{rc: res.Body}
+	body.timer = time.AfterFunc(b.timeout, body.expire)
+	body.timeout = b.timeout
+	res.Body = body
+	return res, nil
+}
+
+type idleTimeoutBody struct {
+	rc      io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	expired atomic.Bool
+	once    sync.Once
+}
+
+func (b *idleTimeoutBody) expire() {
+	b.expired.Store(true)
+	b.rc.Close()
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	if b.expired.Load() {
+		return 0, models.ErrBodyReadTimeout
+	}
+	n, err := b.rc.Read(p)
+	if b.expired.Load() {
+		return n, models.ErrBodyReadTimeout
+	}
+	if err != nil {
+		b.timer.Stop()
+		return n, err
+	}
+	b.timer.Reset(b.timeout)
+	return n, nil
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.once.Do(func() {
+		b.timer.Stop()
+	})
+	return b.rc.Close()
+}
+
+// deadlineBudgetRoundTripper bounds every attempt by a share of the time left
+// before the context deadline, keeping the rest for the caller to act on a
+// failure instead of spending it all waiting on the upstream.
+type deadlineBudgetRoundTripper struct {
+	next    http.RoundTripper
+	reserve float64
+}
+
+func (d deadlineBudgetRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		return d.next.RoundTrip(r)
+	}
+	budget := time.Duration(float64(time.Until(deadline)) * (1 - d.reserve))
+	ctx, cancel := context.WithTimeout(r.Context(), budget)
+	res, err := d.next.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return res, err
+	}
+	res.Body = &cancelOnCloseBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelOnCloseBody releases the context of an attempt once its body is
+// done with.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,91 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestBodyReadIdleTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		flusher := rw.(http.Flusher)
+		rw.Write([]byte("["))
+		for i := 0; i < 5; i++ {
+			if i > 0 {
+				rw.Write([]byte(","))
+			}
+			rw.Write([]byte("1"))
+			flusher.Flush()
+			if req.URL.Path == "/stalled" && i == 2 {
+				time.Sleep(500 * time.Millisecond)
+			} else {
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+		rw.Write([]byte("]"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second, metahttp.WithTimeouts(metahttp.Timeouts{
+		Dial:           time.Second,
+		ResponseHeader: time.Second,
+		BodyReadIdle:   200 * time.Millisecond,
+	}))
+
+	var res []int
+	if _, err := metaHttpClient.Get(context.Background(), "/slow", map[string]string{}, &res); err != nil {
+		t.Fatalf("slow but steady body should succeed: %v", err)
+	}
+	if len(res) != 5 {
+		t.Errorf("unexpected response: %v", res)
+	}
+
+	_, err := metaHttpClient.Get(context.Background(), "/stalled", map[string]string{}, &res)
+	if err == nil || !strings.Contains(err.Error(), "idle timeout") {
+		t.Errorf("expected idle timeout, got: %v", err)
+	}
+}
+
+func TestDeadlineBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow" {
+			select {
+			case <-time.After(time.Second):
+			case <-req.Context().Done():
+			}
+			return
+		}
+		rw.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL, logger, 10*time.Second, metahttp.WithDeadlineBudget(0.5))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	var res struct{ OK bool }
+	if _, err := client.Get(ctx, "/fast", nil, &res); err != nil || !res.OK {
+		t.Fatalf("unexpected result %+v, %v", res, err)
+	}
+
+	started := time.Now()
+	_, err := client.Get(ctx, "/slow", nil, nil)
+	var timeoutErr *models.TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Source != models.TimeoutAttempt {
+		t.Fatalf("expected an attempt timeout, got: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("the attempt must give up before the caller's deadline, took %s", time.Since(started))
+	}
+}
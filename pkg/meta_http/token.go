@@ -0,0 +1,181 @@
+package metahttp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// TokenProvider returns the token used to authenticate outgoing requests.
+type TokenProvider interface {
+	Token(ctx context.Context) (*models.Token, error)
+}
+
+// TokenProviderFunc adapts a plain function to the TokenProvider interface.
+type TokenProviderFunc func(ctx context.Context) (*models.Token, error)
+
+func (f TokenProviderFunc) Token(ctx context.Context) (*models.Token, error) {
+	return f(ctx)
+}
+
+type tokenSigner struct {
+	provider TokenProvider
+}
+
+func (ts tokenSigner) Sign(r *http.Request) error {
+	token, err := ts.provider.Token(r.Context())
+	if err != nil {
+		return fmt.Errorf("fetching token: %w", err)
+	}
+	tokenType := token.TokenType
+	if tokenType == "" {
+		tokenType = "Bearer"
+	}
+	r.Header.Set(string(models.AuthorizationKey), tokenType+" "+token.AccessToken)
+	return nil
+}
+
+const (
+	tokenRefreshTimeout    = 30 * time.Second
+	tokenRefreshRetryDelay = 5 * time.Second
+)
+
+// CachedTokenProvider caches the token returned by another provider and
+// refreshes it in a background goroutine once it is within the refresh window
+// of its expiry, so callers only block on the source when no valid token is
+// cached at all.
+type CachedTokenProvider struct {
+	source        TokenProvider
+	refreshWindow time.Duration
+	logger        Logger
+
+	mu    sync.RWMutex
+	token *models.Token
+
+	fetchMu sync.Mutex
+	start   sync.Once
+	updated chan struct{}
+	stop    chan struct{}
+	stopped sync.Once
+}
+
+func NewCachedTokenProvider(source TokenProvider, refreshWindow time.Duration, log Logger) *CachedTokenProvider {
+	return &CachedTokenProvider{
+		source:        source,
+		refreshWindow: refreshWindow,
+		logger:        orDefault(log),
+		updated:       make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+	}
+}
+
+func (c *CachedTokenProvider) Token(ctx context.Context) (*models.Token, error) {
+	c.mu.RLock()
+	token := c.token
+	c.mu.RUnlock()
+	if token.Valid(time.Now()) {
+		return token, nil
+	}
+
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	// Another caller may have fetched a token while we were waiting.
+	c.mu.RLock()
+	token = c.token
+	c.mu.RUnlock()
+	if token.Valid(time.Now()) {
+		return token, nil
+	}
+	return c.fetch(ctx)
+}
+
+// Close stops the background refresh goroutine.
+func (c *CachedTokenProvider) Close() {
+	c.stopped.Do(func() {
+		close(c.stop)
+	})
+}
+
+// fetch must be called with fetchMu held.
+func (c *CachedTokenProvider) fetch(ctx context.Context) (*models.Token, error) {
+	token, err := c.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+
+	c.start.Do(func() {
+		go c.refreshLoop()
+	})
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return token, nil
+}
+
+func (c *CachedTokenProvider) refreshLoop() {
+	var retryAt time.Time
+	for {
+		c.mu.RLock()
+		token := c.token
+		c.mu.RUnlock()
+
+		var wait <-chan time.Time
+		var timer *time.Timer
+		if token != nil && !token.ExpiresAt.IsZero() {
+			refreshAt := token.ExpiresAt.Add(-c.refreshWindow)
+			if retryAt.After(refreshAt) {
+				refreshAt = retryAt
+			}
+			delay := time.Until(refreshAt)
+			if delay <= 0 {
+				// The source hands out tokens shorter-lived than the window;
+				// refresh halfway to expiry instead of spinning.
+				delay = max(time.Until(token.ExpiresAt)/2, time.Second)
+			}
+			timer = time.NewTimer(delay)
+			wait = timer.C
+		}
+
+		select {
+		case <-c.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-c.updated:
+			if timer != nil {
+				timer.Stop()
+			}
+			retryAt = time.Time{}
+		case <-wait:
+			ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+			c.fetchMu.Lock()
+			_, err := c.fetch(ctx)
+			c.fetchMu.Unlock()
+			cancel()
+			if err != nil {
+				c.logger.WarnContext(ctx, "Token refresh failed", slog.Any("error", err.Error()))
+				retryAt = time.Now().Add(tokenRefreshRetryDelay)
+			} else {
+				// fetch already queued an update; drain it so the next
+				// iteration waits on the new expiry directly.
+				select {
+				case <-c.updated:
+				default:
+				}
+				retryAt = time.Time{}
+			}
+		}
+	}
+}
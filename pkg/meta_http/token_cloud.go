@@ -0,0 +1,181 @@
+package metahttp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+const metadataTimeout = 5 * time.Second
+
+// GCPIdentityTokenProvider fetches Google-signed identity tokens for the
+// given audience from the GCE/GKE metadata server. Wrap it with
+// NewCachedTokenProvider; every call otherwise hits the metadata server.
+type GCPIdentityTokenProvider struct {
+	Audience string
+	// MetadataHost defaults to $GCE_METADATA_HOST or metadata.google.internal.
+	MetadataHost string
+	HTTPClient   *http.Client
+}
+
+const defaultAzureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+func NewGCPIdentityTokenProvider(audience string) *GCPIdentityTokenProvider {
+	return &GCPIdentityTokenProvider{
+		Audience:     audience,
+		MetadataHost: gcpMetadataHost(),
+		HTTPClient:   &http.Client{Timeout: metadataTimeout},
+	}
+}
+
+func gcpMetadataHost() string {
+	if host := os.Getenv("GCE_METADATA_HOST"); host != "" {
+		return host
+	}
+	return "metadata.google.internal"
+}
+
+func (p *GCPIdentityTokenProvider) Token(ctx context.Context) (*models.Token, error) {
+	q := url.Values{}
+	q.Set("audience", p.Audience)
+	q.Set("format", "full")
+	host := p.MetadataHost
+	if host == "" {
+		host = gcpMetadataHost()
+	}
+	ul := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/identity?" + q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ul, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	body, err := fetchMetadata(p.HTTPClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("gcp metadata: %w", err)
+	}
+
+	raw := strings.TrimSpace(string(body))
+	exp, err := jwtExpiry(raw)
+	if err != nil {
+		return nil, fmt.Errorf("gcp metadata: %w", err)
+	}
+	return &models.Token{AccessToken: raw, TokenType: "Bearer", ExpiresAt: exp}, nil
+}
+
+// AzureIMDSTokenProvider fetches managed identity access tokens for the given
+// resource from the Azure Instance Metadata Service. ClientID selects a
+// user-assigned identity and may be left empty for the system identity.
+type AzureIMDSTokenProvider struct {
+	Resource string
+	ClientID string
+	// Endpoint defaults to the well-known IMDS token endpoint.
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+func NewAzureIMDSTokenProvider(resource string, clientID string) *AzureIMDSTokenProvider {
+	return &AzureIMDSTokenProvider{
+		Resource:   resource,
+		ClientID:   clientID,
+		Endpoint:   defaultAzureIMDSEndpoint,
+		HTTPClient: &http.Client{Timeout: metadataTimeout},
+	}
+}
+
+func (p *AzureIMDSTokenProvider) Token(ctx context.Context) (*models.Token, error) {
+	q := url.Values{}
+	q.Set("api-version", "2018-02-01")
+	q.Set("resource", p.Resource)
+	if p.ClientID != "" {
+		q.Set("client_id", p.ClientID)
+	}
+
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = defaultAzureIMDSEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	body, err := fetchMetadata(p.HTTPClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("azure imds: %w", err)
+	}
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("azure imds: %w", err)
+	}
+	token := &models.Token{AccessToken: res.AccessToken, TokenType: res.TokenType}
+	if res.ExpiresOn != "" {
+		secs, err := strconv.ParseInt(res.ExpiresOn, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("azure imds: invalid expires_on %q", res.ExpiresOn)
+		}
+		token.ExpiresAt = time.Unix(secs, 0)
+	}
+	return token, nil
+}
+
+// fetchMetadata falls back to a client with metadataTimeout for providers
+// built as struct literals.
+func fetchMetadata(c *http.Client, req *http.Request) ([]byte, error) {
+	if c == nil {
+		c = &http.Client{Timeout: metadataTimeout}
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d, response: %s", res.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// jwtExpiry reads the exp claim of a JWT without verifying its signature.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("malformed jwt")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed jwt payload: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("malformed jwt claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,99 @@
+package metahttp_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestGCPIdentityTokenProvider(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1900000000}`))
+	jwt := "e30." + payload + ".sig"
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Metadata-Flavor") != "Google" || req.URL.Query().Get("audience") != "https://api.example.com" {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		rw.Write([]byte(jwt))
+	}))
+	defer server.Close()
+
+	provider := metahttp.NewGCPIdentityTokenProvider("https://api.example.com")
+	provider.MetadataHost = strings.TrimPrefix(server.URL, "http://")
+
+	token, err := provider.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != jwt || token.ExpiresAt.Unix() != 1900000000 {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
+
+func TestAzureIMDSTokenProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Metadata") != "true" || req.URL.Query().Get("resource") != "api://partner" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Write([]byte(`{"access_token":"abc","expires_on":"1900000000","token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	provider := metahttp.NewAzureIMDSTokenProvider("api://partner", "")
+	provider.Endpoint = server.URL
+
+	token, err := provider.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "abc" || token.ExpiresAt.Unix() != 1900000000 {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
+
+func TestCloudTokenProvidersAsStructLiterals(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1900000000}`))
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requested = append(requested, req.Host+req.URL.Path)
+		if req.Header.Get("Metadata") == "true" {
+			rw.Write([]byte(`{"access_token":"abc","token_type":"Bearer"}`))
+			return
+		}
+		rw.Write([]byte("e30." + payload + ".sig"))
+	}))
+	defer server.Close()
+	// The server stands in for the default hosts as a proxy.
+	proxyURL, _ := url.Parse(server.URL)
+	proxied := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	// Without a host, $GCE_METADATA_HOST is used, and without an HTTP client
+	// a default one.
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+	if _, err := (&metahttp.GCPIdentityTokenProvider{Audience: "aud"}).Token(context.Background()); err != nil {
+		t.Errorf("gcp: %v", err)
+	}
+	t.Setenv("GCE_METADATA_HOST", "")
+	if _, err := (&metahttp.GCPIdentityTokenProvider{Audience: "aud", HTTPClient: proxied}).Token(context.Background()); err != nil {
+		t.Errorf("gcp: %v", err)
+	}
+	if _, err := (&metahttp.AzureIMDSTokenProvider{Resource: "api://partner", HTTPClient: proxied}).Token(context.Background()); err != nil {
+		t.Errorf("azure: %v", err)
+	}
+
+	want := []string{
+		strings.TrimPrefix(server.URL, "http://") + "/computeMetadata/v1/instance/service-accounts/default/identity",
+		"metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity",
+		"169.254.169.254/metadata/identity/oauth2/token",
+	}
+	if strings.Join(requested, " ") != strings.Join(want, " ") {
+		t.Errorf("expected requests to %q, got %q", want, requested)
+	}
+}
@@ -0,0 +1,63 @@
+package metahttp_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestCachedTokenProviderRefreshesAhead(t *testing.T) {
+	var fetches atomic.Int32
+	source := metahttp.TokenProviderFunc(func(ctx context.Context) (*models.Token, error) {
+		fetches.Add(1)
+		return &models.Token{AccessToken: "token", ExpiresAt: time.Now().Add(300 * time.Millisecond)}, nil
+	})
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	provider := metahttp.NewCachedTokenProvider(source, 200*time.Millisecond, logger)
+	defer provider.Close()
+
+	if _, err := provider.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("expected a single inline fetch, got %d", fetches.Load())
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if fetches.Load() < 2 {
+		t.Errorf("expected background refresh, got %d fetches", fetches.Load())
+	}
+}
+
+func TestClientWithTokenProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer token" {
+			rw.WriteHeader(http.StatusUnauthorized)
+		}
+		rw.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	provider := metahttp.TokenProviderFunc(func(ctx context.Context) (*models.Token, error) {
+		return &models.Token{AccessToken: "token"}, nil
+	})
+	metaHttpClient := metahttp.NewClient(server.URL, logger, 10*time.Second, metahttp.WithTokenProvider(provider))
+
+	var res map[string]any
+	if _, err := metaHttpClient.Get(context.Background(), "/test", map[string]string{}, &res); err != nil {
+		t.Error(err.Error())
+	}
+}
@@ -0,0 +1,49 @@
+package metahttp
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceLogger adds the trace_id and span_id of the active OpenTelemetry span
+// to every entry logged with a context, so client logs can be joined with
+// traces. Entries without a valid span context are left untouched.
+type traceLogger struct {
+	next Logger
+}
+
+func withTraceIDs(log Logger) Logger {
+	if _, ok := log.(traceLogger); ok {
+		return log
+	}
+	return traceLogger{next: log}
+}
+
+func traceArgs(ctx context.Context, args []any) []any {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return args
+	}
+	return append(args,
+		slog.String("trace_id", sc.TraceID().String()),
+		slog.String("span_id", sc.SpanID().String()),
+	)
+}
+
+func (l traceLogger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.next.DebugContext(ctx, msg, traceArgs(ctx, args)...)
+}
+
+func (l traceLogger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.next.InfoContext(ctx, msg, traceArgs(ctx, args)...)
+}
+
+func (l traceLogger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.next.WarnContext(ctx, msg, traceArgs(ctx, args)...)
+}
+
+func (l traceLogger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.next.ErrorContext(ctx, msg, traceArgs(ctx, args)...)
+}
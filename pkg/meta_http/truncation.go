@@ -0,0 +1,80 @@
+package metahttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// truncationRoundTripper makes the bodies of responses cut short by a
+// dropped connection fail with models.ErrTruncatedBody rather than a bare
+// io.ErrUnexpectedEOF, which decoders also return for malformed payloads.
+type truncationRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (t truncationRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(r)
+	if err != nil || res.Body == nil || res.Body == http.NoBody {
+		return res, err
+	}
+	res.Body = truncationBody{res.Body}
+	return res, nil
+}
+
+type truncationBody struct {
+	io.ReadCloser
+}
+
+func (b truncationBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = fmt.Errorf("%w: %w", models.ErrTruncatedBody, err)
+	}
+	return n, err
+}
+
+// truncationRetryRoundTripper reads the bodies of successful GET and HEAD
+// responses in full, sending the request again when they turn out truncated.
+// See WithTruncationRetries.
+type truncationRetryRoundTripper struct {
+	next        http.RoundTripper
+	maxAttempts int
+	delay       time.Duration
+	stats       *clientStats
+}
+
+func (t truncationRetryRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return t.next.RoundTrip(r)
+	}
+	for attempt := 1; ; attempt++ {
+		res, err := t.next.RoundTrip(r)
+		if err != nil || res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+			return res, err
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err == nil {
+			res.Body = io.NopCloser(bytes.NewReader(body))
+			res.ContentLength = int64(len(body))
+			return res, nil
+		}
+		if !errors.Is(err, models.ErrTruncatedBody) || attempt >= t.maxAttempts {
+			return nil, err
+		}
+		select {
+		case <-r.Context().Done():
+			return nil, err
+		case <-time.After(t.delay):
+		}
+		if t.stats != nil {
+			t.stats.retries.Add(1)
+		}
+	}
+}
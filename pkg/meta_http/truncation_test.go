@@ -0,0 +1,70 @@
+package metahttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// truncatingServer drops the connection mid-body for the first truncated
+// requests.
+func truncatingServer(t *testing.T, truncated int32) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body := `{"name":"meta"}`
+		if calls.Add(1) > truncated {
+			rw.Header().Set("Content-Type", "application/json")
+			rw.Write([]byte(body))
+			return
+		}
+		conn, brw, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		brw.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 15\r\n\r\n" + body[:6])
+		brw.Flush()
+		conn.Close()
+	}))
+	return server, &calls
+}
+
+func TestTruncatedResponses(t *testing.T) {
+	server, _ := truncatingServer(t, 1)
+	defer server.Close()
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	var res merchant
+	_, err := client.Get(context.Background(), "/merchants/1", nil, &res)
+	if !errors.Is(err, models.ErrTruncatedBody) || models.CategoryOf(err) != models.CategoryConnection {
+		t.Errorf("truncated body: %v (%s)", err, models.CategoryOf(err))
+	}
+}
+
+func TestTruncationRetries(t *testing.T) {
+	server, calls := truncatingServer(t, 2)
+	defer server.Close()
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithTruncationRetries(3, time.Millisecond))
+
+	var res merchant
+	if _, err := client.Get(context.Background(), "/merchants/1", nil, &res); err != nil || res.Name != "meta" {
+		t.Fatalf("call: %v %+v", err, res)
+	}
+	if calls.Load() != 3 || client.Stats().Retries != 2 {
+		t.Errorf("calls = %d, retries = %d", calls.Load(), client.Stats().Retries)
+	}
+
+	// Only idempotent reads are retried.
+	calls.Store(0)
+	_, err := client.Post(context.Background(), "/merchants", nil, map[string]string{}, &res)
+	if !errors.Is(err, models.ErrTruncatedBody) || calls.Load() != 1 {
+		t.Errorf("post: %v after %d calls", err, calls.Load())
+	}
+}
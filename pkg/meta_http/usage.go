@@ -0,0 +1,138 @@
+package metahttp
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultUsageInterval is the interval usage is aggregated over unless
+// WithUsageReporter says otherwise.
+const DefaultUsageInterval = time.Minute
+
+// EndpointUsage summarizes the calls made to one endpoint during one
+// interval, as reported by WithUsageReporter.
+type EndpointUsage struct {
+	// Start is the beginning of the interval, a multiple of its length.
+	Start    time.Time
+	Interval time.Duration
+	Method   string
+	// Route is the low-cardinality label of the endpoint, see
+	// WithRouteTemplates.
+	Route string
+	// Caller is the caller tag of the calls, see ContextWithCaller.
+	Caller string
+	Calls  int64
+	// Errors counts the calls that returned an error, HTTP errors included.
+	Errors int64
+	// Duration is the total time spent in the calls, retries included.
+	Duration time.Duration
+}
+
+type usageKey struct {
+	start  time.Time
+	method string
+	route  string
+	caller string
+}
+
+// usageReporter aggregates calls into interval buckets and reports each
+// bucket once its interval is over. Its timer only runs while there is
+// something to report.
+type usageReporter struct {
+	interval time.Duration
+	report   func([]EndpointUsage)
+
+	mu      sync.Mutex
+	buckets map[usageKey]*EndpointUsage
+	timer   *time.Timer
+	stopped bool
+}
+
+func newUsageReporter(interval time.Duration, report func([]EndpointUsage)) *usageReporter {
+	if interval <= 0 {
+		interval = DefaultUsageInterval
+	}
+	return &usageReporter{
+		interval: interval,
+		report:   report,
+		buckets:  map[usageKey]*EndpointUsage{},
+	}
+}
+
+// record counts a call in the interval it completed in.
+func (u *usageReporter) record(method, route, caller string, d time.Duration, err error) {
+	now := time.Now()
+	key := usageKey{start: now.Truncate(u.interval), method: method, route: route, caller: caller}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.stopped {
+		return
+	}
+	e, ok := u.buckets[key]
+	if !ok {
+		e = &EndpointUsage{Start: key.start, Interval: u.interval, Method: method, Route: route, Caller: caller}
+		u.buckets[key] = e
+	}
+	e.Calls++
+	if err != nil {
+		e.Errors++
+	}
+	e.Duration += d
+	if u.timer == nil {
+		u.timer = time.AfterFunc(time.Until(key.start.Add(u.interval)), func() { u.flush(false) })
+	}
+}
+
+// flush reports the buckets whose interval is over, every bucket when
+// final, and rearms the timer for the next one to end.
+func (u *usageReporter) flush(final bool) {
+	u.mu.Lock()
+	now := time.Now()
+	var due []EndpointUsage
+	var next time.Time
+	for key, e := range u.buckets {
+		end := key.start.Add(u.interval)
+		if final || !end.After(now) {
+			due = append(due, *e)
+			delete(u.buckets, key)
+		} else if next.IsZero() || end.Before(next) {
+			next = end
+		}
+	}
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+	if !next.IsZero() {
+		u.timer = time.AfterFunc(time.Until(next), func() { u.flush(false) })
+	}
+	u.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+	sort.Slice(due, func(i, j int) bool {
+		a, b := due[i], due[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Caller < b.Caller
+	})
+	u.report(due)
+}
+
+// stop reports what is left, however recent, and ignores later calls.
+func (u *usageReporter) stop() {
+	u.mu.Lock()
+	u.stopped = true
+	u.mu.Unlock()
+	u.flush(true)
+}
@@ -0,0 +1,111 @@
+package metahttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestUsageReporter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	reports := make(chan []metahttp.EndpointUsage, 10)
+	// An interval no test outlives: only Close reports.
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithUsageReporter(time.Hour, func(usage []metahttp.EndpointUsage) { reports <- usage }))
+
+	ctx := context.Background()
+	client.Get(ctx, "/orders/1", nil, nil)
+	client.Get(ctx, "/orders/2?expand=items", nil, nil)
+	client.Get(metahttp.ContextWithCaller(ctx, "checkout"), "/orders/3", nil, nil)
+	client.Get(ctx, "/missing", nil, nil)
+
+	select {
+	case usage := <-reports:
+		t.Fatalf("unexpected report before the interval ended: %+v", usage)
+	default:
+	}
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := <-reports
+	if len(usage) != 3 {
+		t.Fatalf("expected 3 endpoints, got %+v", usage)
+	}
+	missing, orders, checkout := usage[0], usage[1], usage[2]
+	if missing.Route != "/missing" || missing.Calls != 1 || missing.Errors != 1 {
+		t.Errorf("unexpected usage %+v", missing)
+	}
+	if orders.Route != "/orders/{id}" || orders.Method != http.MethodGet || orders.Caller != "" || orders.Calls != 2 || orders.Errors != 0 {
+		t.Errorf("unexpected usage %+v", orders)
+	}
+	if checkout.Caller != "checkout" || checkout.Calls != 1 {
+		t.Errorf("unexpected usage %+v", checkout)
+	}
+	if orders.Interval != time.Hour || !orders.Start.Equal(orders.Start.Truncate(time.Hour)) || orders.Duration <= 0 {
+		t.Errorf("unexpected interval %+v", orders)
+	}
+}
+
+func TestUsageReportedAfterInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	reports := make(chan []metahttp.EndpointUsage, 10)
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithUsageReporter(50*time.Millisecond, func(usage []metahttp.EndpointUsage) { reports <- usage }))
+	client.Get(context.Background(), "/ping", nil, nil)
+
+	select {
+	case usage := <-reports:
+		if len(usage) != 1 || usage[0].Calls != 1 {
+			t.Errorf("unexpected usage %+v", usage)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("usage not reported once the interval ended")
+	}
+}
+
+func TestUsageOfCallsInFlightOnClose(t *testing.T) {
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	reports := make(chan []metahttp.EndpointUsage, 10)
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithUsageReporter(time.Hour, func(usage []metahttp.EndpointUsage) { reports <- usage }))
+
+	ctx := context.Background()
+	go client.Get(ctx, "/orders/1", nil, nil)
+	<-started
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case usage := <-reports:
+		if len(usage) != 1 || usage[0].Calls != 1 {
+			t.Errorf("unexpected usage %+v", usage)
+		}
+	default:
+		t.Fatal("expected the call in flight to be reported on Close")
+	}
+}
@@ -0,0 +1,75 @@
+package metahttp
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// StructValidator validates a request payload before it is marshaled. It is
+// satisfied by *validator.Validate from github.com/go-playground/validator.
+type StructValidator interface {
+	Struct(s interface{}) error
+}
+
+// StructValidatorFunc adapts a plain function to the StructValidator interface.
+type StructValidatorFunc func(s interface{}) error
+
+func (f StructValidatorFunc) Struct(s interface{}) error {
+	return f(s)
+}
+
+// validateRequestBody runs validator against struct payloads, leaving maps,
+// slices and scalars alone.
+func validateRequestBody(validator StructValidator, body interface{}) error {
+	if validator == nil || body == nil {
+		return nil
+	}
+	t := reflect.TypeOf(body)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if err := validator.Struct(body); err != nil {
+		return &models.ValidationError{Err: err}
+	}
+	return nil
+}
+
+// validateResponseBody runs validator against a decoded struct target, or
+// each struct of a slice target.
+func validateResponseBody(validator StructValidator, v interface{}) error {
+	if validator == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+	switch elem := rv.Elem(); elem.Kind() {
+	case reflect.Struct:
+		if err := validator.Struct(rv.Interface()); err != nil {
+			return &models.ResponseValidationError{Err: err}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < elem.Len(); i++ {
+			item := elem.Index(i)
+			for item.Kind() == reflect.Ptr && !item.IsNil() {
+				item = item.Elem()
+			}
+			if item.Kind() != reflect.Struct || !item.CanAddr() {
+				continue
+			}
+			if err := validator.Struct(item.Addr().Interface()); err != nil {
+				return &models.ResponseValidationError{Err: fmt.Errorf("[%d]: %w", i, err)}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package metahttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// CloseIdleConnections closes the pooled connections not carrying a call.
+func (c *client) CloseIdleConnections() {
+	for _, t := range c.transports {
+		t.CloseIdleConnections()
+	}
+}
+
+// WarmUp opens n connections to the host of the base URL, TLS handshake
+// included, and leaves them idle in the pool so the first calls after a
+// deploy do not pay for them. It sends n concurrent OPTIONS * requests
+// directly on the pool, bypassing signing, retries and logging; whatever the
+// status, the connection is kept. n is capped to the idle connections the
+// pool keeps per host, and over HTTP/2 the requests share one connection.
+// A negative n is an error, and 0 does nothing.
+func (c *client) WarmUp(ctx context.Context, n int) error {
+	if c.closed.Load() {
+		return models.ErrClientClosed
+	}
+	if n < 0 {
+		return fmt.Errorf("warm up: negative connection count %d", n)
+	}
+	if n == 0 || c.dryRun {
+		return nil
+	}
+	if max := c.transports[0].MaxIdleConnsPerHost; max > 0 && n > max {
+		n = max
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil || base.Host == "" {
+		return models.ErrBadURL
+	}
+	target := (&url.URL{Scheme: base.Scheme, Host: base.Host}).String()
+
+	// Every request holds its connection by withholding its body until all
+	// of them got one, otherwise a fast server would let them share a
+	// connection.
+	var arrived atomic.Int32
+	ready := make(chan struct{})
+	arrive := func() {
+		if int(arrived.Add(1)) == n {
+			close(ready)
+		}
+	}
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var once sync.Once
+			trace := &httptrace.ClientTrace{GotConn: func(httptrace.GotConnInfo) { once.Do(arrive) }}
+			req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodOptions, target, &barrierBody{ctx: ctx, ready: ready})
+			if err != nil {
+				once.Do(arrive)
+				errs[i] = err
+				return
+			}
+			req.URL.Opaque = "*"
+			res, err := c.transports[0].RoundTrip(req)
+			once.Do(arrive)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// barrierBody is an empty request body whose end is withheld until ready is
+// closed.
+type barrierBody struct {
+	ctx   context.Context
+	ready <-chan struct{}
+}
+
+func (b *barrierBody) Read([]byte) (int, error) {
+	select {
+	case <-b.ready:
+		return 0, io.EOF
+	case <-b.ctx.Done():
+		return 0, b.ctx.Err()
+	}
+}
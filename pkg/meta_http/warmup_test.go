@@ -0,0 +1,58 @@
+package metahttp_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+func TestWarmUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient(server.URL+"/api", logger, 5*time.Second)
+
+	// The pool keeps at least 2 idle connections per host.
+	if err := client.WarmUp(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	pool := client.Stats().Pool
+	if pool.NewConns != 2 || pool.OpenConns != 2 {
+		t.Fatalf("expected 2 warm connections, got %+v", pool)
+	}
+
+	if _, err := client.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if pool := client.Stats().Pool; pool.NewConns != 2 || pool.ReusedConns != 1 {
+		t.Errorf("expected the call to reuse a warm connection, got %+v", pool)
+	}
+
+	client.CloseIdleConnections()
+	deadline := time.Now().Add(time.Second)
+	for client.Stats().Pool.OpenConns != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pool := client.Stats().Pool; pool.OpenConns != 0 {
+		t.Errorf("expected idle connections to be closed, got %+v", pool)
+	}
+
+	if err := client.WarmUp(context.Background(), 0); err != nil {
+		t.Errorf("warming no connection: %v", err)
+	}
+	if err := client.WarmUp(context.Background(), -1); err == nil {
+		t.Error("expected a negative count to be rejected")
+	}
+	if pool := client.Stats().Pool; pool.NewConns != 2 {
+		t.Errorf("expected no new connection, got %+v", pool)
+	}
+}
@@ -0,0 +1,272 @@
+package metahttp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// The message types of WebSocketConn.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+)
+
+const (
+	wsContinuation = 0x0
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+
+	// wsAcceptGUID is appended to the key of the handshake, RFC 6455 1.3.
+	wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// maxWebSocketMessage bounds the messages read, fragments included.
+	maxWebSocketMessage = 32 << 20
+)
+
+// WebSocketCloseError is returned by WebSocketConn.ReadMessage once the
+// server closed the connection.
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WebSocketCloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// DialWebSocket upgrades a GET of path to a WebSocket connection. The
+// handshake carries the headers of the context, the default headers, the
+// caller tag and headers, and goes through the signers and logging of the
+// client, so that realtime integrations are authenticated and correlated like
+// its calls; it is not retried nor cached. The handshake is bounded by ctx
+// and the timeout of the client, the connection is not. A handshake answered
+// with another status than 101 fails with a *models.HttpClientErrorResponse.
+func (c *client) DialWebSocket(ctx context.Context, path string, headers map[string]string) (*WebSocketConn, *models.ResponseData, error) {
+	if c.closed.Load() {
+		return nil, nil, models.ErrClientClosed
+	}
+	if c.dryRun {
+		return nil, nil, models.ErrDryRun
+	}
+	handshake := ctx
+	if c.HTTPClient.Timeout > 0 {
+		var cancel context.CancelFunc
+		handshake, cancel = context.WithTimeout(ctx, c.HTTPClient.Timeout)
+		defer cancel()
+	}
+	req, err := c.newRequest(handshake, http.MethodGet, path, headers, nil, &noCallOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, nil, err
+	}
+	challenge := base64.StdEncoding.EncodeToString(key[:])
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", challenge)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	// Upgrades need HTTP/1.1, which a transport of its own is kept to.
+	upgrader := c.transports[0].Clone()
+	upgrader.ForceAttemptHTTP2 = false
+	if upgrader.TLSClientConfig != nil {
+		upgrader.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	}
+	var transport http.RoundTripper = upgrader
+	if _, disabled := c.logger.(noopLogger); !disabled {
+		transport = loggingRoundTripper{logger: c.logger, next: transport}
+	}
+	if len(c.signers) > 0 {
+		transport = &signingRoundTripper{signers: c.signers, next: transport}
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := &models.ResponseData{Status: res.Status, StatusCode: res.StatusCode, Header: res.Header}
+	if c.responseHeaders != nil {
+		data.Header = c.responseHeaders.filter(res.Header)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		defer res.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+		return nil, data, &models.HttpClientErrorResponse{
+			StatusCode: res.StatusCode,
+			Err: models.ErrorInfo{
+				Message: fmt.Sprintf("websocket upgrade refused, status code: %d, response: %s", res.StatusCode, b),
+			},
+		}
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		res.Body.Close()
+		return nil, data, errors.New("websocket upgrade: connection not writable")
+	}
+	sum := sha1.Sum([]byte(challenge + wsAcceptGUID))
+	if !strings.EqualFold(res.Header.Get("Upgrade"), "websocket") ||
+		res.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		rwc.Close()
+		return nil, data, errors.New("websocket upgrade: invalid handshake response")
+	}
+	return &WebSocketConn{conn: rwc, r: bufio.NewReader(rwc)}, data, nil
+}
+
+// WebSocketConn is a client WebSocket connection. It answers pings itself.
+// One goroutine may read while others write.
+type WebSocketConn struct {
+	conn io.ReadWriteCloser
+	r    *bufio.Reader
+
+	wmu    sync.Mutex
+	closed bool
+}
+
+// ReadMessage returns the next message, TextMessage or BinaryMessage, with
+// its fragments joined. Once the server closes the connection it returns a
+// *WebSocketCloseError.
+func (ws *WebSocketConn) ReadMessage() (int, []byte, error) {
+	var messageType int
+	var message []byte
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case wsPing:
+			if err := ws.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			closeErr := &WebSocketCloseError{Code: 1005}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			ws.writeFrame(wsClose, payload[:min(len(payload), 2)])
+			ws.conn.Close()
+			return 0, nil, closeErr
+		case wsContinuation:
+			if messageType == 0 {
+				return 0, nil, errors.New("websocket: unexpected continuation frame")
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, errors.New("websocket: unfinished fragmented message")
+			}
+			messageType = opcode
+		default:
+			return 0, nil, fmt.Errorf("websocket: unknown opcode %d", opcode)
+		}
+		if len(message)+len(payload) > maxWebSocketMessage {
+			return 0, nil, fmt.Errorf("websocket: message larger than %d bytes", maxWebSocketMessage)
+		}
+		message = append(message, payload...)
+		if fin {
+			return messageType, message, nil
+		}
+	}
+}
+
+func (ws *WebSocketConn) readFrame() (bool, int, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.r, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode := head[0]&0x80 != 0, int(head[0]&0x0f)
+	if head[1]&0x80 != 0 {
+		return false, 0, nil, errors.New("websocket: masked frame from server")
+	}
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > maxWebSocketMessage {
+		return false, 0, nil, fmt.Errorf("websocket: frame larger than %d bytes", maxWebSocketMessage)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(ws.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends data as a single TextMessage or BinaryMessage frame.
+func (ws *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return ws.writeFrame(messageType, data)
+}
+
+// writeFrame sends a final frame, masked as clients must.
+func (ws *WebSocketConn) writeFrame(opcode int, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|byte(opcode))
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	if ws.closed {
+		return net.ErrClosed
+	}
+	if opcode == wsClose {
+		ws.closed = true
+	}
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+// Close sends a normal closure to the server and closes the connection.
+func (ws *WebSocketConn) Close() error {
+	ws.writeFrame(wsClose, binary.BigEndian.AppendUint16(nil, 1000))
+	return ws.conn.Close()
+}
@@ -0,0 +1,153 @@
+package metahttp_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// wsEchoServer accepts WebSocket upgrades, pings the client once and echoes
+// the messages it receives until the client closes.
+func wsEchoServer(t *testing.T, handshakes chan<- http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		handshakes <- r.Header.Clone()
+		if r.URL.Path == "/forbidden" {
+			rw.WriteHeader(http.StatusForbidden)
+			rw.Write([]byte(`{"error":{"message":"no stream for you"},"statusCode":403}`))
+			return
+		}
+		conn, brw, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+		brw.Write([]byte{0x89, 4, 'p', 'i', 'n', 'g'})
+		brw.Flush()
+
+		for {
+			opcode, payload, err := readClientFrame(brw.Reader)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case 0xa:
+				if string(payload) != "ping" {
+					t.Errorf("pong payload = %q", payload)
+				}
+			case 0x8:
+				brw.Write(append([]byte{0x88, byte(len(payload))}, payload...))
+				brw.Flush()
+				return
+			default:
+				// Echoed in two fragments to exercise reassembly.
+				half := len(payload) / 2
+				brw.Write(append([]byte{byte(opcode), byte(half)}, payload[:half]...))
+				brw.Write(append([]byte{0x80, byte(len(payload) - half)}, payload[half:]...))
+				brw.Flush()
+			}
+		}
+	}))
+}
+
+func readClientFrame(r *bufio.Reader) (int, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked client frame")
+	}
+	size := int(head[1] & 0x7f)
+	if size == 126 {
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		size = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	var mask [4]byte
+	io.ReadFull(r, mask[:])
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return int(head[0] & 0x0f), payload, nil
+}
+
+func TestDialWebSocket(t *testing.T) {
+	handshakes := make(chan http.Header, 2)
+	server := wsEchoServer(t, handshakes)
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithSigner(metahttp.SignerFunc(func(r *http.Request) error {
+			r.Header.Set("Authorization", "Bearer token")
+			return nil
+		})))
+	client.SetDefaultHeaders(map[string]string{"X-Client": "payments"})
+	ctx := context.WithValue(context.Background(), models.RequestID, "req-1")
+
+	ws, data, err := client.DialWebSocket(ctx, "/stream", map[string]string{"X-Channel": "quotes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	if data.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("status = %d", data.StatusCode)
+	}
+	header := <-handshakes
+	for k, want := range map[string]string{
+		string(models.RequestID): "req-1",
+		"X-Client":               "payments",
+		"X-Channel":              "quotes",
+		"Authorization":          "Bearer token",
+		"Upgrade":                "websocket",
+	} {
+		if got := header.Get(k); got != want {
+			t.Errorf("handshake header %s = %q, want %q", k, got, want)
+		}
+	}
+
+	long := make([]byte, 200)
+	for i := range long {
+		long[i] = byte('a' + i%26)
+	}
+	for _, msg := range [][]byte{[]byte("hello"), long} {
+		if err := ws.WriteMessage(metahttp.TextMessage, msg); err != nil {
+			t.Fatal(err)
+		}
+		typ, got, err := ws.ReadMessage()
+		if err != nil || typ != metahttp.TextMessage || string(got) != string(msg) {
+			t.Fatalf("echo = %d %q %v", typ, got, err)
+		}
+	}
+
+	if err := ws.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := ws.WriteMessage(metahttp.TextMessage, []byte("late")); err == nil {
+		t.Error("write after close succeeded")
+	}
+
+	_, data, err = client.DialWebSocket(ctx, "/forbidden", nil)
+	var errRes *models.HttpClientErrorResponse
+	if !errors.As(err, &errRes) || errRes.StatusCode != http.StatusForbidden || data.StatusCode != http.StatusForbidden {
+		t.Errorf("refused upgrade: %v %+v", err, data)
+	}
+}
@@ -0,0 +1,51 @@
+package metahttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestSnapshotAndReset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	ctx := metahttp.ContextWithCaller(context.Background(), "reports")
+	for i := 0; i < 3; i++ {
+		client.Get(ctx, "/ok", nil, nil)
+	}
+	client.Get(ctx, "/missing", nil, nil)
+
+	first := client.SnapshotAndReset()
+	if first.Requests != 4 || first.Errors[models.CategoryHTTP4xx] != 1 || first.Callers["reports"] != 4 {
+		t.Errorf("first window = %+v", first.Stats)
+	}
+
+	client.Get(context.Background(), "/ok", nil, nil)
+	second := client.SnapshotAndReset()
+	if second.Requests != 1 || len(second.Errors) != 0 || len(second.Callers) != 0 || second.Attempts != 1 {
+		t.Errorf("second window = %+v", second.Stats)
+	}
+	if !second.Start.Equal(first.End) || second.End.Before(second.Start) {
+		t.Errorf("windows %s-%s then %s-%s", first.Start, first.End, second.Start, second.End)
+	}
+
+	if empty := client.SnapshotAndReset(); empty.Requests != 0 || empty.BytesReceived != 0 {
+		t.Errorf("empty window = %+v", empty.Stats)
+	}
+	if total := client.Stats(); total.Requests != 5 || total.Errors[models.CategoryHTTP4xx] != 1 {
+		t.Errorf("Stats = %+v, want lifetime counters", total)
+	}
+}
@@ -0,0 +1,72 @@
+package metahttptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// AssertJSONRequestBody fails t unless the body of recorded is the JSON
+// document want: a string or []byte holding JSON, or a value marshaled with
+// encoding/json. Documents are compared semantically, ignoring formatting
+// and key order.
+func AssertJSONRequestBody(t testing.TB, recorded RecordedRequest, want interface{}) {
+	t.Helper()
+	var wantJSON []byte
+	switch w := want.(type) {
+	case string:
+		wantJSON = []byte(w)
+	case []byte:
+		wantJSON = w
+	default:
+		var err error
+		if wantJSON, err = json.Marshal(want); err != nil {
+			t.Fatalf("marshaling the expected body: %v", err)
+			return
+		}
+	}
+
+	var got, expected interface{}
+	if err := json.Unmarshal(wantJSON, &expected); err != nil {
+		t.Fatalf("the expected body is not JSON: %v", err)
+		return
+	}
+	if err := json.Unmarshal(recorded.Body, &got); err != nil {
+		t.Errorf("%s %s: request body is not JSON: %v\nbody: %s", recorded.Method, recorded.URL, err, recorded.Body)
+		return
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("%s %s: unexpected request body\ngot:  %s\nwant: %s", recorded.Method, recorded.URL, recorded.Body, wantJSON)
+	}
+}
+
+// AssertHeader fails t unless recorded carries the header key with value.
+func AssertHeader(t testing.TB, recorded RecordedRequest, key, value string) {
+	t.Helper()
+	values, ok := recorded.Header[http.CanonicalHeaderKey(key)]
+	if !ok {
+		t.Errorf("%s %s: missing header %s, want %q", recorded.Method, recorded.URL, key, value)
+		return
+	}
+	if len(values) != 1 || values[0] != value {
+		t.Errorf("%s %s: header %s is %q, want %q", recorded.Method, recorded.URL, key, values, value)
+	}
+}
+
+// AssertNoHeader fails t if recorded carries the header key, e.g. a
+// credential that must not leak.
+func AssertNoHeader(t testing.TB, recorded RecordedRequest, key string) {
+	t.Helper()
+	if values := recorded.Header.Values(key); len(values) > 0 {
+		t.Errorf("%s %s: unexpected header %s: %q", recorded.Method, recorded.URL, key, values)
+	}
+}
+
+// AssertQuery fails t unless the query parameter key of recorded is value.
+func AssertQuery(t testing.TB, recorded RecordedRequest, key, value string) {
+	t.Helper()
+	if got := recorded.URL.Query()[key]; len(got) != 1 || got[0] != value {
+		t.Errorf("%s %s: query parameter %s is %q, want %q", recorded.Method, recorded.URL, key, got, value)
+	}
+}
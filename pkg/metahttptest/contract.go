@@ -0,0 +1,172 @@
+package metahttptest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/onmetahq/meta-http/pkg/openapi"
+)
+
+// ContractVerifier checks requests and their responses against an OpenAPI
+// document and fails T on every mismatch, so that a client and the upstream
+// it is tested against cannot drift apart from the spec unnoticed. Like a
+// RequestRecorder, it works on the client side as a round tripper and on the
+// server side as a handler middleware:
+//
+//	spec, _ := openapi.Parse(doc)
+//	server := httptest.NewServer(metahttptest.NewContractVerifier(t, spec).Handler(fake))
+//
+// Requests must match an operation of the spec, carry its required query and
+// header parameters and, when it has one, a body valid against its request
+// schema. Responses must have a status documented for the operation, by code,
+// range or a "default" response, and their bodies are validated against its
+// schema. Documented responses without a JSON schema have their body
+// unchecked.
+type ContractVerifier struct {
+	T    testing.TB
+	Spec *openapi.Spec
+	// BasePath is trimmed from request paths before they are matched, for
+	// specs whose paths are relative to a server URL having a path.
+	BasePath string
+	// Next sends the verified requests, http.DefaultTransport when nil.
+	Next http.RoundTripper
+}
+
+// NewContractVerifier returns a verifier failing t on mismatches with spec.
+func NewContractVerifier(t testing.TB, spec *openapi.Spec) *ContractVerifier {
+	return &ContractVerifier{T: t, Spec: spec}
+}
+
+func (v *ContractVerifier) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		r = r.Clone(r.Context())
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	op := v.checkRequest(r.Method, r.URL, r.Header, body)
+
+	next := v.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	res, err := next.RoundTrip(r)
+	if err != nil || op == nil {
+		return res, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+	v.checkResponse(op, r.Method, r.URL, res.StatusCode, resBody)
+	return res, nil
+}
+
+// Handler verifies the requests served by next and the responses it writes.
+func (v *ContractVerifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, err := readBody(r)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		op := v.checkRequest(r.Method, r.URL, r.Header, body)
+		if op == nil {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		// The response is buffered to be validated before it is sent on.
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+		v.checkResponse(op, r.Method, r.URL, rec.Code, rec.Body.Bytes())
+		for k, values := range rec.Header() {
+			rw.Header()[k] = values
+		}
+		rw.WriteHeader(rec.Code)
+		rw.Write(rec.Body.Bytes())
+	})
+}
+
+// checkRequest reports the mismatches of a request and returns the operation
+// it matched, nil when none did.
+func (v *ContractVerifier) checkRequest(method string, u *url.URL, header http.Header, body []byte) *openapi.Operation {
+	path := strings.TrimPrefix(u.Path, v.BasePath)
+	op, _, ok := v.Spec.Match(method, path)
+	if !ok {
+		v.fail(method, u, "no operation in the spec")
+		return nil
+	}
+
+	query := u.Query()
+	for _, p := range op.Parameters {
+		if !p.Required {
+			continue
+		}
+		switch {
+		case p.In == "query" && !query.Has(p.Name):
+			v.fail(method, u, fmt.Sprintf("missing required query parameter %q", p.Name))
+		case p.In == "header" && header.Get(p.Name) == "":
+			v.fail(method, u, fmt.Sprintf("missing required header %q", p.Name))
+		}
+	}
+
+	switch {
+	case len(body) == 0:
+		if op.RequestRequired {
+			v.fail(method, u, "missing required request body")
+		}
+	case op.RequestSchema != nil:
+		if err := op.RequestSchema.ValidateJSON(body); err != nil {
+			v.fail(method, u, fmt.Sprintf("request body: %v", err))
+		}
+	}
+	return op
+}
+
+func (v *ContractVerifier) checkResponse(op *openapi.Operation, method string, u *url.URL, status int, body []byte) {
+	if _, ok := op.ResponseKey(status); !ok {
+		v.fail(method, u, fmt.Sprintf("undocumented %d response", status))
+		return
+	}
+	schema, ok := op.ResponseSchema(status)
+	if !ok {
+		return
+	}
+	if len(body) == 0 {
+		v.fail(method, u, fmt.Sprintf("%d response has no body", status))
+		return
+	}
+	if err := schema.ValidateJSON(body); err != nil {
+		v.fail(method, u, fmt.Sprintf("%d response body: %v", status, err))
+	}
+}
+
+// fail reports a mismatch with Errorf, which unlike Fatalf may be called
+// from the goroutines of a server or a client.
+func (v *ContractVerifier) fail(method string, u *url.URL, msg string) {
+	v.T.Errorf("contract: %s %s: %s", method, u.Path, msg)
+}
+
+// readBody reads the body of r, nil when it has none.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	return body, err
+}
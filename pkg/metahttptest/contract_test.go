@@ -0,0 +1,144 @@
+package metahttptest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/metahttptest"
+	"github.com/onmetahq/meta-http/pkg/openapi"
+)
+
+const ordersSpec = `{
+	"openapi": "3.0.3",
+	"paths": {
+		"/orders": {
+			"post": {
+				"parameters": [{"name": "Idempotency-Key", "in": "header", "required": true}],
+				"requestBody": {
+					"required": true,
+					"content": {"application/json": {"schema": {
+						"type": "object",
+						"required": ["amount"],
+						"properties": {"amount": {"type": "integer"}}
+					}}}
+				},
+				"responses": {
+					"201": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Order"}}}}
+				}
+			}
+		},
+		"/orders/{id}": {
+			"get": {
+				"responses": {
+					"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Order"}}}},
+					"default": {"description": "error"}
+				}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"Order": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}}}
+		}
+	}
+}`
+
+func TestContractVerifier(t *testing.T) {
+	spec, err := openapi.Parse([]byte(ordersSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("conforming", func(t *testing.T) {
+		server := metahttptest.NewServer()
+		defer server.Close()
+		server.On(http.MethodPost, "/orders").ReturnJSON(http.StatusCreated, `{"id":"ord_1"}`)
+
+		ft := &fakeT{TB: t}
+		client := &http.Client{Transport: metahttptest.NewContractVerifier(ft, spec)}
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/orders", strings.NewReader(`{"amount":100}`))
+		req.Header.Set("Idempotency-Key", "k1")
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if len(ft.failures) != 0 {
+			t.Errorf("expected no mismatch, got %q", ft.failures)
+		}
+	})
+
+	t.Run("drifted", func(t *testing.T) {
+		server := metahttptest.NewServer()
+		defer server.Close()
+		server.On(http.MethodPost, "/orders").ReturnJSON(http.StatusCreated, `{"order_id":"ord_1"}`)
+
+		ft := &fakeT{TB: t}
+		client := &http.Client{Transport: metahttptest.NewContractVerifier(ft, spec)}
+		res, err := client.Post(server.URL+"/orders", "application/json", strings.NewReader(`{"amount":"100"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		res, err = client.Get(server.URL + "/refunds")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		got := strings.Join(ft.failures, "\n")
+		for _, want := range []string{`missing required header "Idempotency-Key"`, "request body", "201 response body", "GET /refunds: no operation"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected a failure mentioning %q, got:\n%s", want, got)
+			}
+		}
+	})
+
+	t.Run("undocumented status", func(t *testing.T) {
+		server := metahttptest.NewServer()
+		defer server.Close()
+		server.On(http.MethodPost, "/orders").ReturnJSON(http.StatusInternalServerError, `{"error":"boom"}`)
+		server.On(http.MethodGet, "/orders/ord_1").ReturnJSON(http.StatusNotFound, `{"error":"not found"}`)
+
+		ft := &fakeT{TB: t}
+		client := &http.Client{Transport: metahttptest.NewContractVerifier(ft, spec)}
+		post, _ := http.NewRequest(http.MethodPost, server.URL+"/orders", strings.NewReader(`{"amount":100}`))
+		post.Header.Set("Idempotency-Key", "k1")
+		get, _ := http.NewRequest(http.MethodGet, server.URL+"/orders/ord_1", nil)
+		for _, req := range []*http.Request{post, get} {
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+		}
+
+		// The 404 falls under the default response of GET /orders/{id}.
+		if len(ft.failures) != 1 || !strings.Contains(ft.failures[0], "POST /orders: undocumented 500 response") {
+			t.Errorf("expected only the 500 to be reported, got %q", ft.failures)
+		}
+	})
+
+	t.Run("server side", func(t *testing.T) {
+		stub := metahttptest.NewServer()
+		defer stub.Close()
+		stub.On(http.MethodPost, "/orders").ReturnJSON(http.StatusCreated, map[string]string{"id": "ord_1"})
+
+		verifier := metahttptest.NewContractVerifier(t, spec)
+		verifier.BasePath = "/v1"
+		server := httptest.NewServer(verifier.Handler(http.StripPrefix("/v1", stub.Config.Handler)))
+		defer server.Close()
+
+		client := metahttp.NewClient(server.URL+"/v1", nil, 5*time.Second, metahttp.WithoutLogging())
+		var order struct{ ID string }
+		_, err := client.Post(context.Background(), "/orders", map[string]string{"Idempotency-Key": "k1"}, map[string]int{"amount": 100}, &order)
+		if err != nil || order.ID != "ord_1" {
+			t.Fatalf("unexpected response %+v, %v", order, err)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+// Package metahttptest provides helpers for testing code that calls
+// upstreams through meta-http clients: a stub Server, a RequestRecorder
+// capturing the requests an upstream receives, assertions over them, a
+// RetryRecorder capturing how calls were retried, and a ContractVerifier
+// checking traffic against an OpenAPI document.
+package metahttptest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// RecordedRequest is a request captured by a RequestRecorder, its body read
+// in full.
+type RecordedRequest struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// RequestRecorder records requests, either on the client side as a
+// round tripper or on the server side as a handler middleware. It is safe
+// for concurrent use.
+//
+//	rec := &metahttptest.RequestRecorder{}
+//	server := httptest.NewServer(rec.Handler(handler))
+//	...
+//	metahttptest.AssertHeader(t, rec.Last(), "Idempotency-Key", key)
+type RequestRecorder struct {
+	// Next sends the recorded requests, http.DefaultTransport when nil.
+	Next http.RoundTripper
+
+	mu       sync.Mutex
+	requests []RecordedRequest
+}
+
+// NewRequestRecorder returns a recorder sending requests through next.
+func NewRequestRecorder(next http.RoundTripper) *RequestRecorder {
+	return &RequestRecorder{Next: next}
+}
+
+func (rec *RequestRecorder) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, err := rec.record(r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		r = r.Clone(r.Context())
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	next := rec.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(r)
+}
+
+// Handler records the requests served by next.
+func (rec *RequestRecorder) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, err := rec.record(r)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// record captures r and returns its body, nil when it has none.
+func (rec *RequestRecorder) record(r *http.Request) ([]byte, error) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+		r.Body.Close()
+	}
+	u := *r.URL
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, RecordedRequest{
+		Method: r.Method,
+		URL:    &u,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	rec.mu.Unlock()
+	return body, nil
+}
+
+// Requests returns the requests recorded so far, oldest first.
+func (rec *RequestRecorder) Requests() []RecordedRequest {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]RecordedRequest(nil), rec.requests...)
+}
+
+// Last returns the last recorded request, the zero RecordedRequest when
+// there is none.
+func (rec *RequestRecorder) Last() RecordedRequest {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.requests) == 0 {
+		return RecordedRequest{URL: &url.URL{}, Header: http.Header{}}
+	}
+	return rec.requests[len(rec.requests)-1]
+}
+
+// Len returns the number of recorded requests.
+func (rec *RequestRecorder) Len() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.requests)
+}
+
+// Reset forgets the recorded requests.
+func (rec *RequestRecorder) Reset() {
+	rec.mu.Lock()
+	rec.requests = nil
+	rec.mu.Unlock()
+}
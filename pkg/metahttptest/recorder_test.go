@@ -0,0 +1,96 @@
+package metahttptest_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/metahttptest"
+)
+
+// fakeT collects the failures of assertions expected to fail.
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+}
+
+func TestRequestRecorder(t *testing.T) {
+	rec := &metahttptest.RequestRecorder{}
+	var served string
+	server := httptest.NewServer(rec.Handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		served = string(b)
+		rw.WriteHeader(http.StatusNoContent)
+	})))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging())
+	_, err := client.Post(context.Background(), "/orders", map[string]string{"Idempotency-Key": "k1"},
+		map[string]any{"amount": 100, "currency": "USD"}, nil,
+		metahttp.WithQuery(url.Values{"dry_run": {"true"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Len() != 1 || served == "" {
+		t.Fatalf("expected 1 recorded request still served with its body, got %d, %q", rec.Len(), served)
+	}
+	last := rec.Last()
+	metahttptest.AssertJSONRequestBody(t, last, `{"currency": "USD", "amount": 100}`)
+	metahttptest.AssertJSONRequestBody(t, last, map[string]any{"amount": 100, "currency": "USD"})
+	metahttptest.AssertHeader(t, last, "idempotency-key", "k1")
+	metahttptest.AssertNoHeader(t, last, "Authorization")
+	metahttptest.AssertQuery(t, last, "dry_run", "true")
+
+	f := &fakeT{}
+	metahttptest.AssertJSONRequestBody(f, last, `{"amount": 200, "currency": "USD"}`)
+	metahttptest.AssertHeader(f, last, "Idempotency-Key", "k2")
+	metahttptest.AssertHeader(f, last, "X-Missing", "v")
+	metahttptest.AssertNoHeader(f, last, "Idempotency-Key")
+	metahttptest.AssertQuery(f, last, "dry_run", "false")
+	if len(f.failures) != 5 {
+		t.Errorf("expected 5 failures, got %q", f.failures)
+	}
+
+	rec.Reset()
+	if rec.Len() != 0 || rec.Last().Method != "" {
+		t.Error("expected Reset to forget the requests")
+	}
+}
+
+func TestRequestRecorderRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		io.Copy(rw, r.Body)
+	}))
+	defer server.Close()
+
+	rec := metahttptest.NewRequestRecorder(nil)
+	res, err := (&http.Client{Transport: rec}).Post(server.URL+"/echo", "application/json", strings.NewReader(`{"ok":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	if string(b) != `{"ok":true}` {
+		t.Errorf("expected the body to still be sent, got %q", b)
+	}
+	metahttptest.AssertJSONRequestBody(t, rec.Last(), `{"ok": true}`)
+	metahttptest.AssertHeader(t, rec.Last(), "Content-Type", "application/json")
+}
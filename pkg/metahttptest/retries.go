@@ -0,0 +1,80 @@
+package metahttptest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+// RetryRecorder records the attempt timeline of the calls of a client, to
+// assert how they were retried:
+//
+//	rec := &metahttptest.RetryRecorder{}
+//	client := metahttp.NewClientWithRetry(url, nil, timeout, retry, metahttp.WithRetryObserver(rec.Observe))
+//	...
+//	metahttptest.AssertAttempts(t, rec, 3)
+type RetryRecorder struct {
+	mu       sync.Mutex
+	attempts []metahttp.RetryAttempt
+}
+
+// Observe records an attempt, to be passed to metahttp.WithRetryObserver.
+func (rec *RetryRecorder) Observe(a metahttp.RetryAttempt) {
+	rec.mu.Lock()
+	rec.attempts = append(rec.attempts, a)
+	rec.mu.Unlock()
+}
+
+// Attempts returns the attempts recorded so far, in the order they ended.
+func (rec *RetryRecorder) Attempts() []metahttp.RetryAttempt {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]metahttp.RetryAttempt(nil), rec.attempts...)
+}
+
+// Delays returns the delays chosen before each retry.
+func (rec *RetryRecorder) Delays() []time.Duration {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	var delays []time.Duration
+	for _, a := range rec.attempts {
+		if a.Retrying {
+			delays = append(delays, a.Delay)
+		}
+	}
+	return delays
+}
+
+// Reset forgets the recorded attempts.
+func (rec *RetryRecorder) Reset() {
+	rec.mu.Lock()
+	rec.attempts = nil
+	rec.mu.Unlock()
+}
+
+// AssertAttempts fails t unless rec recorded want attempts, listing them
+// otherwise.
+func AssertAttempts(t testing.TB, rec *RetryRecorder, want int) {
+	t.Helper()
+	attempts := rec.Attempts()
+	if len(attempts) == want {
+		return
+	}
+	var b strings.Builder
+	for _, a := range attempts {
+		fmt.Fprintf(&b, "\n  #%d %s %s: ", a.Attempt, a.Method, a.URL)
+		if a.Err != nil {
+			b.WriteString(a.Err.Error())
+		} else {
+			fmt.Fprintf(&b, "%d", a.Status)
+		}
+		if a.Retrying {
+			fmt.Fprintf(&b, ", retrying in %s", a.Delay)
+		}
+	}
+	t.Errorf("expected %d attempts, got %d:%s", want, len(attempts), b.String())
+}
@@ -0,0 +1,57 @@
+package metahttptest_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/metahttptest"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestRetryRecorder(t *testing.T) {
+	server := metahttptest.NewServer()
+	defer server.Close()
+	server.On(http.MethodGet, "/orders/{id}").
+		ReturnStatus(http.StatusServiceUnavailable).
+		Then().ReturnStatus(http.StatusBadGateway).
+		Then().ReturnJSON(http.StatusOK, `{"id":"ord_1"}`)
+	server.On(http.MethodGet, "/health").ReturnStatus(http.StatusNoContent)
+
+	rec := &metahttptest.RetryRecorder{}
+	client := metahttp.NewClientWithRetry(server.URL, nil, 5*time.Second, models.Retry{
+		MaxRetries:        4,
+		DelayBetweenRetry: time.Millisecond,
+		Validator:         func(status int) bool { return status < 500 },
+	}, metahttp.WithoutLogging(), metahttp.WithRetryObserver(rec.Observe))
+
+	if _, err := client.Get(context.Background(), "/orders/ord_1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	metahttptest.AssertAttempts(t, rec, 3)
+	attempts := rec.Attempts()
+	for i, status := range []int{503, 502, 200} {
+		if a := attempts[i]; a.Attempt != i+1 || a.Status != status || a.Retrying != (i < 2) || a.Method != http.MethodGet {
+			t.Errorf("unexpected attempt %d: %+v", i+1, a)
+		}
+	}
+	if delays := rec.Delays(); len(delays) != 2 || delays[0] != time.Millisecond || delays[1] != time.Millisecond {
+		t.Errorf("expected 2 delays of 1ms, got %v", delays)
+	}
+
+	// Calls that are not retried have a single attempt.
+	rec.Reset()
+	if _, err := client.Get(context.Background(), "/health", nil, nil, metahttp.WithRetry(models.Retry{MaxRetries: 1, Validator: func(int) bool { return true }})); err != nil {
+		t.Fatal(err)
+	}
+	metahttptest.AssertAttempts(t, rec, 1)
+
+	ft := &fakeT{TB: t}
+	metahttptest.AssertAttempts(ft, rec, 2)
+	if len(ft.failures) != 1 || !strings.Contains(ft.failures[0], "#1 GET "+server.URL+"/health: 204") {
+		t.Errorf("expected the timeline in the failure, got %q", ft.failures)
+	}
+}
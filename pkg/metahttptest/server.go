@@ -0,0 +1,221 @@
+package metahttptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// Server is a stub upstream configured declaratively:
+//
+//	server := metahttptest.NewServer()
+//	defer server.Close()
+//	server.On(http.MethodPost, "/orders").
+//		ReturnStatus(http.StatusServiceUnavailable).
+//		Then().ReturnJSON(http.StatusCreated, order).WithDelay(10 * time.Millisecond)
+//
+// Requests matching no stub get a 501 Not Implemented in the error format
+// of models.HttpClientErrorResponse. Every request is recorded in Recorder.
+type Server struct {
+	*httptest.Server
+	Recorder *RequestRecorder
+
+	mu    sync.Mutex
+	stubs []*Stub
+}
+
+// NewServer starts a stub server, to be closed with Close.
+func NewServer() *Server {
+	s := &Server{Recorder: &RequestRecorder{}}
+	s.Server = httptest.NewServer(s.Recorder.Handler(http.HandlerFunc(s.serve)))
+	return s
+}
+
+// On stubs the requests with method and path. path is matched against the
+// request path, ignoring the query, and its {param} segments match any
+// segment. When several stubs match, the last one registered wins, so that
+// a test can override a shared setup.
+func (s *Server) On(method, path string) *Stub {
+	st := &Stub{
+		method:    strings.ToUpper(method),
+		segments:  strings.Split(strings.Trim(path, "/"), "/"),
+		responses: []*stubResponse{{status: http.StatusOK, header: http.Header{}}},
+	}
+	s.mu.Lock()
+	s.stubs = append(s.stubs, st)
+	s.mu.Unlock()
+	return st
+}
+
+func (s *Server) serve(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var match *Stub
+	for i := len(s.stubs) - 1; i >= 0; i-- {
+		if s.stubs[i].matches(r) {
+			match = s.stubs[i]
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if match == nil {
+		writeJSON(rw, http.StatusNotImplemented, models.HttpClientErrorResponse{
+			StatusCode: http.StatusNotImplemented,
+			Err:        models.ErrorInfo{Message: fmt.Sprintf("no stub for %s %s", r.Method, r.URL.Path)},
+		})
+		return
+	}
+	match.next().write(rw, r)
+}
+
+// Stub is the behaviour of the server for the requests of one route. Its
+// methods configure the current response; Then starts the next one. The
+// responses are served in order, the last one for every remaining call.
+type Stub struct {
+	method   string
+	segments []string
+
+	mu        sync.Mutex
+	responses []*stubResponse
+	calls     int
+}
+
+type stubResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	delay  time.Duration
+}
+
+func (st *Stub) matches(r *http.Request) bool {
+	if r.Method != st.method {
+		return false
+	}
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) != len(st.segments) {
+		return false
+	}
+	for i, s := range st.segments {
+		if s != segments[i] && !(len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}') {
+			return false
+		}
+	}
+	return true
+}
+
+// next returns the response of the current call.
+func (st *Stub) next() *stubResponse {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	res := st.responses[min(st.calls, len(st.responses)-1)]
+	st.calls++
+	return res
+}
+
+// current is the response being configured.
+func (st *Stub) current() *stubResponse {
+	return st.responses[len(st.responses)-1]
+}
+
+// ReturnJSON answers with status and body as JSON. body is sent as-is when
+// it is a string or []byte, and marshaled otherwise; a body that cannot be
+// marshaled panics, being a mistake of the test.
+func (st *Stub) ReturnJSON(status int, body interface{}) *Stub {
+	var b []byte
+	switch v := body.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			panic(fmt.Sprintf("metahttptest: marshaling stub body: %v", err))
+		}
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	res := st.current()
+	res.status, res.body = status, b
+	res.header.Set("Content-Type", "application/json")
+	return st
+}
+
+// ReturnError answers with status and an error body in the format of
+// models.HttpClientErrorResponse.
+func (st *Stub) ReturnError(status, code int, message string) *Stub {
+	return st.ReturnJSON(status, models.HttpClientErrorResponse{
+		StatusCode: status,
+		Err:        models.ErrorInfo{Code: code, Message: message},
+	})
+}
+
+// ReturnStatus answers with status and no body.
+func (st *Stub) ReturnStatus(status int) *Stub {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	res := st.current()
+	res.status, res.body = status, nil
+	return st
+}
+
+// WithHeader adds a header to the response.
+func (st *Stub) WithHeader(key, value string) *Stub {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.current().header.Add(key, value)
+	return st
+}
+
+// WithDelay delays the response, or until the client gives up.
+func (st *Stub) WithDelay(delay time.Duration) *Stub {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.current().delay = delay
+	return st
+}
+
+// Then starts configuring the response of the next call, a 200 with no
+// body until told otherwise.
+func (st *Stub) Then() *Stub {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.responses = append(st.responses, &stubResponse{status: http.StatusOK, header: http.Header{}})
+	return st
+}
+
+// Calls returns the number of requests the stub answered.
+func (st *Stub) Calls() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.calls
+}
+
+func (res *stubResponse) write(rw http.ResponseWriter, r *http.Request) {
+	if res.delay > 0 {
+		timer := time.NewTimer(res.delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for k, values := range res.header {
+		rw.Header()[k] = values
+	}
+	rw.WriteHeader(res.status)
+	rw.Write(res.body)
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v)
+}
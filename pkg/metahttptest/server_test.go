@@ -0,0 +1,66 @@
+package metahttptest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/metahttptest"
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+func TestStubServer(t *testing.T) {
+	server := metahttptest.NewServer()
+	defer server.Close()
+
+	orders := server.On(http.MethodPost, "/orders").
+		ReturnError(http.StatusServiceUnavailable, 1001, "try again").
+		Then().ReturnJSON(http.StatusCreated, map[string]string{"id": "ord_1"}).WithHeader("Location", "/orders/ord_1")
+	server.On(http.MethodGet, "/orders/{id}").ReturnJSON(http.StatusOK, `{"id":"ord_any"}`)
+	server.On(http.MethodGet, "/orders/ord_2").ReturnStatus(http.StatusNotFound)
+	server.On(http.MethodGet, "/slow").WithDelay(200 * time.Millisecond)
+
+	client := metahttp.NewClientWithRetry(server.URL, nil, 5*time.Second, models.Retry{
+		MaxRetries: 3,
+		Validator:  func(status int) bool { return status < 500 },
+	}, metahttp.WithoutLogging())
+	ctx := context.Background()
+
+	var created struct{ ID string }
+	data, err := client.Post(ctx, "/orders", nil, map[string]int{"amount": 100}, &created)
+	if err != nil || created.ID != "ord_1" || data.StatusCode != http.StatusCreated || data.Header.Get("Location") != "/orders/ord_1" {
+		t.Fatalf("unexpected response %+v, %+v, %v", data, created, err)
+	}
+	if orders.Calls() != 2 || server.Recorder.Len() != 2 {
+		t.Errorf("expected the first response to be retried, got %d calls", orders.Calls())
+	}
+	metahttptest.AssertJSONRequestBody(t, server.Recorder.Last(), `{"amount":100}`)
+
+	// The last response repeats.
+	client.Post(ctx, "/orders", nil, map[string]int{"amount": 100}, &created)
+	if orders.Calls() != 3 || created.ID != "ord_1" {
+		t.Errorf("expected the last response to repeat, got %d calls", orders.Calls())
+	}
+
+	var order struct{ ID string }
+	if _, err := client.Get(ctx, "/orders/ord_1", nil, &order); err != nil || order.ID != "ord_any" {
+		t.Errorf("expected the templated stub to match, got %+v, %v", order, err)
+	}
+	var hce *models.HttpClientErrorResponse
+	if _, err := client.Get(ctx, "/orders/ord_2", nil, &order); !errors.As(err, &hce) || hce.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the later stub to win, got %v", err)
+	}
+
+	if _, err := client.Get(ctx, "/slow", nil, nil, metahttp.WithTimeout(50*time.Millisecond)); models.CategoryOf(err) != models.CategoryTimeout {
+		t.Errorf("expected the delay to time the call out, got %v", err)
+	}
+
+	_, err = client.Do(ctx, http.MethodDelete, "/orders/ord_1", nil, nil, nil)
+	if !errors.As(err, &hce) || hce.StatusCode != http.StatusNotImplemented || !strings.Contains(hce.Err.Message, "no stub for DELETE /orders/ord_1") {
+		t.Errorf("expected unstubbed requests to be reported, got %v", err)
+	}
+}
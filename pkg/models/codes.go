@@ -0,0 +1,34 @@
+package models
+
+import "sync"
+
+var errorCodes sync.Map // map[int]error
+
+// RegisterErrorCode maps an error code of the onmeta error envelope to err,
+// so that error responses carrying the code match err through errors.Is, or
+// errors.As when err is a typed error:
+//
+//	var ErrInsufficientBalance = errors.New("insufficient balance")
+//
+//	func init() {
+//		models.RegisterErrorCode(4021, ErrInsufficientBalance)
+//	}
+//
+//	if errors.Is(err, ErrInsufficientBalance) { ... }
+//
+// Registering a code again replaces the previous error, and registering nil
+// removes it.
+func RegisterErrorCode(code int, err error) {
+	if err == nil {
+		errorCodes.Delete(code)
+		return
+	}
+	errorCodes.Store(code, err)
+}
+
+// ErrorForCode returns the error registered for code, nil if there is none.
+func ErrorForCode(code int) error {
+	err, _ := errorCodes.Load(code)
+	e, _ := err.(error)
+	return e
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCategory is a machine readable classification of a failed call, for
+// metrics and alerting.
+type ErrorCategory string
+
+const (
+	CategoryTimeout    ErrorCategory = "timeout"
+	CategoryCanceled   ErrorCategory = "canceled"
+	CategoryConnection ErrorCategory = "connection"
+	CategoryDNS        ErrorCategory = "dns"
+	CategoryTLS        ErrorCategory = "tls"
+	CategoryDecode     ErrorCategory = "decode"
+	CategoryHTTP3xx    ErrorCategory = "http_3xx"
+	CategoryHTTP4xx    ErrorCategory = "http_4xx"
+	CategoryHTTP5xx    ErrorCategory = "http_5xx"
+	// CategoryRequest covers calls rejected before being sent, e.g. by
+	// validation, path checks or client policies.
+	CategoryRequest ErrorCategory = "request"
+	CategoryUnknown ErrorCategory = "unknown"
+)
+
+// CategorizedError attaches a category to an error that does not carry one
+// itself. Use errors.Is and errors.As to inspect the underlying error.
+type CategorizedError struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *CategorizedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CategorizedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CategorizedError) ErrorCategory() ErrorCategory {
+	return e.Category
+}
+
+// CategoryOf returns the category of err, CategoryUnknown when it has none.
+func CategoryOf(err error) ErrorCategory {
+	var categorized interface{ ErrorCategory() ErrorCategory }
+	if errors.As(err, &categorized) {
+		return categorized.ErrorCategory()
+	}
+	return CategoryUnknown
+}
+
+// categorized creates a sentinel error that carries its own category, so
+// errors wrapping it need no further categorization.
+func categorized(category ErrorCategory, msg string) error {
+	return &CategorizedError{Category: category, Err: errors.New(msg)}
+}
+
+// ErrCanceled is wrapped by errors of calls whose context was canceled by
+// the caller.
+var ErrCanceled = categorized(CategoryCanceled, "request canceled")
+
+// ErrDeadlineExceeded is wrapped by every *TimeoutError.
+var ErrDeadlineExceeded = categorized(CategoryTimeout, "deadline exceeded")
+
+// TimeoutSource names the timeout that ended a call.
+type TimeoutSource string
+
+const (
+	// TimeoutContext is the deadline of the caller's context.
+	TimeoutContext TimeoutSource = "context"
+	// TimeoutClient is the overall timeout the client was created with.
+	TimeoutClient TimeoutSource = "client"
+	// TimeoutCall is a timeout of the call alone, set WithTimeout, by its
+	// endpoint or by a health check.
+	TimeoutCall TimeoutSource = "call"
+	// TimeoutAttempt is a per-attempt limit such as the dial, TLS handshake,
+	// response header or body idle timeouts.
+	TimeoutAttempt TimeoutSource = "attempt"
+)
+
+// TimeoutError is returned when a call ran out of time. It matches both
+// ErrDeadlineExceeded and the underlying error with errors.Is.
+type TimeoutError struct {
+	Source TimeoutSource
+	Err    error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("deadline exceeded (%s timeout): %v", e.Source, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() []error {
+	return []error{ErrDeadlineExceeded, e.Err}
+}
+
+func (e *TimeoutError) ErrorCategory() ErrorCategory {
+	return CategoryTimeout
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -17,14 +18,61 @@ const (
 	APIContextKey    contextKey = "apikey"
 	AuthorizationKey contextKey = "Authorization"
 	XForwardedFor    contextKey = "X-Forwarded-For"
+	// FeatureFlags carries the flag decisions made at the edge, see
+	// utils.ContextWithFeatureFlags.
+	FeatureFlags contextKey = "x-feature-flags"
 )
 
-var ContextKeys = []contextKey{UserID, TenantID, RequestID, MerchantAPIKey, APIContextKey, AuthorizationKey, XForwardedFor}
+var ContextKeys = []contextKey{UserID, TenantID, RequestID, MerchantAPIKey, APIContextKey, AuthorizationKey, XForwardedFor, FeatureFlags}
 
 type ResponseData struct {
 	Status     string // e.g. "200 OK"
 	StatusCode int    // e.g. 200
 	Header     http.Header
+	// Redirect is set for 3xx responses the client did not follow.
+	Redirect *RedirectionResult
+	// ClockSkew is how far the clock of the server is ahead of the local one
+	// according to the Date header, negative when it is behind and 0 when
+	// the response has no Date or the clocks agree within its precision.
+	ClockSkew time.Duration
+	// BytesSent and BytesReceived are the sizes of the request and response
+	// bodies as sent over the wire, every attempt included. BytesReceived
+	// only counts the body read when the call returned, and gzip bodies the
+	// Go transport decoded transparently, without WithAcceptEncoding, count
+	// decoded.
+	BytesSent     int64
+	BytesReceived int64
+}
+
+// RedirectionResult describes a 3xx response returned instead of followed.
+type RedirectionResult struct {
+	StatusCode int
+	// Location is the target resolved against the request URL, nil when
+	// the response has none, e.g. a 300 Multiple Choices.
+	Location *url.URL
+}
+
+// Cookies parses the Set-Cookie headers of the response.
+func (rd *ResponseData) Cookies() []*http.Cookie {
+	if rd == nil {
+		return nil
+	}
+	return (&http.Response{Header: rd.Header}).Cookies()
+}
+
+// ETag returns the entity tag of the response, to be sent back with a
+// conditional write.
+func (rd *ResponseData) ETag() string {
+	if rd == nil {
+		return ""
+	}
+	return rd.Header.Get("ETag")
+}
+
+// NotModified reports a 304 answer to a conditional GET: the copy the
+// validators came from is still current and the target was left untouched.
+func (rd *ResponseData) NotModified() bool {
+	return rd != nil && rd.StatusCode == http.StatusNotModified
 }
 
 type Retry struct {
@@ -33,10 +81,23 @@ type Retry struct {
 	Validator         func(int) bool
 }
 
+type Token struct {
+	AccessToken string
+	TokenType   string    // defaults to "Bearer"
+	ExpiresAt   time.Time // zero means the token never expires
+}
+
+func (t *Token) Valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.ExpiresAt.IsZero() || now.Before(t.ExpiresAt))
+}
+
 type HttpClientErrorResponse struct {
 	Success    bool      `json:"success"`
 	Err        ErrorInfo `json:"error"`
 	StatusCode int       `json:"_"`
+	// Category is set when the error did not come from the response status,
+	// e.g. CategoryDecode for a body that could not be decoded.
+	Category ErrorCategory `json:"-"`
 }
 
 type ErrorInfo struct {
@@ -48,4 +109,124 @@ func (hce *HttpClientErrorResponse) Error() string {
 	return fmt.Sprintf("StatusCode: %d, ErrorCode: %d, Message: %s", hce.StatusCode, hce.Err.Code, hce.Err.Message)
 }
 
-var ErrBadURL = errors.New("invalid url")
+// Unwrap returns the error registered for the error code of the response,
+// see RegisterErrorCode.
+func (hce *HttpClientErrorResponse) Unwrap() error {
+	if hce.Err.Code == 0 {
+		return nil
+	}
+	return ErrorForCode(hce.Err.Code)
+}
+
+// Is reports a 412 Precondition Failed as ErrConflict.
+func (hce *HttpClientErrorResponse) Is(target error) bool {
+	return target == ErrConflict && hce.StatusCode == http.StatusPreconditionFailed
+}
+
+func (hce *HttpClientErrorResponse) ErrorCategory() ErrorCategory {
+	switch {
+	case hce.Category != "":
+		return hce.Category
+	case hce.StatusCode >= 500:
+		return CategoryHTTP5xx
+	case hce.StatusCode >= 400:
+		return CategoryHTTP4xx
+	}
+	return CategoryUnknown
+}
+
+type UnsupportedContentTypeError struct {
+	ContentType string
+}
+
+func (e *UnsupportedContentTypeError) Error() string {
+	return fmt.Sprintf("unsupported response content type: %q", e.ContentType)
+}
+
+func (e *UnsupportedContentTypeError) ErrorCategory() ErrorCategory {
+	return CategoryDecode
+}
+
+// ValidationError is returned when a request payload fails validation and
+// was therefore never sent.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("request validation failed: %v", e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) ErrorCategory() ErrorCategory {
+	return CategoryRequest
+}
+
+// ResponseValidationError is returned when a decoded response fails the
+// response validator of the client. The target holds the decoded response
+// all the same.
+type ResponseValidationError struct {
+	Err error
+}
+
+func (e *ResponseValidationError) Error() string {
+	return fmt.Sprintf("response validation failed: %v", e.Err)
+}
+
+func (e *ResponseValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ResponseValidationError) ErrorCategory() ErrorCategory {
+	return CategoryDecode
+}
+
+var ErrBadURL = categorized(CategoryRequest, "invalid url")
+
+var ErrUnsafePath = categorized(CategoryRequest, "unsafe url path")
+
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+var ErrBodyReadTimeout = categorized(CategoryTimeout, "response body read idle timeout")
+
+// ErrTruncatedBody is wrapped by the errors of responses whose body ended
+// before its Content-Length, or its last chunk, was read: the connection
+// dropped mid-body and the call can be retried.
+var ErrTruncatedBody = categorized(CategoryConnection, "response body truncated")
+
+var ErrPanic = errors.New("recovered from panic")
+
+var ErrMissingTenant = categorized(CategoryRequest, "no tenant id in context")
+
+var ErrRateLimited = categorized(CategoryRequest, "outbound rate limit exceeded")
+
+// ErrLoadShed is wrapped by the errors of calls shed by the load shedder
+// of the client.
+var ErrLoadShed = categorized(CategoryRequest, "call shed under load")
+
+// ErrConcurrencyLimited is wrapped by the errors of requests that found the
+// queue of the concurrency limit full, or gave up waiting in it.
+var ErrConcurrencyLimited = categorized(CategoryRequest, "outbound concurrency limit exceeded")
+
+var ErrBlockedDestination = categorized(CategoryRequest, "destination address not allowed")
+
+var ErrMethodNotAllowed = categorized(CategoryRequest, "method not allowed for this client")
+
+var ErrInvalidConfig = categorized(CategoryRequest, "invalid client configuration")
+
+// ErrConflict matches errors of conditional writes whose precondition no
+// longer holds, i.e. the resource was modified since it was read.
+var ErrConflict = categorized(CategoryHTTP4xx, "precondition failed: resource was modified")
+
+// ErrUnexpectedRedirect is wrapped by the errors of 3xx responses of
+// clients configured with metahttp.RejectRedirects.
+var ErrUnexpectedRedirect = categorized(CategoryHTTP3xx, "unexpected redirect")
+
+var ErrClientClosed = categorized(CategoryRequest, "client is closed")
+
+// ErrDryRun is returned by the operations a client in dry-run mode cannot
+// fake, such as opening a WebSocket.
+var ErrDryRun = categorized(CategoryRequest, "not available in dry-run mode")
@@ -0,0 +1,187 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxScale bounds the exponents ParseDecimal accepts, so that "1e999999999"
+// from an upstream does not expand into a billion digits.
+const maxScale = 1000
+
+// Decimal is an arbitrary-precision decimal number, for amounts that must
+// not drift through float64 rounding. It decodes from JSON numbers and
+// strings alike, keeping every digit, and encodes to a JSON string, as most
+// payment APIs expect. The zero value is 0.
+//
+// A Decimal keeps the scale it was parsed with, so "10.50" prints as
+// "10.50"; Cmp compares values regardless of scale.
+type Decimal struct {
+	// unscaled is never mutated once set, so that copies of a Decimal can
+	// share it. nil is 0.
+	unscaled *big.Int
+	scale    int32
+}
+
+// Money is an amount in a currency, validated by Validate.
+type Money struct {
+	Amount   Decimal `json:"amount" money:"amount"`
+	Currency string  `json:"currency" money:"currency"`
+}
+
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
+
+// ParseDecimal parses a decimal number such as "-12.50", or one with an
+// exponent such as "1.5e3" as found in JSON numbers.
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		if exp, err = strconv.ParseInt(s[i+1:], 10, 32); err != nil {
+			return Decimal{}, fmt.Errorf("money: invalid decimal %q", s)
+		}
+		mantissa = s[:i]
+	}
+	if !IsDecimal(mantissa) {
+		return Decimal{}, fmt.Errorf("money: invalid decimal %q", s)
+	}
+	intPart, frac, _ := strings.Cut(mantissa, ".")
+	scale := int64(len(frac)) - exp
+	if scale > maxScale || scale < -maxScale {
+		return Decimal{}, fmt.Errorf("money: decimal %q out of range", s)
+	}
+
+	sign := ""
+	if intPart != "" && (intPart[0] == '+' || intPart[0] == '-') {
+		sign, intPart = intPart[:1], intPart[1:]
+	}
+	unscaled, _ := new(big.Int).SetString(sign+intPart+frac, 10)
+	d := Decimal{unscaled: unscaled, scale: int32(scale)}
+	if d.scale < 0 {
+		d = d.rescale(0)
+	}
+	return d, nil
+}
+
+// MustParseDecimal is ParseDecimal panicking on invalid input, for constants.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// NewDecimal returns unscaled * 10^-scale, e.g. NewDecimal(1050, 2) is 10.50.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	d := Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+	if scale < 0 {
+		d = d.rescale(0)
+	}
+	return d
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns d with a scale no smaller than its own.
+func (d Decimal) rescale(scale int32) Decimal {
+	if scale <= d.scale {
+		return d
+	}
+	shift := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)-int64(d.scale)), nil)
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), shift), scale: scale}
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Sign returns -1, 0 or 1 as d is negative, zero or positive.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and e by value: -1 when d < e, 0 when they are equal, such
+// as 1.5 and 1.50, and 1 when d > e.
+func (d Decimal) Cmp(e Decimal) int {
+	scale := max(d.scale, e.scale)
+	return d.rescale(scale).int().Cmp(e.rescale(scale).int())
+}
+
+// Add returns d + e, at the larger scale of both.
+func (d Decimal) Add(e Decimal) Decimal {
+	scale := max(d.scale, e.scale)
+	return Decimal{unscaled: new(big.Int).Add(d.rescale(scale).int(), e.rescale(scale).int()), scale: scale}
+}
+
+// Sub returns d - e, at the larger scale of both.
+func (d Decimal) Sub(e Decimal) Decimal {
+	return d.Add(e.Neg())
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// String formats d with its scale, e.g. "-10.50".
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// UnmarshalJSON accepts JSON numbers and strings holding a number. null
+// leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	return d.UnmarshalText(data)
+}
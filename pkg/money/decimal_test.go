@@ -0,0 +1,78 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/onmetahq/meta-http/pkg/money"
+)
+
+func TestDecimal(t *testing.T) {
+	for in, want := range map[string]string{
+		"10.50":                          "10.50",
+		"-0.05":                          "-0.05",
+		"+3":                             "3",
+		".5":                             "0.5",
+		"-.5":                            "-0.5",
+		"1.5e3":                          "1500",
+		"25e-4":                          "0.0025",
+		"12345678901234567890.123456789": "12345678901234567890.123456789",
+	} {
+		d, err := money.ParseDecimal(in)
+		if err != nil || d.String() != want {
+			t.Errorf("ParseDecimal(%q) = %s, %v, want %s", in, d, err, want)
+		}
+	}
+	for _, in := range []string{"", "abc", "1.2.3", "1e", "NaN", "1,5", "1e999999999"} {
+		if _, err := money.ParseDecimal(in); err == nil {
+			t.Errorf("expected ParseDecimal(%q) to fail", in)
+		}
+	}
+
+	// 0.1 + 0.2 is exactly 0.3, unlike with float64.
+	sum := money.MustParseDecimal("0.1").Add(money.MustParseDecimal("0.2"))
+	if sum.Cmp(money.MustParseDecimal("0.30")) != 0 || sum.String() != "0.3" {
+		t.Errorf("expected 0.3, got %s", sum)
+	}
+	if diff := money.NewDecimal(1050, 2).Sub(money.MustParseDecimal("10.5")); !diff.IsZero() {
+		t.Errorf("expected 10.50 - 10.5 to be zero, got %s", diff)
+	}
+	var zero money.Decimal
+	if zero.String() != "0" || zero.Cmp(money.MustParseDecimal("-1")) != 1 || zero.Neg().Sign() != 0 {
+		t.Errorf("unexpected zero value %s", zero)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var settlement struct {
+		Gross money.Decimal  `json:"gross"`
+		Net   money.Money    `json:"net"`
+		Fee   *money.Decimal `json:"fee"`
+	}
+	doc := `{"gross": 9007199254740993.01, "net": {"amount": "9007199254740992.99", "currency": "USD"}, "fee": null}`
+	if err := json.Unmarshal([]byte(doc), &settlement); err != nil {
+		t.Fatal(err)
+	}
+	if settlement.Gross.String() != "9007199254740993.01" || settlement.Fee != nil {
+		t.Errorf("expected the digits to be kept, got %s", settlement.Gross)
+	}
+	if fee := settlement.Gross.Sub(settlement.Net.Amount); fee.String() != "0.02" {
+		t.Errorf("expected a 0.02 fee, got %s", fee)
+	}
+	if err := money.Validate(&settlement); err != nil {
+		t.Errorf("expected a valid settlement, got %v", err)
+	}
+
+	b, err := json.Marshal(settlement.Net)
+	if err != nil || string(b) != `{"amount":"9007199254740992.99","currency":"USD"}` {
+		t.Errorf("unexpected encoding %s, %v", b, err)
+	}
+	if err := json.Unmarshal([]byte(`{"gross": "ten"}`), &settlement); err == nil {
+		t.Error("expected an invalid amount to fail decoding")
+	}
+
+	settlement.Net.Amount = money.MustParseDecimal("-1")
+	if err := money.Validate(&settlement); err == nil {
+		t.Error("expected a negative Money amount to be rejected")
+	}
+}
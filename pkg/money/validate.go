@@ -0,0 +1,238 @@
+// Package money validates and decodes the monetary fields of fintech
+// payloads.
+//
+// Validate checks the fields of a struct tagged with `money:"..."`:
+//
+//	type Payout struct {
+//		Amount   string `json:"amount" money:"amount"`
+//		Fee      int64  `json:"fee" money:"amount"`
+//		Currency string `json:"currency" money:"currency"`
+//		Rate     string `json:"rate" money:"decimal"`
+//	}
+//
+// "amount" fields must be non-negative numbers, or strings holding one,
+// "currency" fields active ISO 4217 codes and "decimal" fields strings
+// holding a plain decimal number. Nested structs, slices, maps and pointers
+// are walked. Validate is a metahttp.StructValidatorFunc, so that responses
+// are checked before they reach business logic with
+//
+//	metahttp.WithResponseValidator(metahttp.StructValidatorFunc(money.Validate))
+//
+// Decimal and Money decode amounts without the rounding of float64.
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Rules of the money struct tag.
+const (
+	RuleAmount   = "amount"
+	RuleCurrency = "currency"
+	RuleDecimal  = "decimal"
+)
+
+// Violation describes a field breaking a rule. Field is the path of the
+// field from the validated value, named after its JSON name, e.g.
+// "items[0].amount".
+type Violation struct {
+	Field   string
+	Rule    string
+	Value   string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (%q)", v.Field, v.Message, v.Value)
+}
+
+// ValidationError lists every violation found in a value.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return "money validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks the money tagged fields of v, returning a
+// *ValidationError listing the violations. Values other than structs and
+// containers of structs are valid.
+func Validate(v interface{}) error {
+	var violations []Violation
+	walk(reflect.ValueOf(v), "", &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func walk(v reflect.Value, path string, out *[]Violation) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walk(v.Elem(), path, out)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i), out)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walk(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), out)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldPath := path
+			if !f.Anonymous {
+				fieldPath = joinPath(path, fieldName(f))
+			}
+			rules := f.Tag.Get("money")
+			if rules == "" || rules == "-" {
+				walk(v.Field(i), fieldPath, out)
+				continue
+			}
+			for _, rule := range strings.Split(rules, ",") {
+				check(v.Field(i), fieldPath, rule, out)
+			}
+		}
+	}
+}
+
+// check applies rule to a field, skipping nil pointers: optional fields are
+// only validated when present.
+func check(v reflect.Value, path, rule string, out *[]Violation) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	add := func(value, msg string) {
+		*out = append(*out, Violation{Field: path, Rule: rule, Value: value, Message: msg})
+	}
+
+	switch rule {
+	case RuleAmount:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Int() < 0 {
+				add(strconv.FormatInt(v.Int(), 10), "amount is negative")
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			switch {
+			case math.IsNaN(f) || math.IsInf(f, 0):
+				add(strconv.FormatFloat(f, 'g', -1, 64), "amount is not a number")
+			case f < 0:
+				add(strconv.FormatFloat(f, 'g', -1, 64), "amount is negative")
+			}
+		case reflect.String:
+			s := v.String()
+			switch {
+			case !IsDecimal(s):
+				add(s, "amount is not a decimal number")
+			case negative(s):
+				add(s, "amount is negative")
+			}
+		default:
+			if s, ok := stringer(v); ok {
+				check(reflect.ValueOf(s), path, rule, out)
+				return
+			}
+			add(fmt.Sprint(v.Interface()), fmt.Sprintf("amount cannot be a %s", v.Type()))
+		}
+	case RuleCurrency:
+		if v.Kind() != reflect.String {
+			add(fmt.Sprint(v.Interface()), fmt.Sprintf("currency cannot be a %s", v.Type()))
+		} else if !ValidCurrency(v.String()) {
+			add(v.String(), "not an ISO 4217 currency code")
+		}
+	case RuleDecimal:
+		s, ok := stringer(v)
+		if !ok && v.Kind() == reflect.String {
+			s, ok = v.String(), true
+		}
+		if !ok {
+			add(fmt.Sprint(v.Interface()), fmt.Sprintf("decimal cannot be a %s", v.Type()))
+		} else if !IsDecimal(s) {
+			add(s, "not a decimal number")
+		}
+	default:
+		add("", fmt.Sprintf("unknown money rule %q", rule))
+	}
+}
+
+// stringer returns the text of values such as json.Number and Decimal
+// that hold a number as text.
+func stringer(v reflect.Value) (string, bool) {
+	if !v.CanInterface() {
+		return "", false
+	}
+	switch s := v.Interface().(type) {
+	case json.Number:
+		return string(s), true
+	case fmt.Stringer:
+		return s.String(), true
+	}
+	return "", false
+}
+
+// IsDecimal reports whether s is a plain decimal number: an optional sign,
+// digits and an optional fraction, such as "-12", "0.50" or ".5". Exponents,
+// separators, spaces and special values such as "NaN" are rejected.
+func IsDecimal(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" && frac == "" {
+		return false
+	}
+	return allDigits(intPart) && allDigits(frac)
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// negative reports whether the decimal s is below zero, "-0.00" not being.
+func negative(s string) bool {
+	return strings.HasPrefix(s, "-") && strings.Trim(s[1:], "0.") != ""
+}
+
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
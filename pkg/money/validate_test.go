@@ -0,0 +1,108 @@
+package money_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/models"
+	"github.com/onmetahq/meta-http/pkg/money"
+)
+
+type fee struct {
+	Amount   float64 `json:"amount" money:"amount"`
+	Currency string  `json:"currency" money:"currency"`
+}
+
+type payout struct {
+	Amount   string         `json:"amount" money:"amount"`
+	Currency string         `json:"currency" money:"currency"`
+	Rate     json.Number    `json:"rate" money:"decimal"`
+	Refunded *int64         `json:"refunded,omitempty" money:"amount"`
+	Fees     []fee          `json:"fees"`
+	Meta     map[string]fee `json:"meta"`
+}
+
+func TestValidate(t *testing.T) {
+	valid := payout{Amount: "10.50", Currency: "USD", Rate: "-0.25", Fees: []fee{{Amount: 0.3, Currency: "EUR"}}}
+	if err := money.Validate(&valid); err != nil {
+		t.Errorf("expected a valid payout, got %v", err)
+	}
+
+	refunded := int64(-5)
+	invalid := payout{
+		Amount:   "-1.00",
+		Currency: "usd",
+		Rate:     "1e3",
+		Refunded: &refunded,
+		Fees:     []fee{{Amount: -0.3, Currency: "EUR"}, {Amount: 1, Currency: "XYZ"}},
+		Meta:     map[string]fee{"tax": {Currency: "INR"}},
+	}
+	err := money.Validate(invalid)
+	var verr *money.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a *money.ValidationError, got %v", err)
+	}
+	got := map[string]string{}
+	for _, v := range verr.Violations {
+		got[v.Field] = v.Rule
+	}
+	want := map[string]string{
+		"amount":           money.RuleAmount,
+		"currency":         money.RuleCurrency,
+		"rate":             money.RuleDecimal,
+		"refunded":         money.RuleAmount,
+		"fees[0].amount":   money.RuleAmount,
+		"fees[1].currency": money.RuleCurrency,
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected violations %v, got %v", want, verr.Violations)
+	}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Errorf("expected a %s violation on %s, got %v", rule, field, verr.Violations)
+		}
+	}
+
+	for s, want := range map[string]bool{"0": true, "12.": true, ".5": true, "+3.10": true, "": false, ".": false, "1,000": false, " 1": false, "NaN": false, "1e3": false, "--1": false} {
+		if money.IsDecimal(s) != want {
+			t.Errorf("IsDecimal(%q) = %t", s, !want)
+		}
+	}
+}
+
+func TestResponseValidator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/bad") {
+			rw.Write([]byte(`[{"amount":"5.00","currency":"USD","rate":"1"},{"amount":"-5.00","currency":"USD","rate":"1"}]`))
+			return
+		}
+		rw.Write([]byte(`[{"amount":"5.00","currency":"USD","rate":"1"}]`))
+	}))
+	defer server.Close()
+
+	client := metahttp.NewClient(server.URL, nil, 5*time.Second, metahttp.WithoutLogging(),
+		metahttp.WithResponseValidator(metahttp.StructValidatorFunc(money.Validate)))
+
+	var payouts []payout
+	if _, err := client.Get(context.Background(), "/payouts", nil, &payouts); err != nil || len(payouts) != 1 {
+		t.Fatalf("unexpected response %v, %v", payouts, err)
+	}
+
+	_, err := client.Get(context.Background(), "/payouts/bad", nil, &payouts)
+	var resErr *models.ResponseValidationError
+	var verr *money.ValidationError
+	if !errors.As(err, &resErr) || !errors.As(err, &verr) || verr.Violations[0].Field != "amount" {
+		t.Fatalf("expected a money violation, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "[1]") || models.CategoryOf(err) != models.CategoryDecode {
+		t.Errorf("expected the failing item and a decode category, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+// Package openapi reads the parts of an OpenAPI 3 document (JSON encoded)
+// that describe operations: method, path template, parameters and the JSON
+// schemas of request and response bodies.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/jsonschema"
+)
+
+type Parameter struct {
+	Name     string
+	In       string // "path", "query", "header" or "cookie"
+	Required bool
+}
+
+type Operation struct {
+	ID           string
+	Method       string
+	PathTemplate string
+	Parameters   []Parameter
+	// RequestSchema is nil when the operation takes no JSON body.
+	RequestSchema   *jsonschema.Schema
+	RequestRequired bool
+	// Responses lists the documented response keys: status codes, or
+	// "2XX"-style ranges and "default" as written in the document, whether or
+	// not they have a JSON body.
+	Responses []string
+	// ResponseSchemas is keyed like Responses, for those with a JSON schema.
+	ResponseSchemas map[string]*jsonschema.Schema
+}
+
+// ResponseKey returns the documented response matching the given status
+// code, preferring exact codes over ranges over "default".
+func (op *Operation) ResponseKey(status int) (string, bool) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		for _, documented := range op.Responses {
+			if documented == key {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ResponseSchema returns the schema describing a response with the given
+// status code. It reports false when the matching documented response has
+// no JSON schema, or when none matches.
+func (op *Operation) ResponseSchema(status int) (*jsonschema.Schema, bool) {
+	key, ok := op.ResponseKey(status)
+	if !ok {
+		return nil, false
+	}
+	s, ok := op.ResponseSchemas[key]
+	return s, ok
+}
+
+type Spec struct {
+	Operations map[string]*Operation
+}
+
+var methods = []string{
+	http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+	http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace,
+}
+
+// Parse reads a JSON encoded OpenAPI 3 document. Operations without an
+// operationId are registered as "METHOD /path/template".
+func Parse(data []byte) (*Spec, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+
+	spec := &Spec{Operations: map[string]*Operation{}}
+	paths, _ := root["paths"].(map[string]interface{})
+	for pathTemplate, rawItem := range paths {
+		item, ok := rawItem.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		shared := parseParameters(item["parameters"], root)
+
+		for _, method := range methods {
+			rawOp, ok := item[strings.ToLower(method)].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			op, err := parseOperation(rawOp, root)
+			if err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", method, pathTemplate, err)
+			}
+			op.Method = method
+			op.PathTemplate = pathTemplate
+			op.Parameters = mergeParameters(shared, op.Parameters)
+			if op.ID == "" {
+				op.ID = method + " " + pathTemplate
+			}
+			if _, dup := spec.Operations[op.ID]; dup {
+				return nil, fmt.Errorf("openapi: duplicate operationId %q", op.ID)
+			}
+			spec.Operations[op.ID] = op
+		}
+	}
+	return spec, nil
+}
+
+func (s *Spec) Operation(id string) (*Operation, bool) {
+	op, ok := s.Operations[id]
+	return op, ok
+}
+
+// Match finds the operation serving method and a concrete request path and
+// returns the path parameters extracted from it. Literal segments win over
+// templated ones when several templates match.
+func (s *Spec) Match(method string, path string) (*Operation, map[string]string, bool) {
+	segments := splitPath(path)
+
+	ids := make([]string, 0, len(s.Operations))
+	for id := range s.Operations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var best *Operation
+	var bestParams map[string]string
+	bestLiterals := -1
+	for _, id := range ids {
+		op := s.Operations[id]
+		if op.Method != method {
+			continue
+		}
+		params, literals, ok := matchTemplate(splitPath(op.PathTemplate), segments)
+		if ok && literals > bestLiterals {
+			best, bestParams, bestLiterals = op, params, literals
+		}
+	}
+	return best, bestParams, best != nil
+}
+
+func matchTemplate(template []string, segments []string) (map[string]string, int, bool) {
+	if len(template) != len(segments) {
+		return nil, 0, false
+	}
+	params := map[string]string{}
+	literals := 0
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			params[t[1:len(t)-1]] = segments[i]
+			continue
+		}
+		if t != segments[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	return params, literals, true
+}
+
+func splitPath(p string) []string {
+	p, _, _ = strings.Cut(p, "?")
+	return strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+}
+
+func parseOperation(raw map[string]interface{}, root map[string]interface{}) (*Operation, error) {
+	op := &Operation{ResponseSchemas: map[string]*jsonschema.Schema{}}
+	op.ID, _ = raw["operationId"].(string)
+	op.Parameters = parseParameters(raw["parameters"], root)
+
+	if body, ok := deref(raw["requestBody"], root); ok {
+		op.RequestRequired, _ = body["required"].(bool)
+		if schema, ok := jsonContentSchema(body, root); ok {
+			compiled, err := jsonschema.FromMap(schema, root)
+			if err != nil {
+				return nil, err
+			}
+			op.RequestSchema = compiled
+		}
+	}
+
+	responses, _ := raw["responses"].(map[string]interface{})
+	for code, rawRes := range responses {
+		op.Responses = append(op.Responses, code)
+		res, ok := deref(rawRes, root)
+		if !ok {
+			continue
+		}
+		if schema, ok := jsonContentSchema(res, root); ok {
+			compiled, err := jsonschema.FromMap(schema, root)
+			if err != nil {
+				return nil, err
+			}
+			op.ResponseSchemas[code] = compiled
+		}
+	}
+	sort.Strings(op.Responses)
+	return op, nil
+}
+
+func parseParameters(raw interface{}, root map[string]interface{}) []Parameter {
+	list, _ := raw.([]interface{})
+	params := make([]Parameter, 0, len(list))
+	for _, rawParam := range list {
+		p, ok := deref(rawParam, root)
+		if !ok {
+			continue
+		}
+		param := Parameter{}
+		param.Name, _ = p["name"].(string)
+		param.In, _ = p["in"].(string)
+		param.Required, _ = p["required"].(bool)
+		params = append(params, param)
+	}
+	return params
+}
+
+func mergeParameters(shared []Parameter, own []Parameter) []Parameter {
+	merged := append([]Parameter{}, own...)
+	for _, s := range shared {
+		found := false
+		for _, o := range own {
+			if o.Name == s.Name && o.In == s.In {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
+
+func jsonContentSchema(node map[string]interface{}, root map[string]interface{}) (map[string]interface{}, bool) {
+	content, _ := node["content"].(map[string]interface{})
+	for mediaType, rawMedia := range content {
+		mt := strings.ToLower(mediaType)
+		if mt != "application/json" && !strings.HasSuffix(mt, "+json") {
+			continue
+		}
+		media, ok := rawMedia.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		schema, ok := media["schema"].(map[string]interface{})
+		return schema, ok
+	}
+	return nil, false
+}
+
+// deref follows a local $ref on request bodies, responses and parameters.
+func deref(raw interface{}, root map[string]interface{}) (map[string]interface{}, bool) {
+	node, ok := raw.(map[string]interface{})
+	for i := 0; ok && i < 16; i++ {
+		ref, isRef := node["$ref"].(string)
+		if !isRef {
+			return node, true
+		}
+		var cur interface{} = root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, isMap := cur.(map[string]interface{})
+			if !isMap {
+				return nil, false
+			}
+			cur = m[part]
+		}
+		node, ok = cur.(map[string]interface{})
+	}
+	return node, ok
+}
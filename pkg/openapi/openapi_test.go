@@ -0,0 +1,194 @@
+package openapi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onmetahq/meta-http/pkg/openapi"
+)
+
+const petsSpec = `{
+	"openapi": "3.0.3",
+	"paths": {
+		"/pets": {
+			"get": {
+				"operationId": "listPets",
+				"parameters": [{"name": "limit", "in": "query", "required": true}],
+				"responses": {
+					"200": {"content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Pet"}}}}},
+					"default": {"$ref": "#/components/responses/Error"}
+				}
+			},
+			"post": {
+				"requestBody": {
+					"required": true,
+					"content": {"application/merge-patch+json": {"schema": {"$ref": "#/components/schemas/Pet"}}}
+				},
+				"responses": {
+					"2XX": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}},
+					"409": {"description": "conflict"}
+				}
+			}
+		},
+		"/pets/{id}": {
+			"parameters": [{"$ref": "#/components/parameters/Tenant"}],
+			"get": {
+				"operationId": "getPet",
+				"responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}}}
+			}
+		},
+		"/pets/mine": {
+			"get": {
+				"operationId": "myPets",
+				"responses": {"200": {"description": "ok"}}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"Pet": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}},
+			"Error": {"type": "object", "required": ["code"], "properties": {"code": {"type": "string"}}}
+		},
+		"responses": {
+			"Error": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+		},
+		"parameters": {
+			"Tenant": {"name": "X-Tenant-Id", "in": "header", "required": true}
+		}
+	}
+}`
+
+func TestParse(t *testing.T) {
+	spec, err := openapi.Parse([]byte(petsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Operations) != 4 {
+		t.Errorf("expected 4 operations, got %d", len(spec.Operations))
+	}
+
+	list, ok := spec.Operation("listPets")
+	if !ok || list.Method != "GET" || list.PathTemplate != "/pets" {
+		t.Fatalf("unexpected listPets %+v", list)
+	}
+	if len(list.Parameters) != 1 || list.Parameters[0] != (openapi.Parameter{Name: "limit", In: "query", Required: true}) {
+		t.Errorf("unexpected listPets parameters %+v", list.Parameters)
+	}
+
+	// Operations without an operationId are named after method and path.
+	create, ok := spec.Operation("POST /pets")
+	if !ok || !create.RequestRequired || create.RequestSchema == nil {
+		t.Fatalf("unexpected POST /pets %+v", create)
+	}
+	if err := create.RequestSchema.ValidateJSON([]byte(`{}`)); err == nil {
+		t.Errorf("expected the +json request schema to be compiled")
+	}
+
+	// Path item parameters are shared by its operations, through $ref.
+	get, _ := spec.Operation("getPet")
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "X-Tenant-Id" || get.Parameters[0].In != "header" {
+		t.Errorf("unexpected getPet parameters %+v", get.Parameters)
+	}
+
+	for _, doc := range []string{
+		`{"paths": {"/a": {"get": {"operationId": "x"}}, "/b": {"get": {"operationId": "x"}}}}`,
+		`{"paths": {"/a": {"get": {"requestBody": {"content": {"application/json": {"schema": {"type": "string", "pattern": "("}}}}}}}}`,
+		`{"paths": `,
+	} {
+		if _, err := openapi.Parse([]byte(doc)); err == nil || !strings.HasPrefix(err.Error(), "openapi: ") {
+			t.Errorf("expected %s to be rejected, got %v", doc, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	spec, err := openapi.Parse([]byte(petsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		method string
+		path   string
+		id     string
+		params map[string]string
+	}{
+		{"GET", "/pets", "listPets", map[string]string{}},
+		{"GET", "/pets?limit=10", "listPets", map[string]string{}},
+		{"GET", "/pets/42", "getPet", map[string]string{"id": "42"}},
+		{"GET", "/pets/42/", "getPet", map[string]string{"id": "42"}},
+		// Literal segments win over templated ones.
+		{"GET", "/pets/mine", "myPets", map[string]string{}},
+		{"POST", "/pets", "POST /pets", map[string]string{}},
+		{"DELETE", "/pets/42", "", nil},
+		{"GET", "/pets/42/toys", "", nil},
+		{"GET", "/owners", "", nil},
+	}
+	for _, tc := range tcs {
+		op, params, ok := spec.Match(tc.method, tc.path)
+		if tc.id == "" {
+			if ok {
+				t.Errorf("%s %s: expected no match, got %s", tc.method, tc.path, op.ID)
+			}
+			continue
+		}
+		if !ok || op.ID != tc.id {
+			t.Errorf("%s %s: expected %s, got %+v", tc.method, tc.path, tc.id, op)
+			continue
+		}
+		if len(params) != len(tc.params) {
+			t.Errorf("%s %s: expected params %v, got %v", tc.method, tc.path, tc.params, params)
+		}
+		for k, v := range tc.params {
+			if params[k] != v {
+				t.Errorf("%s %s: expected params %v, got %v", tc.method, tc.path, tc.params, params)
+			}
+		}
+	}
+}
+
+func TestResponseSchema(t *testing.T) {
+	spec, err := openapi.Parse([]byte(petsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := spec.Operation("listPets")
+	create, _ := spec.Operation("POST /pets")
+
+	tcs := []struct {
+		op     *openapi.Operation
+		status int
+		key    string
+		// valid is a body valid against the schema, empty when the response
+		// has none.
+		valid string
+	}{
+		{list, 200, "200", `[{"name":"rex"}]`},
+		{list, 404, "default", `{"code":"not_found"}`},
+		{list, 500, "default", `{"code":"internal"}`},
+		{create, 201, "2XX", `{"name":"rex"}`},
+		{create, 202, "2XX", `{"name":"rex"}`},
+		{create, 409, "409", ""},
+		{create, 500, "", ""},
+	}
+	for _, tc := range tcs {
+		key, ok := tc.op.ResponseKey(tc.status)
+		if key != tc.key || ok != (tc.key != "") {
+			t.Errorf("%s %d: expected response %q, got %q", tc.op.ID, tc.status, tc.key, key)
+		}
+		schema, ok := tc.op.ResponseSchema(tc.status)
+		if ok != (tc.valid != "") {
+			t.Errorf("%s %d: expected a schema: %t, got %t", tc.op.ID, tc.status, tc.valid != "", ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := schema.ValidateJSON([]byte(tc.valid)); err != nil {
+			t.Errorf("%s %d: %v", tc.op.ID, tc.status, err)
+		}
+		if err := schema.ValidateJSON([]byte(`{"unexpected":true}`)); err == nil {
+			t.Errorf("%s %d: expected the %s schema to reject an unexpected body", tc.op.ID, tc.status, tc.key)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// ContextWithFeatureFlags stores flags, flag names mapped to the variant
+// they evaluated to, in ctx so that calls made with it send them in the
+// x-feature-flags header, e.g. "checkout-v2=on,pricing=b". Downstream
+// services read them back with FetchContextFromHeaders and
+// FeatureFlagsFromContext instead of evaluating the flags again. Flags
+// already in ctx are kept unless flags overrides them.
+func ContextWithFeatureFlags(ctx context.Context, flags map[string]string) context.Context {
+	merged := FeatureFlagsFromContext(ctx)
+	for name, variant := range flags {
+		merged[name] = variant
+	}
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = url.QueryEscape(name) + "=" + url.QueryEscape(merged[name])
+	}
+	return context.WithValue(ctx, models.FeatureFlags, strings.Join(pairs, ","))
+}
+
+// FeatureFlagsFromContext returns the flags of ctx, set by
+// ContextWithFeatureFlags or received in the x-feature-flags header. A flag
+// listed without a variant is "true". Malformed entries are skipped.
+func FeatureFlagsFromContext(ctx context.Context) map[string]string {
+	flags := map[string]string{}
+	header, _ := ctx.Value(models.FeatureFlags).(string)
+	for _, pair := range strings.Split(header, ",") {
+		rawName, rawVariant, found := strings.Cut(strings.TrimSpace(pair), "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil || name == "" {
+			continue
+		}
+		variant := "true"
+		if found {
+			if variant, err = url.QueryUnescape(rawVariant); err != nil {
+				continue
+			}
+		}
+		flags[name] = variant
+	}
+	return flags
+}
@@ -7,16 +7,32 @@ import (
 	"github.com/onmetahq/meta-http/pkg/models"
 )
 
+// contextKeys holds models.ContextKeys converted to interfaces once, as
+// converting them on every lookup allocates.
+var contextKeys = func() []any {
+	keys := make([]any, len(models.ContextKeys))
+	for i, key := range models.ContextKeys {
+		keys[i] = key
+	}
+	return keys
+}()
+
 func FetchHeadersFromContext(ctx context.Context) map[string]string {
 	ctxHeaders := map[string]string{}
-	for _, key := range models.ContextKeys {
-		val, ok := ctx.Value(key).(string)
-		if ok {
-			ctxHeaders[string(key)] = val
+	EachHeaderFromContext(ctx, func(key, val string) {
+		ctxHeaders[key] = val
+	})
+	return ctxHeaders
+}
+
+// EachHeaderFromContext calls fn with every header FetchHeadersFromContext
+// would return, without building a map.
+func EachHeaderFromContext(ctx context.Context, fn func(key, val string)) {
+	for i, key := range contextKeys {
+		if val, ok := ctx.Value(key).(string); ok {
+			fn(string(models.ContextKeys[i]), val)
 		}
 	}
-
-	return ctxHeaders
 }
 
 func FetchContextFromHeaders(ctx context.Context, r *http.Request) context.Context {
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/onmetahq/meta-http/pkg/models"
+)
+
+// JoinPath builds a relative path from raw segments, escaping each one so
+// that user supplied values containing "/", "?" or "#" stay inside their
+// segment, e.g. JoinPath("users", "a/b?c") returns "/users/a%2Fb%3Fc".
+func JoinPath(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = url.PathEscape(s)
+	}
+	return "/" + strings.Join(escaped, "/")
+}
+
+// StrictJoinPath is JoinPath that additionally rejects empty and dot segments
+// and segments carrying control characters or backslashes.
+func StrictJoinPath(segments ...string) (string, error) {
+	for _, s := range segments {
+		if err := CheckPathSegment(s); err != nil {
+			return "", err
+		}
+	}
+	return JoinPath(segments...), nil
+}
+
+// CheckPathSegment reports whether a single unescaped segment is suspicious.
+func CheckPathSegment(segment string) error {
+	if segment == "" || segment == "." || segment == ".." {
+		return fmt.Errorf("%w: segment %q", models.ErrUnsafePath, segment)
+	}
+	for _, r := range segment {
+		if r < 0x20 || r == 0x7f || r == '\\' {
+			return fmt.Errorf("%w: segment %q", models.ErrUnsafePath, segment)
+		}
+	}
+	return nil
+}
+
+// CheckPath validates an already escaped relative path, rejecting dot
+// segments (including percent-encoded ones) and the characters refused by
+// CheckPathSegment. The query string is not inspected.
+func CheckPath(p string) error {
+	p, _, _ = strings.Cut(p, "?")
+	p, _, _ = strings.Cut(p, "#")
+	for _, raw := range strings.Split(p, "/") {
+		if raw == "" {
+			continue
+		}
+		segment, err := url.PathUnescape(raw)
+		if err != nil {
+			return fmt.Errorf("%w: segment %q", models.ErrUnsafePath, raw)
+		}
+		if err := CheckPathSegment(segment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// EncodeQuery converts a struct into query parameters using `url` field tags.
+//
+// The tag value is the parameter name followed by comma separated options:
+//
+//	omitempty  skip zero values
+//	comma      join slice elements with "," instead of repeating the key
+//	space      join slice elements with " "
+//	brackets   repeat the key with a "[]" suffix for slice elements
+//	unix       encode a time.Time as seconds since the epoch
+//	unixmilli  encode a time.Time as milliseconds since the epoch
+//
+// A `layout` tag overrides the default time.RFC3339 layout. Fields tagged
+// "-" and unexported fields are skipped, embedded structs are flattened and
+// nested structs are encoded as name[field].
+func EncodeQuery(v interface{}) (url.Values, error) {
+	values := url.Values{}
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return values, nil
+		}
+		val = val.Elem()
+	}
+	if v == nil {
+		return values, nil
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("query encoding expects a struct, got %T", v)
+	}
+	return values, encodeStruct(values, val, "")
+}
+
+func encodeStruct(values url.Values, val reflect.Value, scope string) error {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		options := map[string]bool{}
+		for _, o := range strings.Split(opts, ",") {
+			options[o] = true
+		}
+
+		fv := val.Field(i)
+		if field.Anonymous && name == "" {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				if err := encodeStruct(values, fv, scope); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if scope != "" {
+			name = scope + "[" + name + "]"
+		}
+
+		if options["omitempty"] && isEmptyValue(fv) {
+			continue
+		}
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr {
+			values.Add(name, "")
+			continue
+		}
+
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && fv.Type().Elem().Kind() != reflect.Uint8 {
+			items := make([]string, 0, fv.Len())
+			for j := 0; j < fv.Len(); j++ {
+				s, err := formatValue(fv.Index(j), field, options)
+				if err != nil {
+					return fmt.Errorf("field %s: %w", field.Name, err)
+				}
+				items = append(items, s)
+			}
+			switch {
+			case options["comma"]:
+				values.Add(name, strings.Join(items, ","))
+			case options["space"]:
+				values.Add(name, strings.Join(items, " "))
+			case options["brackets"]:
+				for _, s := range items {
+					values.Add(name+"[]", s)
+				}
+			default:
+				for _, s := range items {
+					values.Add(name, s)
+				}
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			if _, ok := fv.Interface().(fmt.Stringer); !ok {
+				if err := encodeStruct(values, fv, name); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		s, err := formatValue(fv, field, options)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		values.Add(name, s)
+	}
+	return nil
+}
+
+func formatValue(v reflect.Value, field reflect.StructField, options map[string]bool) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		switch {
+		case options["unix"]:
+			return strconv.FormatInt(t.Unix(), 10), nil
+		case options["unixmilli"]:
+			return strconv.FormatInt(t.UnixMilli(), 10), nil
+		}
+		layout := field.Tag.Get("layout")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return t.Format(layout), nil
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported query value type %s", v.Type())
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+// Package webhook delivers signed webhooks through a meta-http client and
+// verifies signed webhooks on the receiving side.
+//
+// A delivery carries three headers: SignatureHeader ("v1=" followed by the
+// hex HMAC-SHA256 of "<timestamp>.<body>"), TimestampHeader (unix seconds)
+// and IDHeader (stable across retries of the same delivery, for
+// de-duplication).
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	IDHeader        = "X-Webhook-Id"
+)
+
+// DefaultSchedule retries after 1, 5 and 30 minutes.
+var DefaultSchedule = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute}
+
+// Attempt describes one delivery attempt, reported through the sender's
+// attempt callback.
+type Attempt struct {
+	DeliveryID string
+	URL        string
+	Number     int // 1 for the first attempt
+	StatusCode int // 0 when no response was received
+	Err        error
+	Duration   time.Duration
+	// Final is set on the last attempt of a delivery, successful or not.
+	Final bool
+	// NextAttemptAt is when the next attempt is due, zero when Final.
+	NextAttemptAt time.Time
+}
+
+type Sender struct {
+	client    metahttp.Requests
+	secret    []byte
+	schedule  []time.Duration
+	onAttempt func(Attempt)
+	logger    metahttp.Logger
+	now       func() time.Time
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type SenderOption func(*Sender)
+
+// WithSchedule sets the delays between attempts; its length is the number
+// of retries after the first attempt.
+func WithSchedule(schedule ...time.Duration) SenderOption {
+	return func(s *Sender) {
+		s.schedule = schedule
+	}
+}
+
+// WithAttemptCallback reports every delivery attempt to fn.
+func WithAttemptCallback(fn func(Attempt)) SenderOption {
+	return func(s *Sender) {
+		s.onAttempt = fn
+	}
+}
+
+// NewSender returns a sender posting through client, which should be
+// created with an empty base URL since deliveries use absolute URLs.
+func NewSender(client metahttp.Requests, secret []byte, log metahttp.Logger, opts ...SenderOption) *Sender {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sender{
+		client:   client,
+		secret:   secret,
+		schedule: DefaultSchedule,
+		logger:   log,
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Deliver posts event to url, retrying on the configured schedule until it
+// is accepted, a permanent failure is returned or ctx is done. It blocks for
+// the whole schedule; use DeliverAsync to deliver in the background.
+func (s *Sender) Deliver(ctx context.Context, url string, event interface{}) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	id, err := newDeliveryID()
+	if err != nil {
+		return err
+	}
+
+	for n := 1; ; n++ {
+		started := s.now()
+		status, err := s.attempt(ctx, url, id, body)
+		attempt := Attempt{
+			DeliveryID: id,
+			URL:        url,
+			Number:     n,
+			StatusCode: status,
+			Err:        err,
+			Duration:   s.now().Sub(started),
+		}
+
+		if err == nil || !retryable(status) || n > len(s.schedule) {
+			attempt.Final = true
+			s.report(attempt)
+			return err
+		}
+
+		delay := s.schedule[n-1]
+		attempt.NextAttemptAt = s.now().Add(delay)
+		s.report(attempt)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// DeliverAsync runs Deliver in the background. Outcomes are only observable
+// through the attempt callback. Close cancels pending deliveries.
+func (s *Sender) DeliverAsync(url string, event interface{}) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.Deliver(s.ctx, url, event); err != nil && s.logger != nil {
+			s.logger.WarnContext(s.ctx, "Webhook delivery failed", slog.String("url", url), slog.Any("error", err.Error()))
+		}
+	}()
+}
+
+// Close cancels background deliveries still waiting for a retry and waits
+// for them to return or ctx to be done.
+func (s *Sender) Close(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Sender) attempt(ctx context.Context, url string, id string, body []byte) (int, error) {
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	headers := map[string]string{
+		IDHeader:        id,
+		TimestampHeader: ts,
+		SignatureHeader: Sign(s.secret, ts, body),
+	}
+
+	// Receivers acknowledge with all sorts of bodies; capture it raw.
+	var res []byte
+	resp, err := s.client.Post(ctx, url, headers, json.RawMessage(body), &res)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	return status, err
+}
+
+func (s *Sender) report(a Attempt) {
+	if s.onAttempt != nil {
+		s.onAttempt(a)
+	}
+}
+
+// Sign computes the SignatureHeader value for a timestamp and body.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryable treats client errors other than timeouts and rate limiting as
+// permanent; everything else, including transport errors, is retried.
+func retryable(status int) bool {
+	if status >= 400 && status < 500 {
+		return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func newDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
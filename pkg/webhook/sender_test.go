@@ -0,0 +1,79 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/webhook"
+)
+
+func TestSenderRetriesAndSigns(t *testing.T) {
+	secret := []byte("whsec")
+	var calls atomic.Int32
+	var ids = make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		want := webhook.Sign(secret, req.Header.Get(webhook.TimestampHeader), body)
+		if req.Header.Get(webhook.SignatureHeader) != want {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ids <- req.Header.Get(webhook.IDHeader)
+		if calls.Add(1) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	client := metahttp.NewClient("", logger, 10*time.Second)
+
+	var attempts []webhook.Attempt
+	sender := webhook.NewSender(client, secret, logger,
+		webhook.WithSchedule(10*time.Millisecond, 10*time.Millisecond, 10*time.Millisecond),
+		webhook.WithAttemptCallback(func(a webhook.Attempt) {
+			attempts = append(attempts, a)
+		}),
+	)
+
+	err := sender.Deliver(context.Background(), server.URL+"/hooks", map[string]string{"event": "order.paid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 3 || !attempts[2].Final || attempts[2].StatusCode != http.StatusOK || attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("unexpected attempts: %+v", attempts)
+	}
+	if a, b := <-ids, <-ids; a == "" || a != b {
+		t.Errorf("delivery id should be stable across attempts: %q %q", a, b)
+	}
+}
+
+func TestSenderStopsOnPermanentFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	var attempts int
+	sender := webhook.NewSender(metahttp.NewClient("", logger, 10*time.Second), []byte("whsec"), logger,
+		webhook.WithSchedule(time.Hour),
+		webhook.WithAttemptCallback(func(a webhook.Attempt) { attempts++ }),
+	)
+	if err := sender.Deliver(context.Background(), server.URL, map[string]string{}); err == nil {
+		t.Error("expected delivery error")
+	}
+	if attempts != 1 {
+		t.Errorf("permanent failures should not be retried, got %d attempts", attempts)
+	}
+}
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature headers")
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrStaleTimestamp   = errors.New("webhook: timestamp outside tolerance")
+	ErrReplayed         = errors.New("webhook: delivery already processed")
+)
+
+const DefaultTolerance = 5 * time.Minute
+
+// ReplayCache remembers deliveries so that a captured request cannot be
+// replayed within the timestamp tolerance. Implementations backed by shared
+// storage (e.g. Redis SET NX with expiry) protect every replica.
+type ReplayCache interface {
+	// Seen records key until expiresAt and reports whether it was already
+	// recorded.
+	Seen(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// MemoryReplayCache is a process local ReplayCache.
+type MemoryReplayCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+}
+
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{entries: map[string]time.Time{}, now: time.Now}
+}
+
+func (c *MemoryReplayCache) Seen(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, exp := range c.entries {
+		if now.After(exp) {
+			delete(c.entries, k)
+		}
+	}
+	if _, ok := c.entries[id]; ok {
+		return true, nil
+	}
+	c.entries[id] = expiresAt
+	return false, nil
+}
+
+// Verifier checks webhooks signed by Sender or any partner using the same
+// scheme.
+type Verifier struct {
+	secrets   [][]byte
+	tolerance time.Duration
+	cache     ReplayCache
+	now       func() time.Time
+}
+
+type VerifierOption func(*Verifier)
+
+// WithTolerance sets how far the timestamp may drift from the local clock,
+// DefaultTolerance by default.
+func WithTolerance(d time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.tolerance = d
+	}
+}
+
+// WithReplayCache rejects deliveries whose signed timestamp and body were
+// already accepted.
+func WithReplayCache(cache ReplayCache) VerifierOption {
+	return func(v *Verifier) {
+		v.cache = cache
+	}
+}
+
+// WithAdditionalSecrets accepts signatures made with older secrets during a
+// rotation.
+func WithAdditionalSecrets(secrets ...[]byte) VerifierOption {
+	return func(v *Verifier) {
+		v.secrets = append(v.secrets, secrets...)
+	}
+}
+
+func NewVerifier(secret []byte, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		secrets:   [][]byte{secret},
+		tolerance: DefaultTolerance,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify checks the signature and timestamp headers against body and,
+// with a replay cache configured, that the delivery is new. Deliveries are
+// remembered by their signed timestamp and body, which the unsigned ID
+// header cannot disguise. IDs are not remembered: Verify runs before the
+// handler has processed a delivery, and its retry after a failure, signed
+// anew by the sender, must get through.
+func (v *Verifier) Verify(ctx context.Context, header http.Header, body []byte) error {
+	signature := header.Get(SignatureHeader)
+	timestamp := header.Get(TimestampHeader)
+	if signature == "" || timestamp == "" {
+		return ErrMissingSignature
+	}
+
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleTimestamp
+	}
+	sent := time.Unix(secs, 0)
+	if d := v.now().Sub(sent); d > v.tolerance || d < -v.tolerance {
+		return ErrStaleTimestamp
+	}
+
+	if !v.validSignature(signature, timestamp, body) {
+		return ErrInvalidSignature
+	}
+
+	if v.cache != nil {
+		// Keyed on the signature of the primary secret rather than on the
+		// header, which may list several signatures in any order.
+		seen, err := v.cache.Seen(ctx, "sig:"+Sign(v.secrets[0], timestamp, body), sent.Add(v.tolerance))
+		if err != nil {
+			return err
+		}
+		if seen {
+			return ErrReplayed
+		}
+	}
+	return nil
+}
+
+// VerifyRequest reads and verifies r's body, leaving r.Body readable again
+// for the handler.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, v.Verify(r.Context(), r.Header, body)
+}
+
+// Middleware rejects unverified requests with 401 before calling next.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if _, err := v.VerifyRequest(r); err != nil {
+			http.Error(rw, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// validSignature accepts a header listing several comma separated "v1="
+// signatures, comparing each in constant time.
+func (v *Verifier) validSignature(header string, timestamp string, body []byte) bool {
+	valid := false
+	for _, secret := range v.secrets {
+		expected := []byte(Sign(secret, timestamp, body))
+		for _, candidate := range strings.Split(header, ",") {
+			if hmac.Equal([]byte(strings.TrimSpace(candidate)), expected) {
+				valid = true
+			}
+		}
+	}
+	return valid
+}
@@ -0,0 +1,102 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onmetahq/meta-http/pkg/webhook"
+)
+
+func TestVerifier(t *testing.T) {
+	secret := []byte("whsec")
+	body := []byte(`{"event":"order.paid"}`)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	header := http.Header{}
+	header.Set(webhook.IDHeader, "delivery-1")
+	header.Set(webhook.TimestampHeader, ts)
+	header.Set(webhook.SignatureHeader, webhook.Sign(secret, ts, body))
+
+	verifier := webhook.NewVerifier(secret, webhook.WithReplayCache(webhook.NewMemoryReplayCache()))
+	if err := verifier.Verify(context.Background(), header, body); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.Verify(context.Background(), header, body); !errors.Is(err, webhook.ErrReplayed) {
+		t.Errorf("expected replay error, got: %v", err)
+	}
+	header.Set(webhook.IDHeader, "delivery-2")
+	if err := verifier.Verify(context.Background(), header, body); !errors.Is(err, webhook.ErrReplayed) {
+		t.Errorf("expected replay error with a new ID, got: %v", err)
+	}
+	// A retry of the delivery is signed with a new timestamp.
+	retried := strconv.FormatInt(time.Now().Unix()+1, 10)
+	header.Set(webhook.IDHeader, "delivery-1")
+	header.Set(webhook.TimestampHeader, retried)
+	header.Set(webhook.SignatureHeader, webhook.Sign(secret, retried, body))
+	if err := verifier.Verify(context.Background(), header, body); err != nil {
+		t.Errorf("expected a retried delivery to be accepted, got: %v", err)
+	}
+	header.Set(webhook.TimestampHeader, ts)
+	header.Set(webhook.IDHeader, "")
+	header.Set(webhook.SignatureHeader, webhook.Sign([]byte("other"), ts, body)+","+webhook.Sign(secret, ts, body))
+	if err := verifier.Verify(context.Background(), header, body); !errors.Is(err, webhook.ErrReplayed) {
+		t.Errorf("expected replay error with an extra signature, got: %v", err)
+	}
+
+	if err := verifier.Verify(context.Background(), header, []byte(`{"event":"order.refunded"}`)); !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Errorf("expected invalid signature, got: %v", err)
+	}
+
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	header.Set(webhook.TimestampHeader, stale)
+	header.Set(webhook.SignatureHeader, webhook.Sign(secret, stale, body))
+	if err := verifier.Verify(context.Background(), header, body); !errors.Is(err, webhook.ErrStaleTimestamp) {
+		t.Errorf("expected stale timestamp, got: %v", err)
+	}
+
+	rotated := webhook.NewVerifier([]byte("new-secret"), webhook.WithAdditionalSecrets(secret))
+	header.Set(webhook.TimestampHeader, ts)
+	header.Set(webhook.SignatureHeader, webhook.Sign(secret, ts, body))
+	if err := rotated.Verify(context.Background(), header, body); err != nil {
+		t.Errorf("rotated secret should verify: %v", err)
+	}
+}
+
+func TestVerifierMiddlewareRetryAfterFailure(t *testing.T) {
+	secret := []byte("whsec")
+	verifier := webhook.NewVerifier(secret, webhook.WithReplayCache(webhook.NewMemoryReplayCache()))
+	handled := 0
+	server := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		handled++
+		if handled == 1 {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+	})))
+	defer server.Close()
+
+	body := `{"event":"order.paid"}`
+	for i, want := range []int{http.StatusInternalServerError, http.StatusOK} {
+		ts := strconv.FormatInt(time.Now().Unix()+int64(i), 10)
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		req.Header.Set(webhook.IDHeader, "delivery-1")
+		req.Header.Set(webhook.TimestampHeader, ts)
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, ts, []byte(body)))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != want {
+			t.Errorf("attempt %d: expected %d, got %d", i+1, want, res.StatusCode)
+		}
+	}
+	if handled != 2 {
+		t.Errorf("expected the retry to reach the handler, handled %d", handled)
+	}
+}
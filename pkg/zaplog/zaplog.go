@@ -0,0 +1,56 @@
+// Package zaplog adapts a *zap.Logger to metahttp.Logger.
+package zaplog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type logger struct {
+	z *zap.Logger
+}
+
+// New returns a metahttp.Logger writing to z. slog style key/value pairs and
+// attributes are converted to zap fields.
+func New(z *zap.Logger) metahttp.Logger {
+	return logger{z: z.WithOptions(zap.AddCallerSkip(2))}
+}
+
+func (l logger) DebugContext(_ context.Context, msg string, args ...any) {
+	l.write(zap.DebugLevel, msg, args)
+}
+
+func (l logger) InfoContext(_ context.Context, msg string, args ...any) {
+	l.write(zap.InfoLevel, msg, args)
+}
+
+func (l logger) WarnContext(_ context.Context, msg string, args ...any) {
+	l.write(zap.WarnLevel, msg, args)
+}
+
+func (l logger) ErrorContext(_ context.Context, msg string, args ...any) {
+	l.write(zap.ErrorLevel, msg, args)
+}
+
+// write builds the fields of an entry only when its level is enabled.
+func (l logger) write(level zapcore.Level, msg string, args []any) {
+	if ce := l.z.Check(level, msg); ce != nil {
+		ce.Write(fields(args)...)
+	}
+}
+
+func fields(args []any) []zap.Field {
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "", 0)
+	r.Add(args...)
+	fs := make([]zap.Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fs = append(fs, zap.Any(a.Key, a.Value.Resolve().Any()))
+		return true
+	})
+	return fs
+}
@@ -0,0 +1,66 @@
+package zaplog_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metahttp "github.com/onmetahq/meta-http/pkg/meta_http"
+	"github.com/onmetahq/meta-http/pkg/zaplog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	client := metahttp.NewClient(server.URL, zaplog.New(zap.New(core)), 5*time.Second)
+	if _, err := client.Get(context.Background(), "/orders", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ended := logs.FilterMessage("Call Ended").All()
+	if len(ended) != 1 {
+		t.Fatalf("expected one Call Ended entry, got %d", len(ended))
+	}
+	fields := ended[0].ContextMap()
+	if fields["path"] != "/orders" || fields["status"] != int64(http.StatusNoContent) {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+}
+
+type countingValuer struct{ resolved *int }
+
+func (v countingValuer) LogValue() slog.Value {
+	*v.resolved++
+	return slog.StringValue("value")
+}
+
+func TestZapLoggerLevels(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	log := zaplog.New(zap.New(core, zap.AddCaller()))
+
+	resolved := 0
+	log.DebugContext(context.Background(), "skipped", slog.Any("v", countingValuer{&resolved}))
+	log.InfoContext(context.Background(), "logged", slog.Any("v", countingValuer{&resolved}))
+
+	if resolved != 1 {
+		t.Errorf("expected fields to be built for enabled levels only, resolved %d times", resolved)
+	}
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].ContextMap()["v"] != "value" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if !strings.HasSuffix(entries[0].Caller.File, "zaplog_test.go") {
+		t.Errorf("expected the caller of the logger, got %s", entries[0].Caller.File)
+	}
+}